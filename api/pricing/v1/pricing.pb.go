@@ -0,0 +1,1090 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: api/pricing/v1/pricing.proto
+
+package pricingv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Region struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// continent is empty for regions of unknown location.
+	Continent     string `protobuf:"bytes,2,opt,name=continent,proto3" json:"continent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Region) Reset() {
+	*x = Region{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Region) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Region) ProtoMessage() {}
+
+func (x *Region) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Region.ProtoReflect.Descriptor instead.
+func (*Region) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Region) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Region) GetContinent() string {
+	if x != nil {
+		return x.Continent
+	}
+	return ""
+}
+
+type ListRegionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// continent restricts the list, e.g. "europe"; empty lists every region.
+	Continent     string `protobuf:"bytes,1,opt,name=continent,proto3" json:"continent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRegionsRequest) Reset() {
+	*x = ListRegionsRequest{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRegionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRegionsRequest) ProtoMessage() {}
+
+func (x *ListRegionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRegionsRequest.ProtoReflect.Descriptor instead.
+func (*ListRegionsRequest) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListRegionsRequest) GetContinent() string {
+	if x != nil {
+		return x.Continent
+	}
+	return ""
+}
+
+type ListRegionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Regions       []*Region              `protobuf:"bytes,1,rep,name=regions,proto3" json:"regions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRegionsResponse) Reset() {
+	*x = ListRegionsResponse{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRegionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRegionsResponse) ProtoMessage() {}
+
+func (x *ListRegionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRegionsResponse.ProtoReflect.Descriptor instead.
+func (*ListRegionsResponse) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListRegionsResponse) GetRegions() []*Region {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+// UnitPrices are prices per vCPU hour and per GB hour of memory.
+type UnitPrices struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SpotPerVcpu     float64                `protobuf:"fixed64,1,opt,name=spot_per_vcpu,json=spotPerVcpu,proto3" json:"spot_per_vcpu,omitempty"`
+	SpotPerGb       float64                `protobuf:"fixed64,2,opt,name=spot_per_gb,json=spotPerGb,proto3" json:"spot_per_gb,omitempty"`
+	OnDemandPerVcpu float64                `protobuf:"fixed64,3,opt,name=on_demand_per_vcpu,json=onDemandPerVcpu,proto3" json:"on_demand_per_vcpu,omitempty"`
+	OnDemandPerGb   float64                `protobuf:"fixed64,4,opt,name=on_demand_per_gb,json=onDemandPerGb,proto3" json:"on_demand_per_gb,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UnitPrices) Reset() {
+	*x = UnitPrices{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnitPrices) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnitPrices) ProtoMessage() {}
+
+func (x *UnitPrices) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnitPrices.ProtoReflect.Descriptor instead.
+func (*UnitPrices) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UnitPrices) GetSpotPerVcpu() float64 {
+	if x != nil {
+		return x.SpotPerVcpu
+	}
+	return 0
+}
+
+func (x *UnitPrices) GetSpotPerGb() float64 {
+	if x != nil {
+		return x.SpotPerGb
+	}
+	return 0
+}
+
+func (x *UnitPrices) GetOnDemandPerVcpu() float64 {
+	if x != nil {
+		return x.OnDemandPerVcpu
+	}
+	return 0
+}
+
+func (x *UnitPrices) GetOnDemandPerGb() float64 {
+	if x != nil {
+		return x.OnDemandPerGb
+	}
+	return 0
+}
+
+// Machine is a machine type in a region with its current prices and summary statistics.
+type Machine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MachineType   string                 `protobuf:"bytes,1,opt,name=machine_type,json=machineType,proto3" json:"machine_type,omitempty"`
+	RegionName    string                 `protobuf:"bytes,2,opt,name=region_name,json=regionName,proto3" json:"region_name,omitempty"`
+	HourSpotPrice float64                `protobuf:"fixed64,3,opt,name=hour_spot_price,json=hourSpotPrice,proto3" json:"hour_spot_price,omitempty"`
+	// hour_price is the current on-demand price per hour.
+	HourPrice float64 `protobuf:"fixed64,4,opt,name=hour_price,json=hourPrice,proto3" json:"hour_price,omitempty"`
+	// spot_discount_pct is the current spot discount relative to on-demand, in percent.
+	SpotDiscountPct  float64                `protobuf:"fixed64,5,opt,name=spot_discount_pct,json=spotDiscountPct,proto3" json:"spot_discount_pct,omitempty"`
+	MinHourSpotPrice float64                `protobuf:"fixed64,6,opt,name=min_hour_spot_price,json=minHourSpotPrice,proto3" json:"min_hour_spot_price,omitempty"`
+	MaxHourSpotPrice float64                `protobuf:"fixed64,7,opt,name=max_hour_spot_price,json=maxHourSpotPrice,proto3" json:"max_hour_spot_price,omitempty"`
+	AvgHourSpotPrice float64                `protobuf:"fixed64,8,opt,name=avg_hour_spot_price,json=avgHourSpotPrice,proto3" json:"avg_hour_spot_price,omitempty"`
+	FirstSeen        *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	LastSeen         *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// change_count is the number of spot price changes between consecutive snapshots.
+	ChangeCount int64 `protobuf:"varint,11,opt,name=change_count,json=changeCount,proto3" json:"change_count,omitempty"`
+	// last_changed_at, previous_price and change_pct describe the last spot price change
+	// and are unset if the price never changed.
+	LastChangedAt *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=last_changed_at,json=lastChangedAt,proto3" json:"last_changed_at,omitempty"`
+	PreviousPrice *float64               `protobuf:"fixed64,13,opt,name=previous_price,json=previousPrice,proto3,oneof" json:"previous_price,omitempty"`
+	ChangePct     *float64               `protobuf:"fixed64,14,opt,name=change_pct,json=changePct,proto3,oneof" json:"change_pct,omitempty"`
+	// unit_prices is only set when requested.
+	UnitPrices    *UnitPrices `protobuf:"bytes,15,opt,name=unit_prices,json=unitPrices,proto3" json:"unit_prices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Machine) Reset() {
+	*x = Machine{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Machine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Machine) ProtoMessage() {}
+
+func (x *Machine) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Machine.ProtoReflect.Descriptor instead.
+func (*Machine) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Machine) GetMachineType() string {
+	if x != nil {
+		return x.MachineType
+	}
+	return ""
+}
+
+func (x *Machine) GetRegionName() string {
+	if x != nil {
+		return x.RegionName
+	}
+	return ""
+}
+
+func (x *Machine) GetHourSpotPrice() float64 {
+	if x != nil {
+		return x.HourSpotPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetHourPrice() float64 {
+	if x != nil {
+		return x.HourPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetSpotDiscountPct() float64 {
+	if x != nil {
+		return x.SpotDiscountPct
+	}
+	return 0
+}
+
+func (x *Machine) GetMinHourSpotPrice() float64 {
+	if x != nil {
+		return x.MinHourSpotPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetMaxHourSpotPrice() float64 {
+	if x != nil {
+		return x.MaxHourSpotPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetAvgHourSpotPrice() float64 {
+	if x != nil {
+		return x.AvgHourSpotPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetFirstSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return nil
+}
+
+func (x *Machine) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Machine) GetChangeCount() int64 {
+	if x != nil {
+		return x.ChangeCount
+	}
+	return 0
+}
+
+func (x *Machine) GetLastChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastChangedAt
+	}
+	return nil
+}
+
+func (x *Machine) GetPreviousPrice() float64 {
+	if x != nil && x.PreviousPrice != nil {
+		return *x.PreviousPrice
+	}
+	return 0
+}
+
+func (x *Machine) GetChangePct() float64 {
+	if x != nil && x.ChangePct != nil {
+		return *x.ChangePct
+	}
+	return 0
+}
+
+func (x *Machine) GetUnitPrices() *UnitPrices {
+	if x != nil {
+		return x.UnitPrices
+	}
+	return nil
+}
+
+type ListMachinesRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	RegionName string                 `protobuf:"bytes,1,opt,name=region_name,json=regionName,proto3" json:"region_name,omitempty"`
+	// units adds unit prices.
+	Units bool `protobuf:"varint,2,opt,name=units,proto3" json:"units,omitempty"`
+	// sort is a sort key of the JSON listing (e.g. "hour_spot_price"), prefixed with "-"
+	// for descending order. Machines are listed by name, descending, without it.
+	Sort          string `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMachinesRequest) Reset() {
+	*x = ListMachinesRequest{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMachinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMachinesRequest) ProtoMessage() {}
+
+func (x *ListMachinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMachinesRequest.ProtoReflect.Descriptor instead.
+func (*ListMachinesRequest) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListMachinesRequest) GetRegionName() string {
+	if x != nil {
+		return x.RegionName
+	}
+	return ""
+}
+
+func (x *ListMachinesRequest) GetUnits() bool {
+	if x != nil {
+		return x.Units
+	}
+	return false
+}
+
+func (x *ListMachinesRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+type ListMachinesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machines      []*Machine             `protobuf:"bytes,1,rep,name=machines,proto3" json:"machines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMachinesResponse) Reset() {
+	*x = ListMachinesResponse{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMachinesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMachinesResponse) ProtoMessage() {}
+
+func (x *ListMachinesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMachinesResponse.ProtoReflect.Descriptor instead.
+func (*ListMachinesResponse) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListMachinesResponse) GetMachines() []*Machine {
+	if x != nil {
+		return x.Machines
+	}
+	return nil
+}
+
+type StreamPriceHistoryRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	RegionName  string                 `protobuf:"bytes,1,opt,name=region_name,json=regionName,proto3" json:"region_name,omitempty"`
+	MachineType string                 `protobuf:"bytes,2,opt,name=machine_type,json=machineType,proto3" json:"machine_type,omitempty"`
+	// from and to bound the history (inclusive); unset bounds leave it open.
+	From *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	// resolution is raw (default), day, week or month.
+	Resolution string `protobuf:"bytes,5,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	// units adds unit prices.
+	Units         bool `protobuf:"varint,6,opt,name=units,proto3" json:"units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamPriceHistoryRequest) Reset() {
+	*x = StreamPriceHistoryRequest{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamPriceHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamPriceHistoryRequest) ProtoMessage() {}
+
+func (x *StreamPriceHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamPriceHistoryRequest.ProtoReflect.Descriptor instead.
+func (*StreamPriceHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamPriceHistoryRequest) GetRegionName() string {
+	if x != nil {
+		return x.RegionName
+	}
+	return ""
+}
+
+func (x *StreamPriceHistoryRequest) GetMachineType() string {
+	if x != nil {
+		return x.MachineType
+	}
+	return ""
+}
+
+func (x *StreamPriceHistoryRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *StreamPriceHistoryRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *StreamPriceHistoryRequest) GetResolution() string {
+	if x != nil {
+		return x.Resolution
+	}
+	return ""
+}
+
+func (x *StreamPriceHistoryRequest) GetUnits() bool {
+	if x != nil {
+		return x.Units
+	}
+	return false
+}
+
+// PricePoint is a point of a price history. Downsampled points cover a bucket: price is
+// the last price in the bucket and min_price/max_price its range.
+type PricePoint struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp       *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Price           float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	MinPrice        float64                `protobuf:"fixed64,3,opt,name=min_price,json=minPrice,proto3" json:"min_price,omitempty"`
+	MaxPrice        float64                `protobuf:"fixed64,4,opt,name=max_price,json=maxPrice,proto3" json:"max_price,omitempty"`
+	OnDemandPrice   float64                `protobuf:"fixed64,5,opt,name=on_demand_price,json=onDemandPrice,proto3" json:"on_demand_price,omitempty"`
+	SpotDiscountPct float64                `protobuf:"fixed64,6,opt,name=spot_discount_pct,json=spotDiscountPct,proto3" json:"spot_discount_pct,omitempty"`
+	Resolution      string                 `protobuf:"bytes,7,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	UnitPrices      *UnitPrices            `protobuf:"bytes,8,opt,name=unit_prices,json=unitPrices,proto3" json:"unit_prices,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PricePoint) Reset() {
+	*x = PricePoint{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PricePoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PricePoint) ProtoMessage() {}
+
+func (x *PricePoint) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PricePoint.ProtoReflect.Descriptor instead.
+func (*PricePoint) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PricePoint) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *PricePoint) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PricePoint) GetMinPrice() float64 {
+	if x != nil {
+		return x.MinPrice
+	}
+	return 0
+}
+
+func (x *PricePoint) GetMaxPrice() float64 {
+	if x != nil {
+		return x.MaxPrice
+	}
+	return 0
+}
+
+func (x *PricePoint) GetOnDemandPrice() float64 {
+	if x != nil {
+		return x.OnDemandPrice
+	}
+	return 0
+}
+
+func (x *PricePoint) GetSpotDiscountPct() float64 {
+	if x != nil {
+		return x.SpotDiscountPct
+	}
+	return 0
+}
+
+func (x *PricePoint) GetResolution() string {
+	if x != nil {
+		return x.Resolution
+	}
+	return ""
+}
+
+func (x *PricePoint) GetUnitPrices() *UnitPrices {
+	if x != nil {
+		return x.UnitPrices
+	}
+	return nil
+}
+
+type Series struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RegionName    string                 `protobuf:"bytes,1,opt,name=region_name,json=regionName,proto3" json:"region_name,omitempty"`
+	MachineType   string                 `protobuf:"bytes,2,opt,name=machine_type,json=machineType,proto3" json:"machine_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Series) Reset() {
+	*x = Series{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Series) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Series) ProtoMessage() {}
+
+func (x *Series) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Series.ProtoReflect.Descriptor instead.
+func (*Series) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Series) GetRegionName() string {
+	if x != nil {
+		return x.RegionName
+	}
+	return ""
+}
+
+func (x *Series) GetMachineType() string {
+	if x != nil {
+		return x.MachineType
+	}
+	return ""
+}
+
+type GetCurrentPricesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// series lists at most 500 machine types in regions.
+	Series []*Series `protobuf:"bytes,1,rep,name=series,proto3" json:"series,omitempty"`
+	// units adds unit prices.
+	Units         bool `protobuf:"varint,2,opt,name=units,proto3" json:"units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentPricesRequest) Reset() {
+	*x = GetCurrentPricesRequest{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentPricesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentPricesRequest) ProtoMessage() {}
+
+func (x *GetCurrentPricesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentPricesRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentPricesRequest) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetCurrentPricesRequest) GetSeries() []*Series {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *GetCurrentPricesRequest) GetUnits() bool {
+	if x != nil {
+		return x.Units
+	}
+	return false
+}
+
+type CurrentPrice struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	RegionName  string                 `protobuf:"bytes,1,opt,name=region_name,json=regionName,proto3" json:"region_name,omitempty"`
+	MachineType string                 `protobuf:"bytes,2,opt,name=machine_type,json=machineType,proto3" json:"machine_type,omitempty"`
+	// found is false if the machine type was never seen in the region.
+	Found         bool     `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	Machine       *Machine `protobuf:"bytes,4,opt,name=machine,proto3" json:"machine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CurrentPrice) Reset() {
+	*x = CurrentPrice{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CurrentPrice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CurrentPrice) ProtoMessage() {}
+
+func (x *CurrentPrice) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CurrentPrice.ProtoReflect.Descriptor instead.
+func (*CurrentPrice) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CurrentPrice) GetRegionName() string {
+	if x != nil {
+		return x.RegionName
+	}
+	return ""
+}
+
+func (x *CurrentPrice) GetMachineType() string {
+	if x != nil {
+		return x.MachineType
+	}
+	return ""
+}
+
+func (x *CurrentPrice) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *CurrentPrice) GetMachine() *Machine {
+	if x != nil {
+		return x.Machine
+	}
+	return nil
+}
+
+type GetCurrentPricesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// prices follow the order of the requested series.
+	Prices        []*CurrentPrice `protobuf:"bytes,1,rep,name=prices,proto3" json:"prices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentPricesResponse) Reset() {
+	*x = GetCurrentPricesResponse{}
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentPricesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentPricesResponse) ProtoMessage() {}
+
+func (x *GetCurrentPricesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pricing_v1_pricing_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentPricesResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentPricesResponse) Descriptor() ([]byte, []int) {
+	return file_api_pricing_v1_pricing_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetCurrentPricesResponse) GetPrices() []*CurrentPrice {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+var File_api_pricing_v1_pricing_proto protoreflect.FileDescriptor
+
+const file_api_pricing_v1_pricing_proto_rawDesc = "" +
+	"\n" +
+	"\x1capi/pricing/v1/pricing.proto\x12\n" +
+	"pricing.v1\x1a\x1fgoogle/protobuf/timestamp.proto\":\n" +
+	"\x06Region\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tcontinent\x18\x02 \x01(\tR\tcontinent\"2\n" +
+	"\x12ListRegionsRequest\x12\x1c\n" +
+	"\tcontinent\x18\x01 \x01(\tR\tcontinent\"C\n" +
+	"\x13ListRegionsResponse\x12,\n" +
+	"\aregions\x18\x01 \x03(\v2\x12.pricing.v1.RegionR\aregions\"\xa6\x01\n" +
+	"\n" +
+	"UnitPrices\x12\"\n" +
+	"\rspot_per_vcpu\x18\x01 \x01(\x01R\vspotPerVcpu\x12\x1e\n" +
+	"\vspot_per_gb\x18\x02 \x01(\x01R\tspotPerGb\x12+\n" +
+	"\x12on_demand_per_vcpu\x18\x03 \x01(\x01R\x0fonDemandPerVcpu\x12'\n" +
+	"\x10on_demand_per_gb\x18\x04 \x01(\x01R\ronDemandPerGb\"\xd3\x05\n" +
+	"\aMachine\x12!\n" +
+	"\fmachine_type\x18\x01 \x01(\tR\vmachineType\x12\x1f\n" +
+	"\vregion_name\x18\x02 \x01(\tR\n" +
+	"regionName\x12&\n" +
+	"\x0fhour_spot_price\x18\x03 \x01(\x01R\rhourSpotPrice\x12\x1d\n" +
+	"\n" +
+	"hour_price\x18\x04 \x01(\x01R\thourPrice\x12*\n" +
+	"\x11spot_discount_pct\x18\x05 \x01(\x01R\x0fspotDiscountPct\x12-\n" +
+	"\x13min_hour_spot_price\x18\x06 \x01(\x01R\x10minHourSpotPrice\x12-\n" +
+	"\x13max_hour_spot_price\x18\a \x01(\x01R\x10maxHourSpotPrice\x12-\n" +
+	"\x13avg_hour_spot_price\x18\b \x01(\x01R\x10avgHourSpotPrice\x129\n" +
+	"\n" +
+	"first_seen\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tfirstSeen\x127\n" +
+	"\tlast_seen\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12!\n" +
+	"\fchange_count\x18\v \x01(\x03R\vchangeCount\x12B\n" +
+	"\x0flast_changed_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\rlastChangedAt\x12*\n" +
+	"\x0eprevious_price\x18\r \x01(\x01H\x00R\rpreviousPrice\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"change_pct\x18\x0e \x01(\x01H\x01R\tchangePct\x88\x01\x01\x127\n" +
+	"\vunit_prices\x18\x0f \x01(\v2\x16.pricing.v1.UnitPricesR\n" +
+	"unitPricesB\x11\n" +
+	"\x0f_previous_priceB\r\n" +
+	"\v_change_pct\"`\n" +
+	"\x13ListMachinesRequest\x12\x1f\n" +
+	"\vregion_name\x18\x01 \x01(\tR\n" +
+	"regionName\x12\x14\n" +
+	"\x05units\x18\x02 \x01(\bR\x05units\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\"G\n" +
+	"\x14ListMachinesResponse\x12/\n" +
+	"\bmachines\x18\x01 \x03(\v2\x13.pricing.v1.MachineR\bmachines\"\xf1\x01\n" +
+	"\x19StreamPriceHistoryRequest\x12\x1f\n" +
+	"\vregion_name\x18\x01 \x01(\tR\n" +
+	"regionName\x12!\n" +
+	"\fmachine_type\x18\x02 \x01(\tR\vmachineType\x12.\n" +
+	"\x04from\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x05 \x01(\tR\n" +
+	"resolution\x12\x14\n" +
+	"\x05units\x18\x06 \x01(\bR\x05units\"\xc3\x02\n" +
+	"\n" +
+	"PricePoint\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x14\n" +
+	"\x05price\x18\x02 \x01(\x01R\x05price\x12\x1b\n" +
+	"\tmin_price\x18\x03 \x01(\x01R\bminPrice\x12\x1b\n" +
+	"\tmax_price\x18\x04 \x01(\x01R\bmaxPrice\x12&\n" +
+	"\x0fon_demand_price\x18\x05 \x01(\x01R\ronDemandPrice\x12*\n" +
+	"\x11spot_discount_pct\x18\x06 \x01(\x01R\x0fspotDiscountPct\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\a \x01(\tR\n" +
+	"resolution\x127\n" +
+	"\vunit_prices\x18\b \x01(\v2\x16.pricing.v1.UnitPricesR\n" +
+	"unitPrices\"L\n" +
+	"\x06Series\x12\x1f\n" +
+	"\vregion_name\x18\x01 \x01(\tR\n" +
+	"regionName\x12!\n" +
+	"\fmachine_type\x18\x02 \x01(\tR\vmachineType\"[\n" +
+	"\x17GetCurrentPricesRequest\x12*\n" +
+	"\x06series\x18\x01 \x03(\v2\x12.pricing.v1.SeriesR\x06series\x12\x14\n" +
+	"\x05units\x18\x02 \x01(\bR\x05units\"\x97\x01\n" +
+	"\fCurrentPrice\x12\x1f\n" +
+	"\vregion_name\x18\x01 \x01(\tR\n" +
+	"regionName\x12!\n" +
+	"\fmachine_type\x18\x02 \x01(\tR\vmachineType\x12\x14\n" +
+	"\x05found\x18\x03 \x01(\bR\x05found\x12-\n" +
+	"\amachine\x18\x04 \x01(\v2\x13.pricing.v1.MachineR\amachine\"L\n" +
+	"\x18GetCurrentPricesResponse\x120\n" +
+	"\x06prices\x18\x01 \x03(\v2\x18.pricing.v1.CurrentPriceR\x06prices2\xe9\x02\n" +
+	"\x0ePricingService\x12N\n" +
+	"\vListRegions\x12\x1e.pricing.v1.ListRegionsRequest\x1a\x1f.pricing.v1.ListRegionsResponse\x12Q\n" +
+	"\fListMachines\x12\x1f.pricing.v1.ListMachinesRequest\x1a .pricing.v1.ListMachinesResponse\x12U\n" +
+	"\x12StreamPriceHistory\x12%.pricing.v1.StreamPriceHistoryRequest\x1a\x16.pricing.v1.PricePoint0\x01\x12]\n" +
+	"\x10GetCurrentPrices\x12#.pricing.v1.GetCurrentPricesRequest\x1a$.pricing.v1.GetCurrentPricesResponseBSZQgithub.com/mgruszkiewicz/google-cloud-spot-price-history/api/pricing/v1;pricingv1b\x06proto3"
+
+var (
+	file_api_pricing_v1_pricing_proto_rawDescOnce sync.Once
+	file_api_pricing_v1_pricing_proto_rawDescData []byte
+)
+
+func file_api_pricing_v1_pricing_proto_rawDescGZIP() []byte {
+	file_api_pricing_v1_pricing_proto_rawDescOnce.Do(func() {
+		file_api_pricing_v1_pricing_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_pricing_v1_pricing_proto_rawDesc), len(file_api_pricing_v1_pricing_proto_rawDesc)))
+	})
+	return file_api_pricing_v1_pricing_proto_rawDescData
+}
+
+var file_api_pricing_v1_pricing_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_api_pricing_v1_pricing_proto_goTypes = []any{
+	(*Region)(nil),                    // 0: pricing.v1.Region
+	(*ListRegionsRequest)(nil),        // 1: pricing.v1.ListRegionsRequest
+	(*ListRegionsResponse)(nil),       // 2: pricing.v1.ListRegionsResponse
+	(*UnitPrices)(nil),                // 3: pricing.v1.UnitPrices
+	(*Machine)(nil),                   // 4: pricing.v1.Machine
+	(*ListMachinesRequest)(nil),       // 5: pricing.v1.ListMachinesRequest
+	(*ListMachinesResponse)(nil),      // 6: pricing.v1.ListMachinesResponse
+	(*StreamPriceHistoryRequest)(nil), // 7: pricing.v1.StreamPriceHistoryRequest
+	(*PricePoint)(nil),                // 8: pricing.v1.PricePoint
+	(*Series)(nil),                    // 9: pricing.v1.Series
+	(*GetCurrentPricesRequest)(nil),   // 10: pricing.v1.GetCurrentPricesRequest
+	(*CurrentPrice)(nil),              // 11: pricing.v1.CurrentPrice
+	(*GetCurrentPricesResponse)(nil),  // 12: pricing.v1.GetCurrentPricesResponse
+	(*timestamppb.Timestamp)(nil),     // 13: google.protobuf.Timestamp
+}
+var file_api_pricing_v1_pricing_proto_depIdxs = []int32{
+	0,  // 0: pricing.v1.ListRegionsResponse.regions:type_name -> pricing.v1.Region
+	13, // 1: pricing.v1.Machine.first_seen:type_name -> google.protobuf.Timestamp
+	13, // 2: pricing.v1.Machine.last_seen:type_name -> google.protobuf.Timestamp
+	13, // 3: pricing.v1.Machine.last_changed_at:type_name -> google.protobuf.Timestamp
+	3,  // 4: pricing.v1.Machine.unit_prices:type_name -> pricing.v1.UnitPrices
+	4,  // 5: pricing.v1.ListMachinesResponse.machines:type_name -> pricing.v1.Machine
+	13, // 6: pricing.v1.StreamPriceHistoryRequest.from:type_name -> google.protobuf.Timestamp
+	13, // 7: pricing.v1.StreamPriceHistoryRequest.to:type_name -> google.protobuf.Timestamp
+	13, // 8: pricing.v1.PricePoint.timestamp:type_name -> google.protobuf.Timestamp
+	3,  // 9: pricing.v1.PricePoint.unit_prices:type_name -> pricing.v1.UnitPrices
+	9,  // 10: pricing.v1.GetCurrentPricesRequest.series:type_name -> pricing.v1.Series
+	4,  // 11: pricing.v1.CurrentPrice.machine:type_name -> pricing.v1.Machine
+	11, // 12: pricing.v1.GetCurrentPricesResponse.prices:type_name -> pricing.v1.CurrentPrice
+	1,  // 13: pricing.v1.PricingService.ListRegions:input_type -> pricing.v1.ListRegionsRequest
+	5,  // 14: pricing.v1.PricingService.ListMachines:input_type -> pricing.v1.ListMachinesRequest
+	7,  // 15: pricing.v1.PricingService.StreamPriceHistory:input_type -> pricing.v1.StreamPriceHistoryRequest
+	10, // 16: pricing.v1.PricingService.GetCurrentPrices:input_type -> pricing.v1.GetCurrentPricesRequest
+	2,  // 17: pricing.v1.PricingService.ListRegions:output_type -> pricing.v1.ListRegionsResponse
+	6,  // 18: pricing.v1.PricingService.ListMachines:output_type -> pricing.v1.ListMachinesResponse
+	8,  // 19: pricing.v1.PricingService.StreamPriceHistory:output_type -> pricing.v1.PricePoint
+	12, // 20: pricing.v1.PricingService.GetCurrentPrices:output_type -> pricing.v1.GetCurrentPricesResponse
+	17, // [17:21] is the sub-list for method output_type
+	13, // [13:17] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_api_pricing_v1_pricing_proto_init() }
+func file_api_pricing_v1_pricing_proto_init() {
+	if File_api_pricing_v1_pricing_proto != nil {
+		return
+	}
+	file_api_pricing_v1_pricing_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_pricing_v1_pricing_proto_rawDesc), len(file_api_pricing_v1_pricing_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_pricing_v1_pricing_proto_goTypes,
+		DependencyIndexes: file_api_pricing_v1_pricing_proto_depIdxs,
+		MessageInfos:      file_api_pricing_v1_pricing_proto_msgTypes,
+	}.Build()
+	File_api_pricing_v1_pricing_proto = out.File
+	file_api_pricing_v1_pricing_proto_goTypes = nil
+	file_api_pricing_v1_pricing_proto_depIdxs = nil
+}
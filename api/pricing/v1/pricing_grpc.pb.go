@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             (unknown)
+// source: api/pricing/v1/pricing.proto
+
+package pricingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PricingService_ListRegions_FullMethodName        = "/pricing.v1.PricingService/ListRegions"
+	PricingService_ListMachines_FullMethodName       = "/pricing.v1.PricingService/ListMachines"
+	PricingService_StreamPriceHistory_FullMethodName = "/pricing.v1.PricingService/StreamPriceHistory"
+	PricingService_GetCurrentPrices_FullMethodName   = "/pricing.v1.PricingService/GetCurrentPrices"
+)
+
+// PricingServiceClient is the client API for PricingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PricingService serves the spot price history. It mirrors the /api/v1 JSON endpoints.
+type PricingServiceClient interface {
+	// ListRegions lists the regions with prices, optionally on one continent.
+	ListRegions(ctx context.Context, in *ListRegionsRequest, opts ...grpc.CallOption) (*ListRegionsResponse, error)
+	// ListMachines lists the machine types of a region with their current prices.
+	ListMachines(ctx context.Context, in *ListMachinesRequest, opts ...grpc.CallOption) (*ListMachinesResponse, error)
+	// StreamPriceHistory streams the price history of a machine type in a region, oldest first.
+	StreamPriceHistory(ctx context.Context, in *StreamPriceHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PricePoint], error)
+	// GetCurrentPrices returns the current prices of many machine types and regions at once.
+	GetCurrentPrices(ctx context.Context, in *GetCurrentPricesRequest, opts ...grpc.CallOption) (*GetCurrentPricesResponse, error)
+}
+
+type pricingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPricingServiceClient(cc grpc.ClientConnInterface) PricingServiceClient {
+	return &pricingServiceClient{cc}
+}
+
+func (c *pricingServiceClient) ListRegions(ctx context.Context, in *ListRegionsRequest, opts ...grpc.CallOption) (*ListRegionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRegionsResponse)
+	err := c.cc.Invoke(ctx, PricingService_ListRegions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) ListMachines(ctx context.Context, in *ListMachinesRequest, opts ...grpc.CallOption) (*ListMachinesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMachinesResponse)
+	err := c.cc.Invoke(ctx, PricingService_ListMachines_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) StreamPriceHistory(ctx context.Context, in *StreamPriceHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PricePoint], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PricingService_ServiceDesc.Streams[0], PricingService_StreamPriceHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamPriceHistoryRequest, PricePoint]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PricingService_StreamPriceHistoryClient = grpc.ServerStreamingClient[PricePoint]
+
+func (c *pricingServiceClient) GetCurrentPrices(ctx context.Context, in *GetCurrentPricesRequest, opts ...grpc.CallOption) (*GetCurrentPricesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCurrentPricesResponse)
+	err := c.cc.Invoke(ctx, PricingService_GetCurrentPrices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PricingServiceServer is the server API for PricingService service.
+// All implementations must embed UnimplementedPricingServiceServer
+// for forward compatibility.
+//
+// PricingService serves the spot price history. It mirrors the /api/v1 JSON endpoints.
+type PricingServiceServer interface {
+	// ListRegions lists the regions with prices, optionally on one continent.
+	ListRegions(context.Context, *ListRegionsRequest) (*ListRegionsResponse, error)
+	// ListMachines lists the machine types of a region with their current prices.
+	ListMachines(context.Context, *ListMachinesRequest) (*ListMachinesResponse, error)
+	// StreamPriceHistory streams the price history of a machine type in a region, oldest first.
+	StreamPriceHistory(*StreamPriceHistoryRequest, grpc.ServerStreamingServer[PricePoint]) error
+	// GetCurrentPrices returns the current prices of many machine types and regions at once.
+	GetCurrentPrices(context.Context, *GetCurrentPricesRequest) (*GetCurrentPricesResponse, error)
+	mustEmbedUnimplementedPricingServiceServer()
+}
+
+// UnimplementedPricingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPricingServiceServer struct{}
+
+func (UnimplementedPricingServiceServer) ListRegions(context.Context, *ListRegionsRequest) (*ListRegionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListRegions not implemented")
+}
+func (UnimplementedPricingServiceServer) ListMachines(context.Context, *ListMachinesRequest) (*ListMachinesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListMachines not implemented")
+}
+func (UnimplementedPricingServiceServer) StreamPriceHistory(*StreamPriceHistoryRequest, grpc.ServerStreamingServer[PricePoint]) error {
+	return status.Error(codes.Unimplemented, "method StreamPriceHistory not implemented")
+}
+func (UnimplementedPricingServiceServer) GetCurrentPrices(context.Context, *GetCurrentPricesRequest) (*GetCurrentPricesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCurrentPrices not implemented")
+}
+func (UnimplementedPricingServiceServer) mustEmbedUnimplementedPricingServiceServer() {}
+func (UnimplementedPricingServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePricingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PricingServiceServer will
+// result in compilation errors.
+type UnsafePricingServiceServer interface {
+	mustEmbedUnimplementedPricingServiceServer()
+}
+
+func RegisterPricingServiceServer(s grpc.ServiceRegistrar, srv PricingServiceServer) {
+	// If the following call panics, it indicates UnimplementedPricingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PricingService_ServiceDesc, srv)
+}
+
+func _PricingService_ListRegions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRegionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).ListRegions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_ListRegions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).ListRegions(ctx, req.(*ListRegionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_ListMachines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMachinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).ListMachines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_ListMachines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).ListMachines(ctx, req.(*ListMachinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_StreamPriceHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPriceHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PricingServiceServer).StreamPriceHistory(m, &grpc.GenericServerStream[StreamPriceHistoryRequest, PricePoint]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PricingService_StreamPriceHistoryServer = grpc.ServerStreamingServer[PricePoint]
+
+func _PricingService_GetCurrentPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCurrentPricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).GetCurrentPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_GetCurrentPrices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).GetCurrentPrices(ctx, req.(*GetCurrentPricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PricingService_ServiceDesc is the grpc.ServiceDesc for PricingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PricingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pricing.v1.PricingService",
+	HandlerType: (*PricingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListRegions",
+			Handler:    _PricingService_ListRegions_Handler,
+		},
+		{
+			MethodName: "ListMachines",
+			Handler:    _PricingService_ListMachines_Handler,
+		},
+		{
+			MethodName: "GetCurrentPrices",
+			Handler:    _PricingService_GetCurrentPrices_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPriceHistory",
+			Handler:       _PricingService_StreamPriceHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/pricing/v1/pricing.proto",
+}
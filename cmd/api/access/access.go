@@ -0,0 +1,249 @@
+// Package access authenticates HTTP and gRPC requests with API keys and enforces per-key
+// token-bucket rate limits and daily quotas, with a separate per-address rate limit for
+// anonymous clients.
+package access
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/apikey"
+)
+
+// sweepInterval is how often buckets that have refilled are dropped.
+const sweepInterval = time.Minute
+
+// Options configures a Limiter.
+type Options struct {
+	// AnonymousRate and AnonymousBurst limit requests without a key per remote address; a
+	// rate of 0 leaves them unlimited.
+	AnonymousRate  float64
+	AnonymousBurst int
+	// Exempt lists path prefixes that are always served without a key or limits.
+	Exempt []string
+	// Admin lists path prefixes that require a key with the admin flag.
+	Admin []string
+}
+
+// Limiter checks API keys and keeps a token bucket per key and per anonymous address.
+type Limiter struct {
+	pricing *service.PricingService
+	opts    Options
+
+	mu        sync.Mutex
+	buckets   map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// New returns a limiter looking keys up through pricing.
+func New(pricing *service.PricingService, opts Options) *Limiter {
+	return &Limiter{
+		pricing:   pricing,
+		opts:      opts,
+		buckets:   map[string]*rate.Limiter{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware returns a middleware requiring a key from the X-API-Key header or a bearer
+// token, unless anonymous is true. Requests with an unknown or revoked key are refused
+// even where anonymous access is allowed.
+func (l *Limiter) Middleware(anonymous bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasPrefix(r.URL.Path, l.opts.Exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			req := request{
+				key:       requestKey(r),
+				addr:      remoteAddr(r),
+				keySource: "the X-API-Key header",
+				anonymous: anonymous,
+				admin:     hasPrefix(r.URL.Path, l.opts.Admin),
+			}
+			if rejected := l.check(req, w.Header(), time.Now()); rejected != nil {
+				sendError(w, r, rejected.status, rejected.message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// request is what the limiter needs to know about a request, whatever its protocol.
+type request struct {
+	key  string
+	addr string
+	// keySource tells clients without a key where to pass one.
+	keySource string
+	anonymous bool
+	admin     bool
+}
+
+// rejection is the HTTP status and message a refused request is answered with.
+type rejection struct {
+	status  int
+	message string
+}
+
+// check looks the key of req up and takes the request from the key's or the address's
+// rate limit and the key's daily quota, setting the rate limit and quota headers in h.
+// It returns nil if the request may be served.
+func (l *Limiter) check(req request, h http.Header, now time.Time) *rejection {
+	if req.key == "" {
+		if !req.anonymous || req.admin {
+			h.Set("WWW-Authenticate", `Bearer realm="api"`)
+			return &rejection{http.StatusUnauthorized, "an API key is required, pass it in " + req.keySource}
+		}
+		if l.opts.AnonymousRate > 0 {
+			return l.take(h, "addr:"+req.addr, l.opts.AnonymousRate, l.opts.AnonymousBurst, now)
+		}
+		return nil
+	}
+
+	k, err := l.pricing.GetAPIKey(apikey.Hash(req.key))
+	if err != nil {
+		slog.Error("failed to look up API key", "error", err)
+		return &rejection{http.StatusInternalServerError, "failed to check the API key"}
+	}
+	if k == nil {
+		h.Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+		return &rejection{http.StatusUnauthorized, "unknown or revoked API key " + apikey.Display(req.key)}
+	}
+	if req.admin && !k.Admin {
+		return &rejection{http.StatusForbidden, "an admin API key is required"}
+	}
+
+	if k.RateLimit > 0 {
+		if rejected := l.take(h, "key:"+strconv.FormatInt(k.ID, 10), k.RateLimit, k.Burst, now); rejected != nil {
+			l.recordRejection(k, now)
+			return rejected
+		}
+	}
+
+	used, ok, err := l.pricing.RecordAPIKeyRequest(k, now)
+	if err != nil {
+		slog.Error("failed to record API key usage", "key_id", k.ID, "error", err)
+		return &rejection{http.StatusInternalServerError, "failed to check the API key quota"}
+	}
+	if k.DailyQuota > 0 {
+		reset := nextUTCDay(now)
+		h.Set("X-Quota-Limit", strconv.FormatInt(k.DailyQuota, 10))
+		h.Set("X-Quota-Remaining", strconv.FormatInt(max(k.DailyQuota-used, 0), 10))
+		h.Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if !ok {
+			h.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(reset.Sub(now).Seconds())), 10))
+			l.recordRejection(k, now)
+			return &rejection{http.StatusTooManyRequests, "daily quota of " + strconv.FormatInt(k.DailyQuota, 10) + " requests exceeded"}
+		}
+	}
+	return nil
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// take removes a token from the bucket of id, creating it or updating its limits as
+// needed, and sets the X-RateLimit headers. It refuses the request when the bucket is
+// empty.
+func (l *Limiter) take(h http.Header, id string, limit float64, burst int, now time.Time) *rejection {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	bucket, ok := l.buckets[id]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(limit), burst)
+		l.buckets[id] = bucket
+	}
+	if bucket.Limit() != rate.Limit(limit) {
+		bucket.SetLimitAt(now, rate.Limit(limit))
+	}
+	if bucket.Burst() != burst {
+		bucket.SetBurstAt(now, burst)
+	}
+	l.mu.Unlock()
+
+	reservation := bucket.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if !reservation.OK() || delay > 0 {
+		reservation.CancelAt(now)
+	}
+
+	h.Set("X-RateLimit-Limit", strconv.Itoa(burst))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(max(int(bucket.TokensAt(now)), 0)))
+	if !reservation.OK() || delay > 0 {
+		h.Set("Retry-After", strconv.FormatInt(max(int64(math.Ceil(delay.Seconds())), 1), 10))
+		return &rejection{http.StatusTooManyRequests, "rate limit exceeded, retry later"}
+	}
+	return nil
+}
+
+// sweep drops full buckets, which behave exactly like new ones. The caller holds l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	for id, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *Limiter) recordRejection(k *service.APIKey, now time.Time) {
+	if err := l.pricing.RecordAPIKeyRejection(k, now); err != nil {
+		slog.Error("failed to record rejected request", "key_id", k.ID, "error", err)
+	}
+}
+
+// requestKey returns the key from the X-API-Key header or an Authorization bearer token.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return strings.TrimSpace(key)
+	}
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func remoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func nextUTCDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+func sendError(w http.ResponseWriter, _ *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error:   http.StatusText(status),
+		Message: detail,
+		Code:    status,
+	})
+}
@@ -0,0 +1,269 @@
+package access
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/apikey"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/schema"
+)
+
+// Keys of newTestLimiter.
+const (
+	limitedKey = "sph_limited"
+	quotaKey   = "sph_quota"
+	adminKey   = "sph_admin"
+	revokedKey = "sph_revoked"
+)
+
+func newTestLimiter(t *testing.T, opts Options) (*Limiter, *sql.DB) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := schema.InitAPIKeyTables(sqlDB); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []struct {
+		key       string
+		rate      float64
+		burst     int
+		quota     int64
+		admin     bool
+		revokedAt interface{}
+	}{
+		{key: limitedKey, rate: 1, burst: 2},
+		{key: quotaKey, quota: 2},
+		{key: adminKey, admin: true},
+		{key: revokedKey, revokedAt: 1},
+	} {
+		if _, err := sqlDB.Exec(`INSERT INTO api_keys (name, prefix, key_hash, rate_limit, burst, daily_quota, admin, revoked_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, k.key, apikey.Display(k.key), apikey.Hash(k.key), k.rate, k.burst, k.quota, k.admin, k.revokedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(service.NewPricingService(db.NewQuerier(sqlDB)), opts), sqlDB
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter, _ := newTestLimiter(t, Options{Exempt: []string{"/api/v1/health"}, Admin: []string{"/api/v1/alerts"}})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name      string
+		anonymous bool
+		path      string
+		header    map[string]string
+		want      int
+	}{
+		{name: "anonymous allowed", anonymous: true, path: "/api/v1/regions", want: http.StatusOK},
+		{name: "anonymous refused", path: "/api/v1/regions", want: http.StatusUnauthorized},
+		{name: "exempt path", path: "/api/v1/health", want: http.StatusOK},
+		{name: "key header", path: "/api/v1/regions", header: map[string]string{"X-API-Key": quotaKey}, want: http.StatusOK},
+		{name: "bearer token", path: "/api/v1/regions", header: map[string]string{"Authorization": "bearer " + quotaKey}, want: http.StatusOK},
+		{name: "other scheme", path: "/api/v1/regions", header: map[string]string{"Authorization": "Basic " + quotaKey}, want: http.StatusUnauthorized},
+		{name: "unknown key", anonymous: true, path: "/api/v1/regions", header: map[string]string{"X-API-Key": "sph_unknown"}, want: http.StatusUnauthorized},
+		{name: "revoked key", anonymous: true, path: "/api/v1/regions", header: map[string]string{"X-API-Key": revokedKey}, want: http.StatusUnauthorized},
+		{name: "admin path without a key", anonymous: true, path: "/api/v1/alerts", want: http.StatusUnauthorized},
+		{name: "admin path with a key", anonymous: true, path: "/api/v1/alerts/1", header: map[string]string{"X-API-Key": limitedKey}, want: http.StatusForbidden},
+		{name: "admin path with an admin key", path: "/api/v1/alerts", header: map[string]string{"X-API-Key": adminKey}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			limiter.Middleware(tt.anonymous)(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter, sqlDB := newTestLimiter(t, Options{AnonymousRate: 0.5, AnonymousBurst: 1})
+	// Buckets are only swept after the limiter's creation, so the steps follow the clock.
+	now := time.Now()
+
+	steps := []struct {
+		name          string
+		req           request
+		at            time.Duration
+		wantRejected  bool
+		wantRemaining string
+		wantRetry     string
+	}{
+		// The key allows bursts of 2, then 1 request per second.
+		{name: "first of the burst", req: request{key: limitedKey}, wantRemaining: "1"},
+		{name: "second of the burst", req: request{key: limitedKey}, wantRemaining: "0"},
+		{name: "bucket empty", req: request{key: limitedKey}, wantRejected: true, wantRemaining: "0", wantRetry: "1"},
+		{name: "refilled", req: request{key: limitedKey}, at: time.Second, wantRemaining: "0"},
+		// Anonymous clients get a bucket per address.
+		{name: "anonymous", req: request{addr: "192.0.2.1", anonymous: true}, wantRemaining: "0"},
+		{name: "anonymous bucket empty", req: request{addr: "192.0.2.1", anonymous: true}, wantRejected: true, wantRemaining: "0", wantRetry: "2"},
+		{name: "other address", req: request{addr: "192.0.2.2", anonymous: true}, wantRemaining: "0"},
+	}
+	for _, step := range steps {
+		h := http.Header{}
+		rejected := limiter.check(step.req, h, now.Add(step.at))
+		if (rejected != nil) != step.wantRejected {
+			t.Fatalf("%s: rejected = %v, want %v", step.name, rejected, step.wantRejected)
+		}
+		if rejected != nil && rejected.status != http.StatusTooManyRequests {
+			t.Errorf("%s: status = %d, want 429", step.name, rejected.status)
+		}
+		if got := h.Get("X-RateLimit-Remaining"); got != step.wantRemaining {
+			t.Errorf("%s: X-RateLimit-Remaining = %q, want %q", step.name, got, step.wantRemaining)
+		}
+		if got := h.Get("Retry-After"); got != step.wantRetry {
+			t.Errorf("%s: Retry-After = %q, want %q", step.name, got, step.wantRetry)
+		}
+	}
+
+	var requests, rejected int64
+	if err := sqlDB.QueryRow("SELECT requests, rejected FROM api_key_usage").Scan(&requests, &rejected); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || rejected != 1 {
+		t.Errorf("usage = %d requests, %d rejected, want 3 and 1", requests, rejected)
+	}
+
+	// Full buckets are dropped by the next sweep.
+	limiter.check(request{addr: "192.0.2.3", anonymous: true}, http.Header{}, now.Add(time.Hour))
+	if len(limiter.buckets) != 1 {
+		t.Errorf("%d buckets after the sweep, want only the new one", len(limiter.buckets))
+	}
+}
+
+func TestQuota(t *testing.T) {
+	limiter, sqlDB := newTestLimiter(t, Options{})
+	now := time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)
+	midnight := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		at            time.Time
+		wantRejected  bool
+		wantRemaining string
+	}{
+		{at: now, wantRemaining: "1"},
+		{at: now, wantRemaining: "0"},
+		{at: now, wantRejected: true, wantRemaining: "0"},
+		{at: midnight, wantRemaining: "1"},
+	}
+	for i, step := range steps {
+		h := http.Header{}
+		rejected := limiter.check(request{key: quotaKey}, h, step.at)
+		if (rejected != nil) != step.wantRejected {
+			t.Fatalf("request %d: rejected = %v, want %v", i+1, rejected, step.wantRejected)
+		}
+		if got := h.Get("X-Quota-Remaining"); got != step.wantRemaining {
+			t.Errorf("request %d: X-Quota-Remaining = %q, want %q", i+1, got, step.wantRemaining)
+		}
+		if h.Get("X-Quota-Limit") != "2" {
+			t.Errorf("request %d: X-Quota-Limit = %q", i+1, h.Get("X-Quota-Limit"))
+		}
+		if step.wantRejected {
+			if rejected.status != http.StatusTooManyRequests || h.Get("Retry-After") != "3600" {
+				t.Errorf("request %d: status %d, Retry-After %q, want 429 and 3600", i+1, rejected.status, h.Get("Retry-After"))
+			}
+			if h.Get("X-Quota-Reset") != "1719792000" {
+				t.Errorf("request %d: X-Quota-Reset = %q, want the next UTC midnight", i+1, h.Get("X-Quota-Reset"))
+			}
+		}
+	}
+
+	rows, err := sqlDB.Query("SELECT day, requests, rejected FROM api_key_usage ORDER BY day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	type usage struct {
+		day                string
+		requests, rejected int
+	}
+	var got []usage
+	for rows.Next() {
+		var u usage
+		if err := rows.Scan(&u.day, &u.requests, &u.rejected); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, u)
+	}
+	want := []usage{{"2024-06-30", 2, 1}, {"2024-07-01", 1, 0}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("usage = %v, want %v", got, want)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	limiter, _ := newTestLimiter(t, Options{
+		Exempt: []string{"/grpc.reflection."},
+		Admin:  []string{"/admin."},
+	})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	tests := []struct {
+		name      string
+		method    string
+		anonymous bool
+		md        metadata.MD
+		want      codes.Code
+	}{
+		{name: "anonymous allowed", method: "/pricing.v1.PricingService/ListRegions", anonymous: true, want: codes.OK},
+		{name: "anonymous refused", method: "/pricing.v1.PricingService/ListRegions", want: codes.Unauthenticated},
+		{name: "exempt method", method: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", want: codes.OK},
+		{name: "key metadata", method: "/pricing.v1.PricingService/ListRegions", md: metadata.Pairs("x-api-key", quotaKey), want: codes.OK},
+		{name: "bearer token", method: "/pricing.v1.PricingService/ListRegions", md: metadata.Pairs("authorization", "Bearer "+quotaKey), want: codes.OK},
+		{name: "revoked key", method: "/pricing.v1.PricingService/ListRegions", anonymous: true, md: metadata.Pairs("x-api-key", revokedKey), want: codes.Unauthenticated},
+		{name: "admin method", method: "/admin.Service/Do", md: metadata.Pairs("x-api-key", limitedKey), want: codes.PermissionDenied},
+		// The two calls with quotaKey above used its daily quota.
+		{name: "quota exceeded", method: "/pricing.v1.PricingService/ListRegions", md: metadata.Pairs("x-api-key", quotaKey), want: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			_, err := limiter.UnaryInterceptor(tt.anonymous)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	for httpStatus, want := range map[int]codes.Code{
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusTooManyRequests:     codes.ResourceExhausted,
+		http.StatusInternalServerError: codes.Internal,
+	} {
+		if got := statusCode(httpStatus); got != want {
+			t.Errorf("statusCode(%d) = %v, want %v", httpStatus, got, want)
+		}
+	}
+}
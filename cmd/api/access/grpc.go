@@ -0,0 +1,104 @@
+package access
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor checks unary gRPC calls like Middleware checks HTTP requests, reading
+// the key from the x-api-key or authorization metadata. The rate limit and quota headers
+// are sent as response metadata, and Exempt and Admin prefixes match the full method name.
+func (l *Limiter) UnaryInterceptor(anonymous bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.checkCall(ctx, info.FullMethod, anonymous); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor checks streaming gRPC calls like UnaryInterceptor, once per stream.
+func (l *Limiter) StreamInterceptor(anonymous bool) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.checkCall(stream.Context(), info.FullMethod, anonymous); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (l *Limiter) checkCall(ctx context.Context, method string, anonymous bool) error {
+	if hasPrefix(method, l.opts.Exempt) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	req := request{
+		key:       metadataKey(md),
+		addr:      peerAddr(ctx),
+		keySource: "the x-api-key metadata",
+		anonymous: anonymous,
+		admin:     hasPrefix(method, l.opts.Admin),
+	}
+	h := http.Header{}
+	rejected := l.check(req, h, time.Now())
+
+	header := metadata.MD{}
+	for name, values := range h {
+		header.Append(strings.ToLower(name), values...)
+	}
+	if len(header) > 0 {
+		grpc.SetHeader(ctx, header)
+	}
+	if rejected != nil {
+		return status.Error(statusCode(rejected.status), rejected.message)
+	}
+	return nil
+}
+
+// metadataKey returns the key from the x-api-key metadata or an authorization bearer token.
+func metadataKey(md metadata.MD) string {
+	if values := md.Get("x-api-key"); len(values) > 0 && values[0] != "" {
+		return strings.TrimSpace(values[0])
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, token, found := strings.Cut(values[0], " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-fuego/fuego"
+	"github.com/labstack/echo/v4"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+// apiErrorHandler is the fuego error handler. It maps the typed errors of the pricing
+// service to HTTP errors and hides the details of internal errors, which are logged.
+func apiErrorHandler(ctx context.Context, err error) error {
+	return fuego.HandleHTTPError(ctx, httpError(err))
+}
+
+// httpError maps an error to a fuego HTTP error. Errors that already carry a status code,
+// like fuego.BadRequestError, are kept.
+func httpError(err error) error {
+	var withStatus fuego.ErrorWithStatus
+	var invalid *service.InvalidParameterError
+	switch {
+	case errors.As(err, &withStatus):
+		return err
+	case errors.Is(err, service.ErrNotFound):
+		return fuego.NotFoundError{Detail: err.Error(), Err: err}
+	case errors.As(err, &invalid):
+		return fuego.BadRequestError{
+			Detail: invalid.Message,
+			Errors: []fuego.ErrorItem{{Name: invalid.Param, Reason: invalid.Message}},
+			Err:    err,
+		}
+	case errors.Is(err, service.ErrInsufficientHistory):
+		return fuego.BadRequestError{Detail: err.Error(), Err: err}
+	case errors.Is(err, service.ErrUnavailable):
+		return fuego.HTTPError{
+			Status: http.StatusServiceUnavailable,
+			Detail: "the database is unavailable, try again later",
+			Err:    err,
+		}
+	}
+	return fuego.HTTPError{
+		Status: http.StatusInternalServerError,
+		Detail: "internal server error",
+		Err:    err,
+	}
+}
+
+// badRequest reports an invalid request parameter as 400, keeping the parameter name of
+// InvalidParameterErrors.
+func badRequest(err error) error {
+	if errors.Is(err, service.ErrInvalidParameter) {
+		return err
+	}
+	return fuego.BadRequestError{Detail: err.Error(), Err: err}
+}
+
+// errorResponse builds the body of an error response from an HTTP error.
+func errorResponse(err error) models.ErrorResponse {
+	status := http.StatusInternalServerError
+	var withStatus fuego.ErrorWithStatus
+	if errors.As(err, &withStatus) {
+		status = withStatus.StatusCode()
+	}
+	response := models.ErrorResponse{
+		Error:   http.StatusText(status),
+		Message: http.StatusText(status),
+		Code:    status,
+	}
+	var withDetail fuego.ErrorWithDetail
+	if errors.As(err, &withDetail) && withDetail.DetailMsg() != "" {
+		response.Message = withDetail.DetailMsg()
+	}
+	var httpErr fuego.HTTPError
+	if errors.As(err, &httpErr) && len(httpErr.Errors) > 0 {
+		response.Param = httpErr.Errors[0].Name
+	}
+	return response
+}
+
+// sendError writes an error as models.ErrorResponse. It is the fuego error serializer, and
+// is used by the handlers outside fuego's routing.
+func sendError(w http.ResponseWriter, _ *http.Request, err error) {
+	response := errorResponse(httpError(err))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Code)
+	json.NewEncoder(w).Encode(response)
+}
+
+// htmlError reports an error of an HTML route as plain text, with the status code the JSON
+// API would use.
+func htmlError(c echo.Context, err error) error {
+	response := errorResponse(httpError(err))
+	if response.Code >= http.StatusInternalServerError {
+		slog.Error("failed to render page", "path", c.Request().URL.Path, "error", err)
+	}
+	return c.String(response.Code, response.Message)
+}
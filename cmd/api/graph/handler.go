@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+// maxRequestBytes limits the size of a GraphQL request body.
+const maxRequestBytes = 1 << 20
+
+// request is a GraphQL request as sent by POST (JSON body) or GET (query parameters).
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes GraphQL queries against the pricing model.
+type Handler struct {
+	schema  graphql.Schema
+	pricing *service.PricingService
+}
+
+// NewHandler builds the GraphQL schema.
+func NewHandler(pricing *service.PricingService) (*Handler, error) {
+	schema, err := newSchema(pricing)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, pricing: pricing}, nil
+}
+
+// ServeHTTP runs the query of a GET or POST request. Query errors, including queries over
+// the depth and field limits, are reported in the errors field of a 200 response, as
+// GraphQL clients expect; malformed requests get a 400.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, "variables must be a JSON object", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			http.Error(w, "invalid GraphQL request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Syntax errors are left to graphql.Do, which reports them with their location.
+	if doc, err := parser.Parse(parser.ParseParams{Source: req.Query}); err == nil {
+		if err := checkLimits(doc); err != nil {
+			json.NewEncoder(w).Encode(&graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoaders(r.Context(), h.pricing),
+	})
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,223 @@
+package graph
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+var testSchema = []string{
+	`CREATE TABLE price_summary (
+		machine_type varchar(64),
+		region_name varchar(64),
+		current_hour_price REAL,
+		current_spot_hour_price REAL,
+		min_spot_hour_price REAL,
+		max_spot_hour_price REAL,
+		avg_spot_hour_price REAL,
+		first_seen_ts INTEGER,
+		last_seen_ts INTEGER,
+		observations INTEGER,
+		change_count INTEGER,
+		last_change_ts INTEGER,
+		previous_spot_hour_price REAL,
+		PRIMARY KEY(region_name, machine_type)
+	)`,
+	`CREATE TABLE machine_type (
+		id INTEGER PRIMARY KEY,
+		family varchar(64),
+		machine_type varchar(64),
+		cpu_cores REAL,
+		memory_gb REAL,
+		UNIQUE(family, machine_type, cpu_cores, memory_gb)
+	)`,
+	`CREATE TABLE price_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		machine_type varchar(64),
+		family varchar(64),
+		region_name varchar(64),
+		updated_ts INTEGER,
+		old_hour_price REAL,
+		new_hour_price REAL,
+		old_spot_hour_price REAL,
+		new_spot_hour_price REAL
+	)`,
+}
+
+// statementCounter counts the statements a Querier runs.
+type statementCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *statementCounter) observe(name, operation string, duration time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+}
+
+func (c *statementCounter) reset() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := c.count
+	c.count = 0
+	return count
+}
+
+// newTestHandler returns a handler over regions × machines series with two price changes each.
+func newTestHandler(t *testing.T, regions, machines int) (*Handler, *statementCounter) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, statement := range testSchema {
+		if _, err := sqlDB.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for m := 0; m < machines; m++ {
+		machineType := fmt.Sprintf("n2-standard-%d", 2<<m)
+		if _, err := sqlDB.Exec("INSERT INTO machine_type (family, machine_type, cpu_cores, memory_gb) VALUES ('n2', ?, ?, ?)",
+			machineType, 2<<m, 8<<m); err != nil {
+			t.Fatal(err)
+		}
+		for r := 0; r < regions; r++ {
+			region := fmt.Sprintf("europe-west%d", r+1)
+			if _, err := sqlDB.Exec(`INSERT INTO price_summary VALUES (?, ?, 1, 0.3, 0.2, 0.4, 0.3, 0, 200, 3, 2, 200, 0.4)`,
+				machineType, region); err != nil {
+				t.Fatal(err)
+			}
+			for _, ts := range []int64{100, 200} {
+				if _, err := sqlDB.Exec(`INSERT INTO price_changes (machine_type, family, region_name, updated_ts, old_hour_price, new_hour_price, old_spot_hour_price, new_spot_hour_price)
+					VALUES (?, 'n2', ?, ?, 1, 1, 0.2, 0.4)`, machineType, region, ts); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	querier := db.NewQuerier(sqlDB)
+	counter := &statementCounter{}
+	querier.SetObserver(counter.observe)
+	handler, err := NewHandler(service.NewPricingService(querier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, counter
+}
+
+// query runs a GraphQL query and returns its decoded response.
+func query(t *testing.T, handler *Handler, q string) map[string]interface{} {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(q), nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestNestedQueryStatements(t *testing.T) {
+	const nested = `{
+		regions {
+			name
+			machineTypes {
+				name
+				cpuCores
+				changes(limit: 5) {
+					id
+					region { name }
+					machineType { hourSpotPrice }
+				}
+			}
+		}
+	}`
+
+	// The loaders fetch each level with one statement, however many objects it has.
+	var counts []int
+	for _, size := range []int{1, 2, 4} {
+		handler, counter := newTestHandler(t, size, size)
+		counter.reset()
+		response := query(t, handler, nested)
+		if errs, ok := response["errors"]; ok {
+			t.Fatalf("size %d: errors %v", size, errs)
+		}
+		regions := response["data"].(map[string]interface{})["regions"].([]interface{})
+		if len(regions) != size {
+			t.Fatalf("size %d: got %d regions", size, len(regions))
+		}
+		counts = append(counts, counter.reset())
+	}
+	// Regions, their machine types, specs, changes and the current prices of changed series.
+	const want = 5
+	for _, count := range counts {
+		if count != want {
+			t.Fatalf("statements per query = %v, want %d for every size", counts, want)
+		}
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	handler, counter := newTestHandler(t, 1, 1)
+
+	deep := "{ regions { machineTypes { region { machineTypes { region { machineTypes { region { machineTypes { region { machineTypes { name } } } } } } } } } } }"
+	var wide strings.Builder
+	wide.WriteString("{")
+	for i := 0; i <= maxQueryNodes; i++ {
+		fmt.Fprintf(&wide, " r%d: regions { name }", i)
+	}
+	wide.WriteString(" }")
+	fragments := "fragment m on MachineType { name region { name } } " +
+		"{ regions { machineTypes { ...m changes { machineType { ...m } } } } }"
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{name: "shallow", query: "{ regions { name } }"},
+		{name: "fragments within limits", query: fragments},
+		{name: "introspection is not counted", query: "{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name ofType { name ofType { name } } } } } } } } } }"},
+		{name: "too deep", query: deep, wantErr: "nested deeper than"},
+		{name: "too many fields", query: wide.String(), wantErr: "more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter.reset()
+			response := query(t, handler, tt.query)
+			errs, _ := response["errors"].([]interface{})
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].(map[string]interface{})["message"].(string), tt.wantErr) {
+				t.Fatalf("errors = %v, want one containing %q", errs, tt.wantErr)
+			}
+			if count := counter.reset(); count != 0 {
+				t.Errorf("rejected query ran %d statements", count)
+			}
+		})
+	}
+}
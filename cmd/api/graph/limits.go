@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits of the queries the handler executes. Every field of the schema is backed by a
+// loader, so a query costs a few statements per level; these bound the levels and the
+// fields (aliases and fragment spreads included) a single request can ask for.
+const (
+	maxQueryDepth = 10
+	maxQueryNodes = 500
+)
+
+// checkLimits returns an error if an operation of doc selects fields nested deeper than
+// maxQueryDepth or more than maxQueryNodes fields in total once fragments are expanded.
+// Introspection fields are not counted.
+func checkLimits(doc *ast.Document) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		walker := &limitWalker{fragments: fragments, expanding: map[string]bool{}}
+		if err := walker.walk(operation.SelectionSet, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitWalker counts the fields of one operation.
+type limitWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	// expanding are the fragments being expanded, to stop at cycles, which validation rejects.
+	expanding map[string]bool
+	nodes     int
+}
+
+func (w *limitWalker) walk(set *ast.SelectionSet, depth int) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.Name != nil && strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			if depth > maxQueryDepth {
+				return fmt.Errorf("query is nested deeper than %d levels", maxQueryDepth)
+			}
+			w.nodes++
+			if w.nodes > maxQueryNodes {
+				return fmt.Errorf("query selects more than %d fields", maxQueryNodes)
+			}
+			if err := w.walk(selection.SelectionSet, depth+1); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := w.walk(selection.SelectionSet, depth); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if selection.Name == nil {
+				continue
+			}
+			name := selection.Name.Value
+			fragment, ok := w.fragments[name]
+			if !ok || w.expanding[name] {
+				continue
+			}
+			w.expanding[name] = true
+			err := w.walk(fragment.SelectionSet, depth)
+			w.expanding[name] = false
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
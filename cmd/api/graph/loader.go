@@ -0,0 +1,79 @@
+package graph
+
+// loader batches lookups the way dataloaders do. Resolvers register their key with load
+// and return the thunk it gives them; graphql-go runs all thunks of one depth after every
+// resolver of that depth has been called, so the first thunk fetches the keys of the whole
+// level with a single call. A loader lives for one request and is not safe for concurrent
+// use, which graphql-go does not need.
+type loader[K comparable, V any] struct {
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	fetched map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, fetched: map[K]bool{}, results: map[K]V{}, errs: map[K]error{}}
+}
+
+// loadTyped registers key and returns a function returning its value once the batch
+// containing it has been fetched. ok is false if the fetch returned no value for key.
+func (l *loader[K, V]) loadTyped(key K) func() (value V, ok bool, err error) {
+	if !l.fetched[key] {
+		l.pending = append(l.pending, key)
+	}
+	return func() (V, bool, error) {
+		if len(l.pending) > 0 {
+			l.dispatch()
+		}
+		value, ok := l.results[key]
+		return value, ok, l.errs[key]
+	}
+}
+
+// load registers key and returns a thunk resolving to its value, or to null if there is none.
+func (l *loader[K, V]) load(key K) func() (interface{}, error) {
+	return l.loadField(key, func(value V) interface{} { return value })
+}
+
+// loadField is like load but resolves to get applied to the value.
+func (l *loader[K, V]) loadField(key K, get func(V) interface{}) func() (interface{}, error) {
+	typed := l.loadTyped(key)
+	return func() (interface{}, error) {
+		value, ok, err := typed()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return get(value), nil
+	}
+}
+
+// dispatch fetches every pending key that has not been fetched yet.
+func (l *loader[K, V]) dispatch() {
+	seen := map[K]bool{}
+	var keys []K
+	for _, key := range l.pending {
+		if l.fetched[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		l.fetched[key] = true
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if value, ok := values[key]; ok {
+			l.results[key] = value
+		}
+	}
+}
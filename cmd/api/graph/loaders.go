@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+type seriesKey struct {
+	regionName  string
+	machineType string
+}
+
+// historyKey selects a price history; zero timestamps leave the window open.
+type historyKey struct {
+	regionName  string
+	machineType string
+	fromTS      int64
+	toTS        int64
+	resolution  string
+}
+
+// changesKey selects the latest changes of a series; zero timestamps leave the window open.
+type changesKey struct {
+	regionName  string
+	machineType string
+	fromTS      int64
+	toTS        int64
+	limit       int
+}
+
+// loaders are the loaders of one request.
+type loaders struct {
+	// machines are the machine types of a region.
+	machines *loader[string, []models.Machine]
+	// current are the current prices of a machine type in a region.
+	current *loader[seriesKey, models.Machine]
+	specs   *loader[string, models.MachineSpec]
+	history *loader[historyKey, []models.PriceHistory]
+	changes *loader[changesKey, []models.PriceChange]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, pricing *service.PricingService) context.Context {
+	return context.WithValue(ctx, loadersKey{}, newLoaders(pricing))
+}
+
+func loadersFrom(p graphql.ResolveParams) *loaders {
+	return p.Context.Value(loadersKey{}).(*loaders)
+}
+
+func newLoaders(pricing *service.PricingService) *loaders {
+	return &loaders{
+		machines: newLoader(pricing.GetMachinesByRegions),
+
+		current: newLoader(func(keys []seriesKey) (map[seriesKey]models.Machine, error) {
+			current := map[seriesKey]models.Machine{}
+			err := inChunks(keys, func(chunk []seriesKey) error {
+				opts := service.BatchOptions{Series: make([]service.BatchSeries, len(chunk))}
+				for i, key := range chunk {
+					opts.Series[i] = service.BatchSeries{RegionName: key.regionName, MachineType: key.machineType}
+				}
+				response, err := pricing.GetBatchPrices(opts)
+				if err != nil {
+					return err
+				}
+				for i, result := range response.Results {
+					if result.Current != nil {
+						current[chunk[i]] = *result.Current
+					}
+				}
+				return nil
+			})
+			return current, err
+		}),
+
+		specs: newLoader(func(keys []string) (map[string]models.MachineSpec, error) {
+			return pricing.GetMachineSpecs(keys...)
+		}),
+
+		history: newLoader(func(keys []historyKey) (map[historyKey][]models.PriceHistory, error) {
+			history := map[historyKey][]models.PriceHistory{}
+			byResolution := map[string][]historyKey{}
+			for _, key := range keys {
+				byResolution[key.resolution] = append(byResolution[key.resolution], key)
+				history[key] = []models.PriceHistory{}
+			}
+			for resolution, keys := range byResolution {
+				err := inChunks(keys, func(chunk []historyKey) error {
+					opts := service.BatchOptions{Series: make([]service.BatchSeries, len(chunk)), History: true, Resolution: resolution}
+					for i, key := range chunk {
+						opts.Series[i] = service.BatchSeries{
+							RegionName:  key.regionName,
+							MachineType: key.machineType,
+							From:        unixOrZero(key.fromTS),
+							To:          unixOrZero(key.toTS),
+						}
+					}
+					response, err := pricing.GetBatchPrices(opts)
+					if err != nil {
+						return err
+					}
+					for i, result := range response.Results {
+						if result.History != nil {
+							history[chunk[i]] = result.History
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return history, nil
+		}),
+
+		changes: newLoader(func(keys []changesKey) (map[changesKey][]models.PriceChange, error) {
+			changes := map[changesKey][]models.PriceChange{}
+			byLimit := map[int][]changesKey{}
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key)
+				changes[key] = []models.PriceChange{}
+			}
+			for limit, keys := range byLimit {
+				err := inChunks(keys, func(chunk []changesKey) error {
+					series := make([]service.BatchSeries, len(chunk))
+					for i, key := range chunk {
+						series[i] = service.BatchSeries{
+							RegionName:  key.regionName,
+							MachineType: key.machineType,
+							From:        unixOrZero(key.fromTS),
+							To:          unixOrZero(key.toTS),
+						}
+					}
+					result, err := pricing.GetBatchChanges(series, limit)
+					if err != nil {
+						return err
+					}
+					for i, seriesChanges := range result {
+						if seriesChanges != nil {
+							changes[chunk[i]] = seriesChanges
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return changes, nil
+		}),
+	}
+}
+
+// inChunks calls f for consecutive chunks of at most service.MaxBatchSeries keys, which
+// keeps the VALUES lists of batch queries within SQLite's limits.
+func inChunks[K any](keys []K, f func(chunk []K) error) error {
+	for start := 0; start < len(keys); start += service.MaxBatchSeries {
+		end := min(start+service.MaxBatchSeries, len(keys))
+		if err := f(keys[start:end]); err != nil {
+			return fmt.Errorf("failed to load batch: %w", err)
+		}
+	}
+	return nil
+}
+
+// unixOrZero converts a window bound back to a time, 0 being an open bound.
+func unixOrZero(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0).UTC()
+}
@@ -0,0 +1,418 @@
+// Package graph serves the pricing model over GraphQL. Nested fields are resolved
+// through per-request loaders, so a query touching many regions or machine types costs
+// a few set-based queries per level rather than one per object.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+// source returns the source object of a field, which may be passed by value or pointer.
+func source[T any](p graphql.ResolveParams) T {
+	if ptr, ok := p.Source.(*T); ok {
+		return *ptr
+	}
+	return p.Source.(T)
+}
+
+// fieldOf defines a field read from its source object by get.
+func fieldOf[T any](typ graphql.Output, description string, get func(T) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type:        typ,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(source[T](p)), nil
+		},
+	}
+}
+
+// window parses the optional from and to arguments of a field.
+func window(p graphql.ResolveParams) (int64, int64, error) {
+	from, _ := p.Args["from"].(string)
+	to, _ := p.Args["to"].(string)
+	fromTime, err := timeutil.ParseTime("from", from, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	toTime, err := timeutil.ParseTime("to", to, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !fromTime.IsZero() && !toTime.IsZero() && toTime.Before(fromTime) {
+		return 0, 0, fmt.Errorf("to must not be before from")
+	}
+	var fromTS, toTS int64
+	if !fromTime.IsZero() {
+		fromTS = fromTime.Unix()
+	}
+	if !toTime.IsZero() {
+		toTS = toTime.Unix()
+	}
+	return fromTS, toTS, nil
+}
+
+var windowArgs = graphql.FieldConfigArgument{
+	"from": &graphql.ArgumentConfig{Type: graphql.String, Description: "Start of the window (YYYY-MM-DD or RFC 3339)"},
+	"to":   &graphql.ArgumentConfig{Type: graphql.String, Description: "End of the window, inclusive (YYYY-MM-DD or RFC 3339)"},
+}
+
+// withArgs returns windowArgs extended by args.
+func withArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	merged := graphql.FieldConfigArgument{}
+	for name, arg := range windowArgs {
+		merged[name] = arg
+	}
+	for name, arg := range args {
+		merged[name] = arg
+	}
+	return merged
+}
+
+func newSchema(pricing *service.PricingService) (graphql.Schema, error) {
+	var regionType, machineType *graphql.Object
+
+	pricePointType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PricePoint",
+		Description: "A point of a price history. Downsampled points cover a bucket: price is the last price in the bucket and minPrice/maxPrice its range.",
+		Fields: graphql.Fields{
+			"timestamp":       fieldOf(graphql.NewNonNull(graphql.DateTime), "", func(h models.PriceHistory) interface{} { return h.Timestamp }),
+			"price":           fieldOf(graphql.NewNonNull(graphql.Float), "Spot price per hour", func(h models.PriceHistory) interface{} { return h.Price }),
+			"minPrice":        fieldOf(graphql.NewNonNull(graphql.Float), "", func(h models.PriceHistory) interface{} { return h.MinPrice }),
+			"maxPrice":        fieldOf(graphql.NewNonNull(graphql.Float), "", func(h models.PriceHistory) interface{} { return h.MaxPrice }),
+			"onDemandPrice":   fieldOf(graphql.NewNonNull(graphql.Float), "", func(h models.PriceHistory) interface{} { return h.OnDemandPrice }),
+			"spotDiscountPct": fieldOf(graphql.NewNonNull(graphql.Float), "Spot discount relative to on-demand, in percent", func(h models.PriceHistory) interface{} { return h.SpotDiscountPct }),
+			"resolution":      fieldOf(graphql.NewNonNull(graphql.String), "raw, day, week or month", func(h models.PriceHistory) interface{} { return h.Resolution }),
+		},
+	})
+
+	changeEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ChangeEvent",
+		Description: "A snapshot in which the spot or on-demand price of a machine type in a region changed.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":                fieldOf(graphql.NewNonNull(graphql.ID), "", func(c models.PriceChange) interface{} { return c.ID }),
+				"machineTypeName":   fieldOf(graphql.NewNonNull(graphql.String), "", func(c models.PriceChange) interface{} { return c.MachineType }),
+				"family":            fieldOf(graphql.NewNonNull(graphql.String), "", func(c models.PriceChange) interface{} { return c.Family }),
+				"regionName":        fieldOf(graphql.NewNonNull(graphql.String), "", func(c models.PriceChange) interface{} { return c.RegionName }),
+				"timestamp":         fieldOf(graphql.NewNonNull(graphql.DateTime), "Snapshot in which the new prices were first observed", func(c models.PriceChange) interface{} { return c.Timestamp }),
+				"oldHourSpotPrice":  fieldOf(graphql.NewNonNull(graphql.Float), "", func(c models.PriceChange) interface{} { return c.OldHourSpotPrice }),
+				"newHourSpotPrice":  fieldOf(graphql.NewNonNull(graphql.Float), "", func(c models.PriceChange) interface{} { return c.NewHourSpotPrice }),
+				"spotChange":        fieldOf(graphql.NewNonNull(graphql.Float), "New minus old spot price", func(c models.PriceChange) interface{} { return c.SpotChange }),
+				"spotChangePct":     fieldOf(graphql.NewNonNull(graphql.Float), "Spot price change relative to the old price, in percent", func(c models.PriceChange) interface{} { return c.SpotChangePct }),
+				"oldHourPrice":      fieldOf(graphql.NewNonNull(graphql.Float), "On-demand price before the change", func(c models.PriceChange) interface{} { return c.OldHourPrice }),
+				"newHourPrice":      fieldOf(graphql.NewNonNull(graphql.Float), "On-demand price after the change", func(c models.PriceChange) interface{} { return c.NewHourPrice }),
+				"onDemandChange":    fieldOf(graphql.NewNonNull(graphql.Float), "New minus old on-demand price", func(c models.PriceChange) interface{} { return c.OnDemandChange }),
+				"onDemandChangePct": fieldOf(graphql.NewNonNull(graphql.Float), "On-demand price change relative to the old price, in percent", func(c models.PriceChange) interface{} { return c.OnDemandChangePct }),
+				"region":            fieldOf(graphql.NewNonNull(regionType), "", func(c models.PriceChange) interface{} { return c.RegionName }),
+				"machineType": {
+					Type:        machineType,
+					Description: "Current prices of the machine type in the region",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						c := source[models.PriceChange](p)
+						return loadersFrom(p).current.load(seriesKey{c.RegionName, c.MachineType}), nil
+					},
+				},
+			}
+		}),
+	})
+
+	changePageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ChangePage",
+		Description: "A page of price changes, newest first.",
+		Fields: graphql.Fields{
+			"changes":    fieldOf(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(changeEventType))), "", func(r *models.PriceChangeListResponse) interface{} { return r.Changes }),
+			"count":      fieldOf(graphql.NewNonNull(graphql.Int), "", func(r *models.PriceChangeListResponse) interface{} { return r.Count }),
+			"nextCursor": fieldOf(graphql.String, "Pass as cursor to fetch the next page; null on the last page", func(r *models.PriceChangeListResponse) interface{} { return nullIfEmpty(r.NextCursor) }),
+		},
+	})
+
+	machineType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MachineType",
+		Description: "A machine type offered in a region, with its current prices and summary statistics.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":             fieldOf(graphql.NewNonNull(graphql.String), "", func(m models.Machine) interface{} { return m.MachineType }),
+				"family":           fieldOf(graphql.NewNonNull(graphql.String), "", func(m models.Machine) interface{} { return strings.Split(m.MachineType, "-")[0] }),
+				"regionName":       fieldOf(graphql.NewNonNull(graphql.String), "", func(m models.Machine) interface{} { return m.RegionName }),
+				"region":           fieldOf(graphql.NewNonNull(regionType), "", func(m models.Machine) interface{} { return m.RegionName }),
+				"hourSpotPrice":    fieldOf(graphql.NewNonNull(graphql.Float), "Spot price per hour in the latest snapshot", func(m models.Machine) interface{} { return m.HourSpotPrice }),
+				"hourPrice":        fieldOf(graphql.NewNonNull(graphql.Float), "On-demand price per hour in the latest snapshot", func(m models.Machine) interface{} { return m.HourPrice }),
+				"spotDiscountPct":  fieldOf(graphql.NewNonNull(graphql.Float), "Current spot discount relative to on-demand, in percent", func(m models.Machine) interface{} { return m.SpotDiscountPct }),
+				"minHourSpotPrice": fieldOf(graphql.NewNonNull(graphql.Float), "", func(m models.Machine) interface{} { return m.MinHourSpotPrice }),
+				"maxHourSpotPrice": fieldOf(graphql.NewNonNull(graphql.Float), "", func(m models.Machine) interface{} { return m.MaxHourSpotPrice }),
+				"avgHourSpotPrice": fieldOf(graphql.NewNonNull(graphql.Float), "", func(m models.Machine) interface{} { return m.AvgHourSpotPrice }),
+				"firstSeen":        fieldOf(graphql.NewNonNull(graphql.DateTime), "", func(m models.Machine) interface{} { return m.FirstSeen }),
+				"lastSeen":         fieldOf(graphql.NewNonNull(graphql.DateTime), "", func(m models.Machine) interface{} { return m.LastSeen }),
+				"changeCount":      fieldOf(graphql.NewNonNull(graphql.Int), "Number of spot price changes between consecutive snapshots", func(m models.Machine) interface{} { return m.ChangeCount }),
+				"lastChangedAt":    fieldOf(graphql.DateTime, "Snapshot in which the spot price last changed, null if it never changed", func(m models.Machine) interface{} { return m.LastChangedAt }),
+				"previousPrice":    fieldOf(graphql.Float, "Spot price before the last change", func(m models.Machine) interface{} { return m.PreviousPrice }),
+				"changePct":        fieldOf(graphql.Float, "Last spot price change relative to the previous price, in percent", func(m models.Machine) interface{} { return m.ChangePct }),
+				"cpuCores": {
+					Type: graphql.Float,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p).specs.loadField(source[models.Machine](p).MachineType, func(spec models.MachineSpec) interface{} { return spec.CpuCores }), nil
+					},
+				},
+				"memoryGb": {
+					Type: graphql.Float,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p).specs.loadField(source[models.Machine](p).MachineType, func(spec models.MachineSpec) interface{} { return spec.MemoryGB }), nil
+					},
+				},
+				"history": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(pricePointType))),
+					Description: "Price history within the window",
+					Args: withArgs(graphql.FieldConfigArgument{
+						"resolution": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "raw", Description: "raw, day, week or month"},
+					}),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						m := source[models.Machine](p)
+						fromTS, toTS, err := window(p)
+						if err != nil {
+							return nil, err
+						}
+						resolution, err := service.ValidateResolution(p.Args["resolution"].(string))
+						if err != nil {
+							return nil, err
+						}
+						return loadersFrom(p).history.load(historyKey{m.RegionName, m.MachineType, fromTS, toTS, resolution}), nil
+					},
+				},
+				"changes": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(changeEventType))),
+					Description: "Latest price changes within the window, newest first",
+					Args: withArgs(graphql.FieldConfigArgument{
+						"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10, Description: "Maximum number of changes (1-500)"},
+					}),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						m := source[models.Machine](p)
+						fromTS, toTS, err := window(p)
+						if err != nil {
+							return nil, err
+						}
+						limit := p.Args["limit"].(int)
+						if limit <= 0 || limit > 500 {
+							return nil, fmt.Errorf("limit must be between 1 and 500")
+						}
+						return loadersFrom(p).changes.load(changesKey{m.RegionName, m.MachineType, fromTS, toTS, limit}), nil
+					},
+				},
+			}
+		}),
+	})
+
+	regionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Region",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":      fieldOf(graphql.NewNonNull(graphql.String), "", func(name string) interface{} { return name }),
+				"continent": fieldOf(graphql.String, "", func(name string) interface{} { return nullIfEmpty(service.RegionContinent(name)) }),
+				"machineTypes": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(machineType))),
+					Description: "Machine types offered in the region, by name descending",
+					Args: graphql.FieldConfigArgument{
+						"names":  &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Only include these machine types"},
+						"family": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only include machine types of this family"},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						names := map[string]bool{}
+						if list, ok := p.Args["names"].([]interface{}); ok {
+							for _, name := range list {
+								names[name.(string)] = true
+							}
+						}
+						family, _ := p.Args["family"].(string)
+						machines := loadersFrom(p).machines.loadTyped(source[string](p))
+						return func() (interface{}, error) {
+							all, _, err := machines()
+							if err != nil {
+								return nil, err
+							}
+							filtered := []models.Machine{}
+							for _, m := range all {
+								if len(names) > 0 && !names[m.MachineType] {
+									continue
+								}
+								if family != "" && strings.Split(m.MachineType, "-")[0] != family {
+									continue
+								}
+								filtered = append(filtered, m)
+							}
+							return filtered, nil
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	snapshotPriceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SnapshotPrice",
+		Description: "The price of a machine type in a region in effect at a point in time.",
+		Fields: graphql.Fields{
+			"regionName":      fieldOf(graphql.NewNonNull(graphql.String), "", func(sp models.SnapshotPrice) interface{} { return sp.RegionName }),
+			"machineTypeName": fieldOf(graphql.NewNonNull(graphql.String), "", func(sp models.SnapshotPrice) interface{} { return sp.MachineType }),
+			"hourSpotPrice":   fieldOf(graphql.NewNonNull(graphql.Float), "", func(sp models.SnapshotPrice) interface{} { return sp.HourSpotPrice }),
+			"hourPrice":       fieldOf(graphql.NewNonNull(graphql.Float), "", func(sp models.SnapshotPrice) interface{} { return sp.HourPrice }),
+			"spotDiscountPct": fieldOf(graphql.NewNonNull(graphql.Float), "", func(sp models.SnapshotPrice) interface{} { return sp.SpotDiscountPct }),
+			"observedAt":      fieldOf(graphql.NewNonNull(graphql.DateTime), "Snapshot the prices come from, the latest at or before asOf", func(sp models.SnapshotPrice) interface{} { return sp.ObservedAt }),
+			"inSnapshot":      fieldOf(graphql.NewNonNull(graphql.Boolean), "False if the machine type was missing from the snapshot in effect", func(sp models.SnapshotPrice) interface{} { return sp.InSnapshot }),
+			"region":          fieldOf(graphql.NewNonNull(regionType), "", func(sp models.SnapshotPrice) interface{} { return sp.RegionName }),
+			"machineType": {
+				Type:        machineType,
+				Description: "Current prices of the machine type in the region",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					sp := source[models.SnapshotPrice](p)
+					return loadersFrom(p).current.load(seriesKey{sp.RegionName, sp.MachineType}), nil
+				},
+			},
+		},
+	})
+
+	snapshotType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Snapshot",
+		Description: "The prices in effect at a point in time.",
+		Fields: graphql.Fields{
+			"asOf":       fieldOf(graphql.NewNonNull(graphql.DateTime), "", func(s *models.SnapshotResponse) interface{} { return s.AsOf }),
+			"snapshotAt": fieldOf(graphql.DateTime, "Latest snapshot at or before asOf, null if there is none", func(s *models.SnapshotResponse) interface{} { return s.SnapshotAt }),
+			"count":      fieldOf(graphql.NewNonNull(graphql.Int), "", func(s *models.SnapshotResponse) interface{} { return s.Count }),
+			"prices":     fieldOf(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(snapshotPriceType))), "", func(s *models.SnapshotResponse) interface{} { return s.Prices }),
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"regions": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(regionType))),
+				Args: graphql.FieldConfigArgument{
+					"continent": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only include regions on this continent"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var filter service.RegionFilter
+					if continent, ok := p.Args["continent"].(string); ok {
+						filter.Continents = []string{continent}
+					}
+					if err := filter.Validate(); err != nil {
+						return nil, err
+					}
+					regions, err := pricing.GetAllRegions()
+					if err != nil {
+						return nil, err
+					}
+					matching := []string{}
+					for _, region := range regions {
+						if filter.Matches(region) {
+							matching = append(matching, region)
+						}
+					}
+					return matching, nil
+				},
+			},
+			"region": {
+				Type: regionType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					regions, err := pricing.GetAllRegions()
+					if err != nil {
+						return nil, err
+					}
+					name := p.Args["name"].(string)
+					for _, region := range regions {
+						if region == name {
+							return region, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+			"machineType": {
+				Type:        machineType,
+				Description: "A machine type in a region, null if it was never seen there",
+				Args: graphql.FieldConfigArgument{
+					"region": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"name":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p).current.load(seriesKey{p.Args["region"].(string), p.Args["name"].(string)}), nil
+				},
+			},
+			"changes": {
+				Type:        graphql.NewNonNull(changePageType),
+				Description: "Price changes, newest first. Pages are linked by nextCursor",
+				Args: withArgs(graphql.FieldConfigArgument{
+					"region":       &graphql.ArgumentConfig{Type: graphql.String},
+					"family":       &graphql.ArgumentConfig{Type: graphql.String},
+					"machineType":  &graphql.ArgumentConfig{Type: graphql.String},
+					"direction":    &graphql.ArgumentConfig{Type: graphql.String, Description: "up or down to only include spot price increases or decreases"},
+					"minChangePct": &graphql.ArgumentConfig{Type: graphql.Float, Description: "Smallest absolute spot price change to include, in percent"},
+					"limit":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50, Description: "Maximum number of changes (1-500)"},
+					"cursor":       &graphql.ArgumentConfig{Type: graphql.String, Description: "nextCursor of the previous page"},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filter := service.ChangeFilter{Limit: p.Args["limit"].(int)}
+					filter.RegionName, _ = p.Args["region"].(string)
+					filter.Family, _ = p.Args["family"].(string)
+					filter.MachineType, _ = p.Args["machineType"].(string)
+					filter.Direction, _ = p.Args["direction"].(string)
+					filter.MinChangePct, _ = p.Args["minChangePct"].(float64)
+					filter.Cursor, _ = p.Args["cursor"].(string)
+					from, _ := p.Args["from"].(string)
+					to, _ := p.Args["to"].(string)
+					var err error
+					if filter.From, err = timeutil.ParseTime("from", from, false); err != nil {
+						return nil, err
+					}
+					if filter.To, err = timeutil.ParseTime("to", to, true); err != nil {
+						return nil, err
+					}
+					if err := filter.Validate(); err != nil {
+						return nil, err
+					}
+					return pricing.GetPriceChanges(filter)
+				},
+			},
+			"snapshot": {
+				Type:        graphql.NewNonNull(snapshotType),
+				Description: "The prices in effect at a point in time",
+				Args: graphql.FieldConfigArgument{
+					"asOf":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "Unix timestamp, RFC 3339 timestamp or date (end of that day)"},
+					"region":      &graphql.ArgumentConfig{Type: graphql.String},
+					"machineType": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					asOf, err := timeutil.ParseInstant("asOf", p.Args["asOf"].(string))
+					if err != nil {
+						return nil, err
+					}
+					if asOf.IsZero() {
+						return nil, fmt.Errorf("asOf must not be empty")
+					}
+					var filter service.SnapshotFilter
+					filter.RegionName, _ = p.Args["region"].(string)
+					filter.MachineType, _ = p.Args["machineType"].(string)
+					return pricing.GetSnapshot(asOf, filter)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// nullIfEmpty maps an empty string to null.
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
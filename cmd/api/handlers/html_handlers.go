@@ -52,7 +52,7 @@ func (h *HTMLHandler) RegionHandler(c echo.Context) error {
 		return c.HTML(http.StatusOK, "")
 	}
 
-	machines, err := h.service.GetMachinesByRegion(regionName)
+	machines, err := h.service.GetMachinesByRegion(regionName, service.MachineListOptions{})
 	if err != nil {
 		return c.String(http.StatusInternalServerError, "Failed to query machines: "+err.Error())
 	}
@@ -80,7 +80,7 @@ func (h *HTMLHandler) ComputeHandler(c echo.Context) error {
 		return c.HTML(http.StatusOK, "")
 	}
 
-	machineData, err := h.service.GetMachineDetail(regionName, machineType)
+	machineData, err := h.service.GetMachineDetail(regionName, machineType, service.HistoryOptions{})
 	if err != nil {
 		return c.String(http.StatusInternalServerError, "Failed to query compute history: "+err.Error())
 	}
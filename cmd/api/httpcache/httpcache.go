@@ -0,0 +1,262 @@
+// Package httpcache adds validators derived from the data version to GET responses,
+// answers conditional requests with 304 Not Modified and keeps recent responses in memory
+// until the data version changes.
+package httpcache
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+// storedHeaders are the response headers replayed from the cache.
+var storedHeaders = []string{"Content-Type", "Content-Language", "Vary"}
+
+// Options configures a Cache.
+type Options struct {
+	// MaxAge is how long clients may reuse a response without revalidating it; 0 makes
+	// them revalidate every time, which is cheap with 304 responses.
+	MaxAge time.Duration
+	// MaxBytes bounds the size of the cached response bodies; 0 disables the response
+	// cache but keeps the validators.
+	MaxBytes int64
+	// Skip lists path prefixes that are never cached, e.g. routes whose data does not
+	// come from imports.
+	Skip []string
+}
+
+// Cache is an HTTP response cache invalidated by data version changes.
+type Cache struct {
+	version func() (service.DataVersion, bool)
+	opts    Options
+
+	mu      sync.Mutex
+	etag    string
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+}
+
+type entry struct {
+	key    string
+	header http.Header
+	body   []byte
+}
+
+// New returns a cache for the data version reported by version, which returns false
+// while the version is unknown. version is called for every request and again before a
+// response is stored, so it should be cheap and current.
+func New(version func() (service.DataVersion, bool), opts Options) *Cache {
+	return &Cache{
+		version: version,
+		opts:    opts,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Middleware serves GET and HEAD requests from the cache or forwards them, adding ETag,
+// Last-Modified and Cache-Control headers. Responses are cached per Accept header, so
+// they also carry Vary: Accept.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, ok := c.version()
+		if !ok || !c.cacheable(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		etag := version.ETag()
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Set("Last-Modified", version.ModifiedAt.Format(http.TimeFormat))
+		h.Set("Cache-Control", c.cacheControl())
+		addVary(h, "Accept")
+		if notModified(r, etag, version.ModifiedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		key := r.URL.RequestURI() + "\x00" + r.Header.Get("Accept")
+		if e, ok := c.get(etag, key); ok {
+			for name, values := range e.header {
+				h[name] = values
+			}
+			addVary(h, "Accept")
+			h.Set("X-Cache", "HIT")
+			w.Write(e.body)
+			return
+		}
+
+		h.Set("X-Cache", "MISS")
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, store: c.opts.MaxBytes > 0}
+		next.ServeHTTP(rec, r)
+		if rec.status != http.StatusOK || !rec.store || r.Method != http.MethodGet {
+			return
+		}
+		// An import that finished while the response was built may be part of it.
+		if current, ok := c.version(); !ok || current.ETag() != etag {
+			return
+		}
+		header := http.Header{}
+		for _, name := range storedHeaders {
+			if values := h.Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		c.put(etag, &entry{key: key, header: header, body: rec.body.Bytes()})
+	})
+}
+
+func (c *Cache) cacheable(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	for _, prefix := range c.opts.Skip {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cache) cacheControl() string {
+	if c.opts.MaxAge <= 0 {
+		return "public, no-cache"
+	}
+	return "public, max-age=" + strconv.FormatInt(int64(c.opts.MaxAge.Seconds()), 10)
+}
+
+// addVary adds name to the Vary header unless it is listed already.
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since without it (RFC 9110 13.2.2).
+func notModified(r *http.Request, etag string, modifiedAt time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// Weak comparison: W/ prefixes are ignored.
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modifiedAt.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// get returns a cached response of the current version, dropping every entry when the
+// version changed.
+func (c *Cache) get(etag, key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.etag != etag {
+		c.etag = etag
+		c.entries = map[string]*list.Element{}
+		c.lru.Init()
+		c.size = 0
+		return nil, false
+	}
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*entry), true
+}
+
+// put stores a response unless its version is no longer current or it is too large,
+// evicting the least recently used entries to stay within MaxBytes.
+func (c *Cache) put(etag string, e *entry) {
+	n := int64(len(e.body) + len(e.key))
+	if n > c.opts.MaxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.etag != etag {
+		return
+	}
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += n
+	for c.size > c.opts.MaxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	c.size -= int64(len(e.body) + len(e.key))
+}
+
+// recorder copies the body of a response while writing it through. Responses with
+// errors drop the validators, and streams are not stored.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	store       bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.status = status
+		// The handler may have replaced the Vary header set before it ran.
+		addVary(r.Header(), "Accept")
+		if status >= http.StatusInternalServerError {
+			h := r.Header()
+			h.Del("ETag")
+			h.Del("Last-Modified")
+			h.Set("Cache-Control", "no-store")
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.store {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes on and stops recording, since flushed responses are streams.
+func (r *recorder) Flush() {
+	r.store = false
+	r.body.Reset()
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,243 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+var modifiedAt = time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+// testVersion is a data version the tests can change, also from within a handler.
+type testVersion struct {
+	v     service.DataVersion
+	known bool
+}
+
+func (t *testVersion) get() (service.DataVersion, bool) {
+	return t.v, t.known
+}
+
+func newVersion() *testVersion {
+	return &testVersion{v: service.DataVersion{SnapshotTS: 1, RunID: 1, RunFinishedTS: 2, ModifiedAt: modifiedAt}, known: true}
+}
+
+// countingHandler answers with the request path and counts the requests it served.
+type countingHandler struct {
+	calls  int
+	status int
+	before func()
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	if h.before != nil {
+		h.before()
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+	w.Write([]byte("body of " + r.URL.Path))
+}
+
+func serve(handler http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestNotModified(t *testing.T) {
+	etag := `W/"1-1-2-0"`
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   bool
+	}{
+		{"no validators", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": etag}, true},
+		{"strong form of the etag", map[string]string{"If-None-Match": `"1-1-2-0"`}, true},
+		{"etag in a list", map[string]string{"If-None-Match": `"other", ` + etag}, true},
+		{"any etag", map[string]string{"If-None-Match": "*"}, true},
+		{"other etag", map[string]string{"If-None-Match": `W/"1-1-3-0"`}, false},
+		{"modified since", map[string]string{"If-Modified-Since": modifiedAt.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": modifiedAt.Format(http.TimeFormat)}, true},
+		{"malformed date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{
+			"etag takes precedence over the date",
+			map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modifiedAt.Format(http.TimeFormat)},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for name, value := range tt.header {
+			r.Header.Set(name, value)
+		}
+		if got := notModified(r, etag, modifiedAt.Add(500*time.Millisecond)); got != tt.want {
+			t.Errorf("%s: notModified = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	version := newVersion()
+	next := &countingHandler{}
+	handler := New(version.get, Options{MaxBytes: 1 << 20, Skip: []string{"/api/v1/alerts"}}).Middleware(next)
+
+	first := serve(handler, http.MethodGet, "/a", nil)
+	if first.Header().Get("X-Cache") != "MISS" || first.Header().Get("ETag") != version.v.ETag() {
+		t.Fatalf("first response headers = %v", first.Header())
+	}
+	if got := first.Header().Get("Last-Modified"); got != modifiedAt.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, no-cache" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := first.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept" {
+		t.Errorf("Vary = %q, want Accept", got)
+	}
+
+	second := serve(handler, http.MethodGet, "/a", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != "body of /a" || second.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("second response = %v %q", second.Header(), second.Body)
+	}
+	if got := second.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept" {
+		t.Errorf("cached Vary = %q, want Accept", got)
+	}
+	if next.calls != 1 {
+		t.Errorf("handler called %d times, want 1", next.calls)
+	}
+
+	if w := serve(handler, http.MethodGet, "/a", map[string]string{"If-None-Match": version.v.ETag()}); w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("conditional request = %d %v %q, want 304 with Vary and without a body", w.Code, w.Header(), w.Body)
+	}
+
+	// A new version empties the cache and changes the validators.
+	version.v.RunID++
+	third := serve(handler, http.MethodGet, "/a", nil)
+	if third.Header().Get("X-Cache") != "MISS" || third.Header().Get("ETag") != version.v.ETag() || next.calls != 2 {
+		t.Errorf("after a new version: %v, %d calls", third.Header(), next.calls)
+	}
+
+	// Skipped prefixes, other methods and unknown versions go straight to the handler.
+	for _, w := range []*httptest.ResponseRecorder{
+		serve(handler, http.MethodGet, "/api/v1/alerts", nil),
+		serve(handler, http.MethodPost, "/a", nil),
+	} {
+		if w.Header().Get("ETag") != "" || w.Header().Get("X-Cache") != "" {
+			t.Errorf("uncached response has headers %v", w.Header())
+		}
+	}
+	version.known = false
+	if w := serve(handler, http.MethodGet, "/a", nil); w.Header().Get("ETag") != "" {
+		t.Errorf("response without a version has headers %v", w.Header())
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	tests := []struct {
+		vary []string
+		want []string
+	}{
+		{vary: nil, want: []string{"Accept"}},
+		{vary: []string{"Origin"}, want: []string{"Origin", "Accept"}},
+		{vary: []string{"Origin, accept"}, want: []string{"Origin, accept"}},
+		{vary: []string{"*"}, want: []string{"*"}},
+	}
+	for _, tt := range tests {
+		h := http.Header{"Vary": tt.vary}
+		addVary(h, "Accept")
+		if got := h.Values("Vary"); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("addVary(%q) = %q, want %q", tt.vary, got, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareDoesNotStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+		// during runs inside the handler.
+		during func(v *testVersion)
+	}{
+		{name: "not found", method: http.MethodGet, status: http.StatusNotFound},
+		{name: "server error", method: http.MethodGet, status: http.StatusInternalServerError},
+		{name: "HEAD", method: http.MethodHead},
+		{name: "version changed while building", method: http.MethodGet, during: func(v *testVersion) { v.v.SnapshotTS++ }},
+		{name: "version unreadable after building", method: http.MethodGet, during: func(v *testVersion) { v.known = false }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := newVersion()
+			next := &countingHandler{status: tt.status}
+			if tt.during != nil {
+				next.before = func() { tt.during(version) }
+			}
+			cache := New(version.get, Options{MaxBytes: 1 << 20})
+			handler := cache.Middleware(next)
+			first := serve(handler, tt.method, "/a", nil)
+			if tt.status >= http.StatusInternalServerError && (first.Header().Get("ETag") != "" || first.Header().Get("Cache-Control") != "no-store") {
+				t.Errorf("server error headers = %v", first.Header())
+			}
+			if len(cache.entries) != 0 {
+				t.Errorf("stored %d entries, want none", len(cache.entries))
+			}
+		})
+	}
+}
+
+func TestLRU(t *testing.T) {
+	version := newVersion()
+	// Each entry is "/x" plus a NUL and the body "body of /x": 13 bytes.
+	const entrySize = 13
+	cache := New(version.get, Options{MaxBytes: 3 * entrySize})
+	handler := cache.Middleware(&countingHandler{})
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		serve(handler, http.MethodGet, path, nil)
+	}
+	// Using /a makes /b the least recently used entry, evicted by /d.
+	serve(handler, http.MethodGet, "/a", nil)
+	serve(handler, http.MethodGet, "/d", nil)
+
+	var cached []string
+	for el := cache.lru.Front(); el != nil; el = el.Next() {
+		cached = append(cached, strings.TrimSuffix(el.Value.(*entry).key, "\x00"))
+	}
+	if got := strings.Join(cached, " "); got != "/d /a /c" {
+		t.Errorf("cached (most recent first) = %q, want %q", got, "/d /a /c")
+	}
+	if cache.size != 3*entrySize {
+		t.Errorf("size = %d, want %d", cache.size, 3*entrySize)
+	}
+
+	// Responses larger than the whole cache are not stored.
+	big := New(version.get, Options{MaxBytes: entrySize - 1})
+	serve(big.Middleware(&countingHandler{}), http.MethodGet, "/a", nil)
+	if len(big.entries) != 0 || big.size != 0 {
+		t.Errorf("stored an oversized response: %d entries, %d bytes", len(big.entries), big.size)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	version := newVersion()
+	handler := New(version.get, Options{MaxAge: 90 * time.Second}).Middleware(&countingHandler{})
+	w := serve(handler, http.MethodGet, "/a", nil)
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=90" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := w.Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("X-Cache = %q", got)
+	}
+}
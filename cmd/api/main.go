@@ -5,13 +5,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"flag"
+	"fmt"
 	"html/template"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
@@ -20,10 +25,20 @@ import (
 
 	"github.com/go-fuego/fuego"
 	"github.com/go-fuego/fuego/option"
+	"github.com/go-fuego/fuego/param"
+	"google.golang.org/grpc"
 
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/access"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/graph"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/httpcache"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/metrics"
 	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/rpc"
 	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/coverage"
 	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/schema"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
 )
 
 // TemplateRenderer is a custom html/template renderer for Echo framework
@@ -36,12 +51,65 @@ func (t *TemplateRenderer) Render(w io.Writer, name string, data interface{}, c
 	return t.templates.ExecuteTemplate(w, name, data)
 }
 
+// sortHeader is a column header of the machine list that re-sorts the list when clicked.
+type sortHeader struct {
+	Label string
+	// Sort is the sort key the header requests: the column ascending, or descending
+	// if the list is already sorted by it in ascending order.
+	Sort       string
+	Active     bool
+	Descending bool
+}
+
+// machineSortHeaders returns the headers of the machine list sorted by sortKey.
+// Without a sort key machines are listed by name, descending.
+func machineSortHeaders(sortKey string) []sortHeader {
+	if sortKey == "" {
+		sortKey = "-machine_type"
+	}
+	key, descending := strings.CutPrefix(sortKey, "-")
+	columns := []struct{ label, key string }{
+		{"Machine type", "machine_type"},
+		{"Spot", "hour_spot_price"},
+		{"On-demand", "hour_price"},
+		{"Discount", "spot_discount_pct"},
+	}
+	headers := make([]sortHeader, len(columns))
+	for i, column := range columns {
+		header := sortHeader{Label: column.label, Sort: column.key}
+		if column.key == key {
+			header.Active = true
+			header.Descending = descending
+			if !descending {
+				header.Sort = "-" + column.key
+			}
+		}
+		headers[i] = header
+	}
+	return headers
+}
+
 func main() {
 	// Parse command line flags
 	dbPath := flag.String("dbpath", "db.sqlite3", "Path to sqlite3 database containing data from dataprocessing")
 	port := flag.String("port", "8080", "Port to run the server on")
+	grpcPort := flag.String("grpc-port", "9090", "Port to run the gRPC server on, empty to disable it")
+	pollInterval := flag.Duration("poll-interval", 5*time.Second, "How often to check the database for new snapshots to stream")
+	cacheMaxAge := flag.Duration("cache-max-age", 0, "How long clients may reuse responses without revalidating them")
+	cacheSize := flag.Int64("cache-size", 64, "Size of the in-process response cache in MB, 0 to disable it")
+	anonymousAPI := flag.Bool("anonymous-api", true, "Serve /api/v1, /graphql, /metrics and gRPC requests without an API key")
+	publicMetrics := flag.Bool("public-metrics", false, "Serve /metrics without an API key or rate limit, whatever -anonymous-api says")
+	anonymousHTML := flag.Bool("anonymous-html", true, "Serve the HTML pages without an API key")
+	anonymousRate := flag.Float64("anonymous-rate", 0, "Requests per second allowed per address without an API key, 0 for unlimited")
+	anonymousBurst := flag.Int("anonymous-burst", 20, "Requests allowed at once per address without an API key before -anonymous-rate applies")
+	allowPrivateWebhooks := flag.Bool("allow-private-webhooks", false, "Accept alert webhook URLs on loopback, link-local and private addresses")
 	flag.Parse()
 
+	if *anonymousRate > 0 && *anonymousBurst < 1 {
+		slog.Error("-anonymous-burst must be at least 1 when -anonymous-rate is set")
+		return
+	}
+
 	// Initialize database connection
 	sqlDB, err := sql.Open("sqlite3", *dbPath)
 	if err != nil {
@@ -55,14 +123,55 @@ func main() {
 		slog.Error("failed to ping database", "error", err)
 		return
 	}
+	// Alert rules and API key usage are written here, so their tables must not wait for the first import
+	if err := schema.InitAlertTables(sqlDB); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return
+	}
+	if err := schema.InitAPIKeyTables(sqlDB); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return
+	}
 
 	// Initialize querier and service
 	querier := db.NewQuerier(sqlDB)
 	pricingService := service.NewPricingService(querier)
+	apiMetrics := metrics.New(pricingService)
+	querier.SetObserver(apiMetrics.ObserveQuery)
+
+	// Watch the database for imports to stream
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	changeFeed := service.NewChangeFeed(pricingService, *pollInterval)
+	go changeFeed.Run(ctx)
+
+	// Check API keys and rate limits before serving anything, including cached responses;
+	// alert rules send requests to arbitrary URLs, so only admin keys may manage them
+	limiter := access.New(pricingService, access.Options{
+		AnonymousRate:  *anonymousRate,
+		AnonymousBurst: *anonymousBurst,
+		Exempt:         []string{"/api/v1/health", "/swagger", "/grpc.reflection."},
+		Admin:          []string{"/api/v1/alerts"},
+	})
+
+	// Cache responses until the next import; alerts are changed through the API itself
+	responseCache := httpcache.New(pricingService.CurrentDataVersion, httpcache.Options{
+		MaxAge:   *cacheMaxAge,
+		MaxBytes: *cacheSize << 20,
+		Skip:     []string{"/api/v1/alerts", "/api/v1/changes/stream", "/api/v1/health", "/swagger"},
+	})
 
 	// Create Fuego server with OpenAPI auto-generation
 	s := fuego.NewServer(
+		fuego.WithGlobalMiddlewares(apiMetrics.Middleware),
+		fuego.WithErrorSerializer(sendError),
+		fuego.WithRouteOptions(
+			option.AddError(http.StatusBadRequest, "Invalid parameter", models.ErrorResponse{}),
+			option.AddError(http.StatusInternalServerError, "Internal server error", models.ErrorResponse{}),
+			option.AddError(http.StatusServiceUnavailable, "Database unavailable or not imported yet", models.ErrorResponse{}),
+		),
 		fuego.WithEngineOptions(
+			fuego.WithErrorHandler(apiErrorHandler),
 			fuego.WithOpenAPIConfig(fuego.OpenAPIConfig{
 				DisableSwaggerUI: false,
 				DisableLocalSave: true,
@@ -76,6 +185,8 @@ func main() {
 		),
 	)
 
+	fuego.Use(s, limiter.Middleware(*anonymousAPI), responseCache.Middleware)
+
 	// Add server URL to OpenAPI spec (fixes the "null" base URL issue in Swagger UI)
 	serverURL := "http://localhost:" + *port
 	s.Engine.OpenAPI.Description().Servers = openapi3.Servers{
@@ -92,16 +203,19 @@ func main() {
 		templates: template.Must(template.ParseGlob("cmd/api/templates/*.html")),
 	}
 	e.Renderer = renderer
+	e.Use(metrics.EchoRoute())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(echo.WrapMiddleware(limiter.Middleware(*anonymousHTML)))
 	e.Use(middleware.Gzip())
+	e.Use(echo.WrapMiddleware(responseCache.Middleware))
 
 	// HTML Routes (existing functionality using Echo directly)
 	e.GET("/", func(c echo.Context) error {
 		regions, err := pricingService.GetAllRegions()
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to query regions: "+err.Error())
+			return htmlError(c, err)
 		}
 		return c.Render(http.StatusOK, "index.html", map[string]interface{}{"Regions": regions})
 	})
@@ -111,13 +225,19 @@ func main() {
 		if regionName == "" {
 			return c.HTML(http.StatusOK, "")
 		}
-		machines, err := pricingService.GetMachinesByRegion(regionName)
+		opts := service.MachineListOptions{Sort: c.QueryParam("sort")}
+		if err := opts.Validate(); err != nil {
+			return htmlError(c, err)
+		}
+		machines, err := pricingService.GetMachinesByRegion(regionName, opts)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to query machines: "+err.Error())
+			return htmlError(c, err)
 		}
 		return c.Render(http.StatusOK, "machines.html", map[string]interface{}{
-			"ListTitle": "Machine types in " + regionName,
-			"Machines":  machines,
+			"ListTitle":  "Machine types in " + regionName,
+			"RegionName": regionName,
+			"Headers":    machineSortHeaders(opts.Sort),
+			"Machines":   machines,
 		})
 	})
 
@@ -127,13 +247,37 @@ func main() {
 		if regionName == "" || machineType == "" {
 			return c.HTML(http.StatusOK, "")
 		}
-		machineData, err := pricingService.GetMachineDetail(regionName, machineType)
+		opts, err := historyOptions(c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("resolution"))
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to query compute history: "+err.Error())
+			return htmlError(c, badRequest(err))
+		}
+		machineData, err := pricingService.GetMachineDetail(regionName, machineType, opts)
+		if err != nil {
+			return htmlError(c, err)
 		}
 		return c.Render(http.StatusOK, "prices.html", machineData)
 	})
 
+	e.GET("/compare", func(c echo.Context) error {
+		machineType := c.QueryParam("machine_type")
+		if machineType == "" {
+			return c.HTML(http.StatusOK, "")
+		}
+		filter, err := regionFilter(c.QueryParams()["continent"], c.QueryParams()["regions"])
+		if err != nil {
+			return htmlError(c, badRequest(err))
+		}
+		comparison, err := pricingService.CompareMachineAcrossRegions(machineType, filter)
+		if err != nil {
+			return htmlError(c, err)
+		}
+		return c.Render(http.StatusOK, "compare.html", map[string]interface{}{
+			"Comparison": comparison,
+			"Continents": service.Continents(),
+			"Continent":  c.QueryParam("continent"),
+		})
+	})
+
 	// Fuego API Routes - Auto-generates OpenAPI docs from function signatures!
 	// No annotations needed - types are inferred from return values
 
@@ -156,30 +300,472 @@ func main() {
 	// GET /api/v1/regions/{region}/machines
 	fuego.Get(s, "/api/v1/regions/{region}/machines", func(c fuego.ContextNoBody) (models.MachineListResponse, error) {
 		region := c.PathParam("region")
-		machines, err := pricingService.GetMachinesByRegion(region)
+		opts := service.MachineListOptions{Sort: c.QueryParam("sort")}
+		var err error
+		if opts.Units, err = c.QueryParamBoolErr("units"); err != nil {
+			return models.MachineListResponse{}, fuego.BadRequestError{Detail: "units must be true or false"}
+		}
+		if opts.Stats, err = c.QueryParamBoolErr("stats"); err != nil {
+			return models.MachineListResponse{}, fuego.BadRequestError{Detail: "stats must be true or false"}
+		}
+		if opts.StatsWindow, err = windowParam(c.QueryParam("window")); err != nil {
+			return models.MachineListResponse{}, badRequest(err)
+		}
+		if opts.AsOf, err = timeutil.ParseInstant("as_of", c.QueryParam("as_of")); err != nil {
+			return models.MachineListResponse{}, badRequest(err)
+		}
+		if err := opts.Validate(); err != nil {
+			return models.MachineListResponse{}, err
+		}
+		machines, err := pricingService.GetMachinesByRegion(region, opts)
 		if err != nil {
 			return models.MachineListResponse{}, err
 		}
-		return models.MachineListResponse{
+		response := models.MachineListResponse{
 			RegionName: region,
 			Machines:   machines,
 			Count:      len(machines),
-		}, nil
+		}
+		if !opts.AsOf.IsZero() {
+			response.AsOf = &opts.AsOf
+		}
+		return response, nil
 	},
 		option.Summary("List machines in a region"),
 		option.Description("Get all machine types available in a specific region with pricing information"),
 		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Region not found", models.ErrorResponse{}),
+		option.QueryBool("units", "Include spot and on-demand prices per vCPU and per GB of memory", param.Default(false)),
+		option.QueryBool("stats", "Include volatility metrics of the daily spot price over the window", param.Default(false)),
+		option.Query("window", "Volatility window ending at the latest snapshot (e.g. 30d, 12w)", param.Default("90d")),
+		option.Query("as_of", "List prices and statistics as they were at this instant (YYYY-MM-DD for the end of that day, RFC 3339 or Unix timestamp)"),
+		option.Query("sort", "Sort key, prefixed with - for descending order: machine_type, hour_spot_price, hour_price, spot_discount_pct, avg_hour_spot_price, change_count, or a volatility metric (std_dev, coefficient_of_variation, mean_hours_between_changes, max_drawdown_pct, max_spike_pct, stability_score), which implies stats"),
+	)
+
+	// GET /api/v1/snapshots/{timestamp}
+	fuego.Get(s, "/api/v1/snapshots/{timestamp}", func(c fuego.ContextNoBody) (*models.SnapshotResponse, error) {
+		asOf, err := timeutil.ParseInstant("timestamp", c.PathParam("timestamp"))
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		return pricingService.GetSnapshot(asOf, service.SnapshotFilter{
+			RegionName:  c.QueryParam("region"),
+			MachineType: c.QueryParam("machine_type"),
+		})
+	},
+		option.Summary("Get prices at a point in time"),
+		option.Description("Get the spot and on-demand price of every machine type in every region as it was at an instant: the latest observation at or before it. timestamp is a Unix timestamp, an RFC 3339 timestamp or a date (YYYY-MM-DD), which means the end of that day; eight digits that read as a date (20240315) are rejected as ambiguous"),
+		option.Tags("snapshots"),
+		option.Query("region", "Only include this region"),
+		option.Query("machine_type", "Only include this machine type"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
 	)
 
 	// GET /api/v1/regions/{region}/machines/{machine_type}/history
 	fuego.Get(s, "/api/v1/regions/{region}/machines/{machine_type}/history", func(c fuego.ContextNoBody) (*models.MachineDetail, error) {
 		region := c.PathParam("region")
 		machineType := c.PathParam("machine_type")
-		return pricingService.GetMachineDetail(region, machineType)
+		opts, err := historyOptions(c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("resolution"))
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		if opts.Units, err = c.QueryParamBoolErr("units"); err != nil {
+			return nil, fuego.BadRequestError{Detail: "units must be true or false"}
+		}
+		return pricingService.GetMachineDetail(region, machineType, opts)
 	},
 		option.Summary("Get machine price history"),
-		option.Description("Get detailed price history for a specific machine type in a region"),
+		option.Description("Get detailed price history for a specific machine type in a region. Min/max statistics cover the selected window."),
+		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
+		option.Query("from", "Start of the window (YYYY-MM-DD or RFC 3339)"),
+		option.Query("to", "End of the window, inclusive (YYYY-MM-DD or RFC 3339)"),
+		option.Query("resolution", "raw, day, week or month; downsampled points report the last price and the min/max of each bucket", param.Default("raw")),
+		option.QueryBool("units", "Include prices per vCPU and per GB of memory on the machine and every history point", param.Default(false)),
+	)
+
+	// GET /api/v1/regions/{region}/machines/{machine_type}/stats
+	fuego.Get(s, "/api/v1/regions/{region}/machines/{machine_type}/stats", func(c fuego.ContextNoBody) (*models.MachineStats, error) {
+		window, err := windowParam(c.QueryParam("window"))
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		return pricingService.GetMachineStats(c.PathParam("region"), c.PathParam("machine_type"), window)
+	},
+		option.Summary("Get machine price volatility"),
+		option.Description("Get volatility metrics of the daily spot price of a machine type in a region over a window ending at the latest snapshot: standard deviation, coefficient of variation, number of changes, mean time between changes, max drawdown and spike"),
+		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
+		option.Query("window", "Window ending at the latest snapshot (e.g. 30d, 12w)", param.Default("90d")),
+	)
+
+	// GET /api/v1/regions/{region}/machines/{machine_type}/forecast
+	fuego.Get(s, "/api/v1/regions/{region}/machines/{machine_type}/forecast", func(c fuego.ContextNoBody) (*models.SpotPriceForecast, error) {
+		opts, err := forecastOptions(c)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		return pricingService.GetSpotPriceForecast(c.PathParam("region"), c.PathParam("machine_type"), opts)
+	},
+		option.Summary("Forecast machine spot price"),
+		option.Description("Project the daily spot price of a machine type in a region beyond its last observed day with a linear trend or Holt exponential smoothing model, with prediction intervals. With backtest=true the last horizon of the history is also forecast from the days before it and compared to the observed prices, next to a naive last-price baseline"),
+		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
+		option.Query("horizon", "How far ahead to forecast (e.g. 14d, 4w, max 365d)", param.Default("30d")),
+		option.Query("model", "holt or linear", param.Default("holt")),
+		option.Query("history", "How much of the daily series to fit on (e.g. 90d, 1y is 365d)", param.Default("180d")),
+		option.QueryInt("confidence", "Prediction interval confidence level in percent: 80, 90, 95 or 99", param.Default(95)),
+		option.QueryBool("backtest", "Also report the error of forecasting the last horizon of the history", param.Default(false)),
+	)
+
+	// GET /api/v1/families/unit-prices
+	fuego.Get(s, "/api/v1/families/unit-prices", func(c fuego.ContextNoBody) (*models.FamilyUnitPriceResponse, error) {
+		from, err := dateParam("from", c.QueryParam("from"))
+		if err != nil {
+			return nil, err
+		}
+		to, err := dateParam("to", c.QueryParam("to"))
+		if err != nil {
+			return nil, err
+		}
+		opts := service.UnitPriceOptions{
+			Families: listParam(c.QueryParamArr("family")),
+			Regions: service.RegionFilter{
+				Continents: listParam(c.QueryParamArr("continent")),
+				Regions:    listParam(c.QueryParamArr("regions")),
+			},
+			From:       from,
+			To:         to,
+			Resolution: c.QueryParam("resolution"),
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+		return pricingService.GetFamilyUnitPrices(opts)
+	},
+		option.Summary("Get unit price series of machine families"),
+		option.Description("Get the spot and on-demand price per vCPU hour and per GB hour of machine families over time, averaged over all machine types and regions of each family from the daily price series"),
+		option.Tags("families"),
+		option.Query("family", "Machine families (comma separated, e.g. e2,n2,c3,t2d)", param.Required()),
+		option.Query("continent", "Only include regions on these continents (comma separated): "+strings.Join(service.Continents(), ", ")),
+		option.Query("regions", "Only include these regions (comma separated)"),
+		option.Query("from", "First day (YYYY-MM-DD)"),
+		option.Query("to", "Last day, inclusive (YYYY-MM-DD)"),
+		option.Query("resolution", "day, week or month", param.Default("day")),
+	)
+
+	// GET /api/v1/regions/{region}/machines/{machine_type}/daily
+	fuego.Get(s, "/api/v1/regions/{region}/machines/{machine_type}/daily", func(c fuego.ContextNoBody) (*models.DailyPriceSeries, error) {
+		from, err := dateParam("from", c.QueryParam("from"))
+		if err != nil {
+			return nil, err
+		}
+		to, err := dateParam("to", c.QueryParam("to"))
+		if err != nil {
+			return nil, err
+		}
+		return pricingService.GetDailyPrices(c.PathParam("region"), c.PathParam("machine_type"), from, to)
+	},
+		option.Summary("Get machine daily prices"),
+		option.Description("Get the spot and on-demand price on each calendar day (UTC), carrying the last observed snapshot forward"),
+		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
+		option.Query("from", "First day to include (YYYY-MM-DD)"),
+		option.Query("to", "Last day to include (YYYY-MM-DD)"),
+	)
+
+	// GET /api/v1/ingestion/runs
+	fuego.Get(s, "/api/v1/ingestion/runs", func(c fuego.ContextNoBody) (models.IngestionRunListResponse, error) {
+		limit := c.QueryParamInt("limit")
+		if limit <= 0 || limit > 500 {
+			return models.IngestionRunListResponse{}, fuego.BadRequestError{Detail: "limit must be between 1 and 500"}
+		}
+		runs, err := pricingService.GetIngestionRuns(limit)
+		if err != nil {
+			return models.IngestionRunListResponse{}, err
+		}
+		return models.IngestionRunListResponse{
+			Runs:  runs,
+			Count: len(runs),
+		}, nil
+	},
+		option.Summary("List ingestion runs"),
+		option.Description("Get the most recent dataprocessing imports with file and row counts, warnings and errors"),
+		option.Tags("ingestion"),
+		option.QueryInt("limit", "Maximum number of runs to return (1-500)", param.Default(20)),
+	)
+
+	// GET /api/v1/machines/{machine_type}/regions
+	fuego.Get(s, "/api/v1/machines/{machine_type}/regions", func(c fuego.ContextNoBody) (*models.MachineRegionComparison, error) {
+		filter, err := regionFilter(c.QueryParamArr("continent"), c.QueryParamArr("regions"))
+		if err != nil {
+			return nil, err
+		}
+		return pricingService.CompareMachineAcrossRegions(c.PathParam("machine_type"), filter)
+	},
+		option.Summary("Compare a machine type across regions"),
+		option.Description("Get the current spot and on-demand price of a machine type in every region, ranked by current spot price, with each region's historical min/max/avg and rank by average spot price"),
 		option.Tags("machines"),
+		option.AddError(http.StatusNotFound, "Machine type not found", models.ErrorResponse{}),
+		option.Query("continent", "Only include regions on these continents (comma separated): "+strings.Join(service.Continents(), ", ")),
+		option.Query("regions", "Only include these regions (comma separated)"),
+	)
+
+	// GET /api/v1/search/cheapest
+	fuego.Get(s, "/api/v1/search/cheapest", func(c fuego.ContextNoBody) (*models.CheapestSearchResponse, error) {
+		opts, err := searchOptions(c)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		return pricingService.SearchCheapest(opts)
+	},
+		option.Summary("Find the cheapest instances for a set of requirements"),
+		option.Description("Rank machine types in every region that meet the CPU, memory and architecture requirements by current spot price, with the price per vCPU and per GB of memory. Set stability_window to score how stable each candidate's daily spot price was"),
+		option.Tags("search"),
+		option.Query("min_cpu", "Minimum number of vCPUs"),
+		option.Query("min_memory_gb", "Minimum memory in GB"),
+		option.Query("arch", "CPU architecture: x86 or arm"),
+		option.Query("family", "Only include these machine families (comma separated, e.g. n2,c3)"),
+		option.Query("continent", "Only include regions on these continents (comma separated): "+strings.Join(service.Continents(), ", ")),
+		option.Query("regions", "Only include these regions (comma separated)"),
+		option.Query("rank_by", "Ranking: price, per_vcpu or per_gb", param.Default("price")),
+		option.QueryInt("limit", "Maximum number of candidates to return (1-500)", param.Default(20)),
+		option.Query("stability_window", "Score the daily spot price stability over this period (e.g. 30d, 12w)"),
+	)
+
+	// POST /api/v1/savings
+	fuego.Post(s, "/api/v1/savings", func(c fuego.ContextWithBody[models.SavingsRequest]) (*models.SavingsReport, error) {
+		req, err := c.Body()
+		if err != nil {
+			return nil, err
+		}
+		if err := service.ValidateSavingsRequest(req); err != nil {
+			return nil, err
+		}
+		return pricingService.CalculateSavings(req)
+	},
+		option.Summary("Calculate historical savings"),
+		option.Description("Replay the daily price history of a machine type in a region over a date range and compute, per month, what a workload of instance_count instances running hours_per_day would have cost on spot, on-demand and 1 and 3 year committed use discounts (which are billed around the clock)"),
+		option.Tags("savings"),
+		option.AddError(http.StatusNotFound, "Region or machine type not found", models.ErrorResponse{}),
+	)
+
+	// POST /api/v1/prices/batch
+	fuego.Post(s, "/api/v1/prices/batch", func(c fuego.ContextWithBody[models.BatchPriceRequest]) (*models.BatchPriceResponse, error) {
+		req, err := c.Body()
+		if err != nil {
+			return nil, err
+		}
+		opts, err := batchOptions(req)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		return pricingService.GetBatchPrices(opts)
+	},
+		option.Summary("Get prices of many machine types and regions"),
+		option.Description(fmt.Sprintf("Return the current prices of up to %d (region_name, machine_type) series in one request, in request order, and with history=true their price histories within each series' optional from/to window at the requested resolution. Series that were never seen are reported with found=false", service.MaxBatchSeries)),
+		option.Tags("prices"),
+	)
+
+	// GET /api/v1/changes
+	fuego.Get(s, "/api/v1/changes", func(c fuego.ContextNoBody) (*models.PriceChangeListResponse, error) {
+		filter := service.ChangeFilter{
+			RegionName:  c.QueryParam("region"),
+			Family:      c.QueryParam("family"),
+			MachineType: c.QueryParam("machine_type"),
+			Direction:   c.QueryParam("direction"),
+			Limit:       c.QueryParamInt("limit"),
+			Cursor:      c.QueryParam("cursor"),
+		}
+		var err error
+		if filter.From, err = timeutil.ParseTime("from", c.QueryParam("from"), false); err != nil {
+			return nil, badRequest(err)
+		}
+		if filter.To, err = timeutil.ParseTime("to", c.QueryParam("to"), true); err != nil {
+			return nil, badRequest(err)
+		}
+		if filter.MinChangePct, err = floatParam("min_change_pct", c.QueryParam("min_change_pct")); err != nil {
+			return nil, badRequest(err)
+		}
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+		return pricingService.GetPriceChanges(filter)
+	},
+		option.Summary("List price changes"),
+		option.Description("List snapshots in which the spot or on-demand price of a machine type in a region changed, newest first, with the old and new prices and the absolute and relative change. Pages are linked by next_cursor"),
+		option.Tags("changes"),
+		option.Query("region", "Only include changes in this region"),
+		option.Query("family", "Only include changes of this machine family"),
+		option.Query("machine_type", "Only include changes of this machine type"),
+		option.Query("from", "Start of the window (YYYY-MM-DD or RFC 3339)"),
+		option.Query("to", "End of the window, inclusive (YYYY-MM-DD or RFC 3339)"),
+		option.Query("direction", "up or down to only include spot price increases or decreases"),
+		option.Query("min_change_pct", "Smallest absolute spot price change to include, in percent"),
+		option.QueryInt("limit", "Maximum number of changes to return (1-500)", param.Default(50)),
+		option.Query("cursor", "next_cursor of the previous page"),
+	)
+
+	// GET /api/v1/changes/stream
+	fuego.GetStd(s, "/api/v1/changes/stream", changeStreamHandler(pricingService, changeFeed),
+		option.Summary("Stream new price changes"),
+		option.Description("Stream price changes as server-sent events as soon as the API server sees them in the database. Each change is a \"change\" event whose id is the change ID and whose data is a price change as listed by /api/v1/changes; each new snapshot is a \"snapshot\" event. Reconnecting with Last-Event-ID (or last_event_id) first replays the changes recorded after that ID"),
+		option.Tags("changes"),
+		option.Query("regions", "Only stream changes in these regions (comma separated)"),
+		option.Query("families", "Only stream changes of these machine families (comma separated)"),
+		option.Query("min_change_pct", "Smallest absolute spot price change to stream, in percent"),
+		option.Header("Last-Event-ID", "ID of the last change received; changes recorded after it are replayed"),
+		option.Query("last_event_id", "Same as the Last-Event-ID header, for clients that cannot set it"),
+	)
+
+	// Alert rules require an admin API key, checked by the limiter
+	adminOnly := option.Group(
+		option.AddError(http.StatusUnauthorized, "API key missing, unknown or revoked", models.ErrorResponse{}),
+		option.AddError(http.StatusForbidden, "API key is not an admin key", models.ErrorResponse{}),
+	)
+
+	// GET /api/v1/alerts
+	fuego.Get(s, "/api/v1/alerts", func(c fuego.ContextNoBody) (models.AlertRuleListResponse, error) {
+		rules, err := pricingService.GetAlertRules()
+		if err != nil {
+			return models.AlertRuleListResponse{}, err
+		}
+		return models.AlertRuleListResponse{
+			Rules: rules,
+			Count: len(rules),
+		}, nil
+	},
+		option.Summary("List alert rules"),
+		option.Description("List the price alert rules dataprocessing evaluates after each import. Secrets are not returned"),
+		option.Tags("alerts"),
+		adminOnly,
+	)
+
+	// POST /api/v1/alerts
+	fuego.Post(s, "/api/v1/alerts", func(c fuego.ContextWithBody[models.AlertRuleRequest]) (*models.AlertRule, error) {
+		req, err := c.Body()
+		if err != nil {
+			return nil, err
+		}
+		if err := service.ValidateAlertRule(&req); err != nil {
+			return nil, err
+		}
+		if !*allowPrivateWebhooks {
+			if err := service.CheckWebhookURL(c.Context(), req.WebhookURL); err != nil {
+				return nil, err
+			}
+		}
+		return pricingService.CreateAlertRule(req)
+	},
+		option.Summary("Create an alert rule"),
+		option.Description("Create a price alert rule. After each import, dataprocessing posts the price changes that triggered the rule to webhook_url as JSON, signed with an HMAC-SHA256 of the body in the X-Signature-256 header (sha256=<hex>), retrying on network errors, 429 and 5xx responses. above and below fire when the price crosses threshold, change_pct when it changes by at least threshold percent. The secret is only returned here"),
+		option.Tags("alerts"),
+		adminOnly,
+		option.DefaultStatusCode(http.StatusCreated),
+	)
+
+	// GET /api/v1/alerts/{id}
+	fuego.Get(s, "/api/v1/alerts/{id}", func(c fuego.ContextNoBody) (*models.AlertRule, error) {
+		id, err := fuego.PathParamIntErr(c, "id")
+		if err != nil {
+			return nil, err
+		}
+		return pricingService.GetAlertRule(int64(id))
+	},
+		option.Summary("Get an alert rule"),
+		option.Tags("alerts"),
+		adminOnly,
+		option.AddError(http.StatusNotFound, "Alert rule not found", models.ErrorResponse{}),
+	)
+
+	// PUT /api/v1/alerts/{id}
+	fuego.Put(s, "/api/v1/alerts/{id}", func(c fuego.ContextWithBody[models.AlertRuleRequest]) (*models.AlertRule, error) {
+		id, err := fuego.PathParamIntErr(c, "id")
+		if err != nil {
+			return nil, err
+		}
+		req, err := c.Body()
+		if err != nil {
+			return nil, err
+		}
+		if err := service.ValidateAlertRule(&req); err != nil {
+			return nil, err
+		}
+		if !*allowPrivateWebhooks {
+			if err := service.CheckWebhookURL(c.Context(), req.WebhookURL); err != nil {
+				return nil, err
+			}
+		}
+		return pricingService.UpdateAlertRule(int64(id), req)
+	},
+		option.Summary("Replace an alert rule"),
+		option.Description("Replace an alert rule. An empty secret keeps the current one"),
+		option.Tags("alerts"),
+		adminOnly,
+		option.AddError(http.StatusNotFound, "Alert rule not found", models.ErrorResponse{}),
+	)
+
+	// DELETE /api/v1/alerts/{id}
+	fuego.Delete(s, "/api/v1/alerts/{id}", func(c fuego.ContextNoBody) (any, error) {
+		id, err := fuego.PathParamIntErr(c, "id")
+		if err != nil {
+			return nil, err
+		}
+		return nil, pricingService.DeleteAlertRule(int64(id))
+	},
+		option.Summary("Delete an alert rule"),
+		option.Description("Delete an alert rule and its delivery log"),
+		option.Tags("alerts"),
+		adminOnly,
+		option.AddError(http.StatusNotFound, "Alert rule not found", models.ErrorResponse{}),
+		option.DefaultStatusCode(http.StatusNoContent),
+	)
+
+	// GET /api/v1/alerts/{id}/deliveries
+	fuego.Get(s, "/api/v1/alerts/{id}/deliveries", func(c fuego.ContextNoBody) (*models.AlertDeliveryListResponse, error) {
+		id, err := fuego.PathParamIntErr(c, "id")
+		if err != nil {
+			return nil, err
+		}
+		limit := c.QueryParamInt("limit")
+		if limit <= 0 || limit > service.MaxAlertDeliveries {
+			return nil, fuego.BadRequestError{Detail: fmt.Sprintf("limit must be between 1 and %d", service.MaxAlertDeliveries)}
+		}
+		deliveries, err := pricingService.GetAlertDeliveries(int64(id), limit)
+		if err != nil {
+			return nil, err
+		}
+		return &models.AlertDeliveryListResponse{
+			Deliveries: deliveries,
+			Count:      len(deliveries),
+		}, nil
+	},
+		option.Summary("List alert deliveries"),
+		option.Description("List the webhook notifications sent for an alert rule, newest first, with their status, attempts, last response and payload"),
+		option.Tags("alerts"),
+		adminOnly,
+		option.AddError(http.StatusNotFound, "Alert rule not found", models.ErrorResponse{}),
+		option.QueryInt("limit", "Maximum number of deliveries to return", param.Default(50)),
+	)
+
+	// GET /api/v1/coverage
+	fuego.Get(s, "/api/v1/coverage", func(c fuego.ContextNoBody) (*coverage.Report, error) {
+		threshold, err := timeutil.ParseDuration(c.QueryParam("threshold"))
+		if err != nil {
+			return nil, fuego.BadRequestError{Detail: "invalid threshold: " + err.Error()}
+		}
+		return pricingService.GetCoverage(coverage.Options{
+			GapThreshold: threshold,
+			RegionName:   c.QueryParam("region"),
+			MachineType:  c.QueryParam("machine_type"),
+		})
+	},
+		option.Summary("Snapshot coverage report"),
+		option.Description("Find periods without snapshots longer than a threshold, overall and per region/machine series where a machine is missing from snapshots it should appear in"),
+		option.Tags("coverage"),
+		option.Query("threshold", "Longest interval between snapshots not reported as a gap (e.g. 36h, 7d)", param.Default("7d")),
+		option.Query("region", "Only analyze series in this region"),
+		option.Query("machine_type", "Only analyze series of this machine type"),
 	)
 
 	// GET /api/v1/health
@@ -194,9 +780,43 @@ func main() {
 		option.Tags("health"),
 	)
 
+	// GraphQL endpoint, outside the OpenAPI description
+	graphHandler, err := graph.NewHandler(pricingService)
+	if err != nil {
+		slog.Error("failed to build GraphQL schema", "error", err)
+		return
+	}
+	s.Mux.Handle("/graphql", limiter.Middleware(*anonymousAPI)(graphHandler))
+
+	// Prometheus metrics, outside the OpenAPI description
+	metricsHandler := apiMetrics.Handler()
+	if !*publicMetrics {
+		metricsHandler = limiter.Middleware(*anonymousAPI)(metricsHandler)
+	}
+	s.Mux.Handle("GET /metrics", metricsHandler)
+
 	// Mount Echo routes on Fuego server
 	s.Mux.Handle("/", e)
 
+	// Start the gRPC server next to the HTTP server
+	if *grpcPort != "" {
+		listener, err := net.Listen("tcp", ":"+*grpcPort)
+		if err != nil {
+			slog.Error("failed to listen for gRPC", "port", *grpcPort, "error", err)
+			return
+		}
+		grpcServer := rpc.NewServer(pricingService,
+			grpc.UnaryInterceptor(limiter.UnaryInterceptor(*anonymousAPI)),
+			grpc.StreamInterceptor(limiter.StreamInterceptor(*anonymousAPI)))
+		defer grpcServer.GracefulStop()
+		go func() {
+			slog.Info("starting gRPC server", "port", *grpcPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Error("gRPC server stopped", "error", err)
+			}
+		}()
+	}
+
 	// Update server address
 	s.Addr = ":" + *port
 
@@ -0,0 +1,153 @@
+// Package metrics exports current prices, HTTP request and database query metrics in the
+// Prometheus format.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+const namespace = "spot_price_history"
+
+// Metrics holds the registry behind /metrics.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+}
+
+// New registers the price gauges of pricing, which are read from the database on every
+// scrape, next to the request and query metrics and the Go runtime metrics.
+func New(pricing *service.PricingService) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to serve HTTP requests by method and route; streams count until they are closed.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time to run database statements, including scanning their rows, by query name and operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"query", "operation"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Database statements that failed, by query name and operation. Queries without a row are not counted.",
+		}, []string{"query", "operation"}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.requestDuration, m.queryDuration, m.queryErrors,
+		&priceCollector{pricing: pricing},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveQuery records a database statement. It is a db.QueryObserver, installed with
+// db.Querier.SetObserver. Statements run without a name are recorded as "unnamed".
+func (m *Metrics) ObserveQuery(name, operation string, duration time.Duration, err error) {
+	if name == "" {
+		name = "unnamed"
+	}
+	m.queryDuration.WithLabelValues(name, operation).Observe(duration.Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		m.queryErrors.WithLabelValues(name, operation).Inc()
+	}
+}
+
+// routeKey holds the *string a router below Middleware stores its matched route in.
+type routeKey struct{}
+
+// Middleware records every request by the route pattern it matched: the pattern of the
+// http.ServeMux route, or the route reported by EchoRoute for requests passed on to Echo.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var route string
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, &route))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if route == "" {
+			// Patterns may start with a method, which is already a label
+			_, path, found := strings.Cut(r.Pattern, " ")
+			if !found {
+				path = r.Pattern
+			}
+			route = path
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+		m.requestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// EchoRoute reports the route Echo matched to Middleware, since the ServeMux pattern of
+// every Echo request is the catch-all "/".
+func EchoRoute() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if route, ok := c.Request().Context().Value(routeKey{}).(*string); ok {
+				*route = c.Path()
+				if c.Path() == "" || errors.Is(err, echo.ErrNotFound) {
+					*route = "unmatched"
+				}
+			}
+			return err
+		}
+	}
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes flushes of streaming responses on; writers wrapping this one look for
+// http.Flusher rather than unwrapping it.
+func (r *statusRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+var (
+	spotPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "spot_price_usd_per_hour"),
+		"Current spot price per hour of a machine type in a region.",
+		[]string{"machine_type", "region", "family"}, nil,
+	)
+	onDemandPriceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "on_demand_price_usd_per_hour"),
+		"Current on-demand price per hour of a machine type in a region.",
+		[]string{"machine_type", "region", "family"}, nil,
+	)
+	snapshotTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "snapshot_timestamp_seconds"),
+		"Unix time of the latest price snapshot.",
+		nil, nil,
+	)
+	snapshotAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "snapshot_age_seconds"),
+		"Time since the latest price snapshot.",
+		nil, nil,
+	)
+)
+
+// priceCollector reads the prices of the latest snapshot on every scrape, so the gauges
+// follow imports without the API polling for them.
+type priceCollector struct {
+	pricing *service.PricingService
+}
+
+func (c *priceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- spotPriceDesc
+	ch <- onDemandPriceDesc
+	ch <- snapshotTimestampDesc
+	ch <- snapshotAgeDesc
+}
+
+func (c *priceCollector) Collect(ch chan<- prometheus.Metric) {
+	prices, snapshotAt, err := c.pricing.GetCurrentPrices()
+	if err != nil {
+		slog.Error("failed to collect price metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(spotPriceDesc, err)
+		return
+	}
+	if snapshotAt.IsZero() {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(snapshotTimestampDesc, prometheus.GaugeValue, float64(snapshotAt.Unix()))
+	ch <- prometheus.MustNewConstMetric(snapshotAgeDesc, prometheus.GaugeValue, time.Since(snapshotAt).Seconds())
+	for _, p := range prices {
+		ch <- prometheus.MustNewConstMetric(spotPriceDesc, prometheus.GaugeValue, p.HourSpotPrice, p.MachineType, p.RegionName, p.Family)
+		ch <- prometheus.MustNewConstMetric(onDemandPriceDesc, prometheus.GaugeValue, p.HourPrice, p.MachineType, p.RegionName, p.Family)
+	}
+}
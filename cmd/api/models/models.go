@@ -9,35 +9,68 @@ type Region struct {
 }
 
 // Machine represents a machine type with pricing information.
+// HourSpotPrice and HourPrice are the prices in the latest snapshot containing the machine.
 type Machine struct {
-	MachineType      string  `json:"machine_type" example:"n1-standard-1"`
-	RegionName       string  `json:"region_name" example:"us-central1"`
-	MinHourSpotPrice float64 `json:"min_hour_spot_price" example:"0.01"`
-	MaxHourSpotPrice float64 `json:"max_hour_spot_price" example:"0.05"`
-	HourSpotPrice    float64 `json:"hour_spot_price" example:"0.02"`
+	MachineType      string           `json:"machine_type" example:"n1-standard-1"`
+	RegionName       string           `json:"region_name" example:"us-central1"`
+	MinHourSpotPrice float64          `json:"min_hour_spot_price" example:"0.01"`
+	MaxHourSpotPrice float64          `json:"max_hour_spot_price" example:"0.05"`
+	AvgHourSpotPrice float64          `json:"avg_hour_spot_price" example:"0.025"`
+	HourSpotPrice    float64          `json:"hour_spot_price" example:"0.02"`
+	HourPrice        float64          `json:"hour_price" example:"0.0475" description:"Current on-demand price per hour"`
+	SpotDiscountPct  float64          `json:"spot_discount_pct" example:"57.89" description:"Current spot discount relative to on-demand, in percent"`
+	FirstSeen        time.Time        `json:"first_seen" example:"2023-05-08T06:42:47Z"`
+	LastSeen         time.Time        `json:"last_seen" example:"2025-05-22T04:01:24Z"`
+	ChangeCount      int              `json:"change_count" example:"12" description:"Number of spot price changes between consecutive snapshots"`
+	LastChangedAt    *time.Time       `json:"last_changed_at" example:"2025-04-01T04:01:24Z" description:"Snapshot in which the spot price last changed, null if it never changed"`
+	PreviousPrice    *float64         `json:"previous_price" example:"0.025" description:"Spot price before the last change"`
+	ChangePct        *float64         `json:"change_pct" example:"-20" description:"Last spot price change relative to the previous price, in percent"`
+	UnitPrices       *UnitPrices      `json:"unit_prices,omitempty" description:"Current prices per vCPU and per GB of memory, only present when requested"`
+	Volatility       *VolatilityStats `json:"volatility,omitempty" description:"Only present when stats were requested or the list is sorted by a volatility metric"`
 }
 
 // PriceHistory represents a single price data point.
+// Downsampled points (compacted history or a coarser requested resolution) cover a
+// bucket: Price is then the last price in the bucket and MinPrice/MaxPrice its range.
 type PriceHistory struct {
-	Price     float64   `json:"price" example:"0.02"`
-	Timestamp time.Time `json:"timestamp" example:"2024-01-01T00:00:00Z"`
+	Price           float64     `json:"price" example:"0.02"`
+	MinPrice        float64     `json:"min_price" example:"0.018"`
+	MaxPrice        float64     `json:"max_price" example:"0.021"`
+	OnDemandPrice   float64     `json:"on_demand_price" example:"0.0475"`
+	SpotDiscountPct float64     `json:"spot_discount_pct" example:"57.89" description:"Spot discount relative to on-demand, in percent"`
+	Timestamp       time.Time   `json:"timestamp" example:"2024-01-01T00:00:00Z"`
+	Resolution      string      `json:"resolution" example:"raw" description:"Resolution of this point: raw, day, week or month"`
+	UnitPrices      *UnitPrices `json:"unit_prices,omitempty" description:"Prices per vCPU and per GB of memory, only present when requested"`
 }
 
 // MachineDetail contains full machine information including price history.
+// HourSpotPrice and HourPrice are the prices in the latest snapshot, independent of the selected window.
 type MachineDetail struct {
 	MachineType          string         `json:"machine_type"`
 	RegionName           string         `json:"region_name"`
 	MinHourSpotPrice     float64        `json:"min_hour_spot_price"`
 	MaxHourSpotPrice     float64        `json:"max_hour_spot_price"`
 	HourSpotPrice        float64        `json:"hour_spot_price"`
+	HourPrice            float64        `json:"hour_price" description:"Current on-demand price per hour"`
+	SpotDiscountPct      float64        `json:"spot_discount_pct" description:"Current spot discount relative to on-demand, in percent"`
+	LastChangedAt        *time.Time     `json:"last_changed_at" description:"Snapshot in which the spot price last changed, null if it never changed"`
+	PreviousPrice        *float64       `json:"previous_price" description:"Spot price before the last change"`
+	ChangePct            *float64       `json:"change_pct" description:"Last spot price change relative to the previous price, in percent"`
+	CpuCores             float64        `json:"cpu_cores,omitempty" example:"8" description:"Only present when unit prices were requested"`
+	MemoryGB             float64        `json:"memory_gb,omitempty" example:"32" description:"Only present when unit prices were requested"`
+	UnitPrices           *UnitPrices    `json:"unit_prices,omitempty" description:"Current prices per vCPU and per GB of memory, only present when requested"`
+	From                 *time.Time     `json:"from,omitempty" description:"Start of the selected window; statistics cover the window"`
+	To                   *time.Time     `json:"to,omitempty" description:"End of the selected window"`
+	Resolution           string         `json:"resolution" example:"raw" description:"Resolution of the returned history: raw, day, week, month or mixed"`
 	SpotHourPriceHistory []PriceHistory `json:"spot_hour_price_history"`
 }
 
-// ErrorResponse represents an error response.
+// ErrorResponse is the body of every error response of the JSON API.
 type ErrorResponse struct {
-	Error   string `json:"error" example:"Invalid request"`
-	Message string `json:"message" example:"Detailed error message"`
-	Code    int    `json:"code" example:"400"`
+	Error   string `json:"error" example:"Not Found" description:"HTTP status text"`
+	Message string `json:"message" example:"region \"us-nowhere1\" not found" description:"What went wrong"`
+	Code    int    `json:"code" example:"404" description:"HTTP status code"`
+	Param   string `json:"param,omitempty" example:"resolution" description:"Name of the rejected parameter, for invalid parameters that concern a single one"`
 }
 
 // SuccessResponse represents a generic success response.
@@ -54,7 +87,392 @@ type RegionListResponse struct {
 
 // MachineListResponse represents a list of machines response.
 type MachineListResponse struct {
-	RegionName string    `json:"region_name"`
-	Machines   []Machine `json:"machines"`
-	Count      int       `json:"count"`
+	RegionName string     `json:"region_name"`
+	AsOf       *time.Time `json:"as_of,omitempty" description:"Instant the listing describes, only present for point-in-time listings"`
+	Machines   []Machine  `json:"machines"`
+	Count      int        `json:"count"`
+}
+
+// IngestionRun describes one dataprocessing import recorded in ingestion_runs.
+type IngestionRun struct {
+	ID             int64      `json:"id" example:"42"`
+	StartedAt      time.Time  `json:"started_at" example:"2024-01-01T00:00:00Z"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" example:"2024-01-01T00:02:00Z"`
+	Status         string     `json:"status" example:"completed" description:"running, completed, completed_with_errors or failed"`
+	DataPath       string     `json:"data_path" example:"/tmp/pricing-data"`
+	FilesProcessed int        `json:"files_processed" example:"120"`
+	FilesSkipped   int        `json:"files_skipped" example:"0"`
+	FilesFailed    int        `json:"files_failed" example:"0"`
+	RowsInserted   int64      `json:"rows_inserted" example:"24000"`
+	RowsIgnored    int64      `json:"rows_ignored" example:"1200000"`
+	WarningCount   int        `json:"warning_count" example:"0"`
+	ErrorCount     int        `json:"error_count" example:"0"`
+	Warnings       []string   `json:"warnings"`
+	Errors         []string   `json:"errors"`
+}
+
+// IngestionRunListResponse represents a list of ingestion runs, most recent first.
+type IngestionRunListResponse struct {
+	Runs  []IngestionRun `json:"runs"`
+	Count int            `json:"count"`
+}
+
+// DailyPrice is the price of a machine type on one calendar day (UTC), carried
+// forward from the last observation at or before the end of the day.
+type DailyPrice struct {
+	Date           string    `json:"date" example:"2024-01-01"`
+	HourPrice      float64   `json:"hour_price" example:"0.38" description:"On-demand price per hour"`
+	HourSpotPrice  float64   `json:"hour_spot_price" example:"0.09"`
+	ObservedAt     time.Time `json:"observed_at" example:"2023-12-30T06:42:47Z" description:"Timestamp of the snapshot the prices come from"`
+	CarriedForward bool      `json:"carried_forward" example:"true" description:"True if no snapshot was taken on this day"`
+}
+
+// DailyPriceSeries represents the daily price series of a machine type in a region.
+type DailyPriceSeries struct {
+	MachineType string       `json:"machine_type" example:"n2-standard-8"`
+	RegionName  string       `json:"region_name" example:"us-central1"`
+	From        string       `json:"from,omitempty" example:"2024-01-01"`
+	To          string       `json:"to,omitempty" example:"2024-03-31"`
+	Prices      []DailyPrice `json:"prices"`
+	Count       int          `json:"count"`
+}
+
+// RegionPrice holds the current and historical prices of a machine type in one region.
+type RegionPrice struct {
+	RegionName       string    `json:"region_name" example:"europe-west4"`
+	Continent        string    `json:"continent" example:"europe"`
+	Rank             int       `json:"rank" example:"1" description:"Rank by current spot price, 1 is cheapest"`
+	HistoricalRank   int       `json:"historical_rank" example:"3" description:"Rank by average spot price over the whole history, 1 is cheapest"`
+	HourSpotPrice    float64   `json:"hour_spot_price" example:"0.09"`
+	HourPrice        float64   `json:"hour_price" example:"0.38"`
+	SpotDiscountPct  float64   `json:"spot_discount_pct" example:"76.3"`
+	MinHourSpotPrice float64   `json:"min_hour_spot_price" example:"0.07"`
+	MaxHourSpotPrice float64   `json:"max_hour_spot_price" example:"0.12"`
+	AvgHourSpotPrice float64   `json:"avg_hour_spot_price" example:"0.095"`
+	LastSeen         time.Time `json:"last_seen"`
+	InLatestSnapshot bool      `json:"in_latest_snapshot" description:"False if the machine type is no longer offered in this region"`
+}
+
+// MachineRegionComparison compares a machine type across regions.
+type MachineRegionComparison struct {
+	MachineType                string        `json:"machine_type" example:"n2-standard-8"`
+	CheapestRegion             string        `json:"cheapest_region,omitempty" example:"us-central1"`
+	HistoricallyCheapestRegion string        `json:"historically_cheapest_region,omitempty" example:"us-east1"`
+	Regions                    []RegionPrice `json:"regions"`
+	Count                      int           `json:"count"`
+}
+
+// VolatilityStats describes how much the daily spot price of a machine type in a region
+// moved over a window ending at the latest snapshot.
+type VolatilityStats struct {
+	WindowDays              int      `json:"window_days" example:"90"`
+	From                    string   `json:"from" example:"2024-01-01" description:"First day of the window"`
+	To                      string   `json:"to" example:"2024-03-30" description:"Last day of the window, the day of the latest snapshot"`
+	Days                    int      `json:"days" example:"90" description:"Days with a price in the window"`
+	MeanHourSpotPrice       float64  `json:"mean_hour_spot_price" example:"0.095"`
+	MinHourSpotPrice        float64  `json:"min_hour_spot_price" example:"0.081"`
+	MaxHourSpotPrice        float64  `json:"max_hour_spot_price" example:"0.12"`
+	StdDev                  float64  `json:"std_dev" example:"0.0076" description:"Standard deviation of the daily spot price"`
+	CoefficientOfVariation  float64  `json:"coefficient_of_variation" example:"0.08" description:"Standard deviation divided by the mean"`
+	ChangeCount             int      `json:"change_count" example:"6" description:"Spot price changes in the window"`
+	MeanHoursBetweenChanges *float64 `json:"mean_hours_between_changes" example:"312" description:"Average time between consecutive spot price changes, null with fewer than two changes"`
+	MaxDrawdownPct          float64  `json:"max_drawdown_pct" example:"18.5" description:"Largest fall of the daily spot price from a preceding high, in percent"`
+	MaxSpikePct             float64  `json:"max_spike_pct" example:"32.1" description:"Largest rise of the daily spot price from a preceding low, in percent"`
+	StabilityScore          float64  `json:"stability_score" example:"0.556" description:"1 for a constant price, 0.5 when the standard deviation is 10% of the mean, approaching 0 for very volatile prices"`
+}
+
+// MachineStats holds the volatility metrics of a machine type in a region.
+type MachineStats struct {
+	MachineType string          `json:"machine_type" example:"n2-standard-8"`
+	RegionName  string          `json:"region_name" example:"us-central1"`
+	Volatility  VolatilityStats `json:"volatility"`
+}
+
+// InstanceCandidate is a machine type in a region that satisfies a search.
+type InstanceCandidate struct {
+	Rank             int              `json:"rank" example:"1"`
+	MachineType      string           `json:"machine_type" example:"n2-standard-16"`
+	Family           string           `json:"family" example:"n2"`
+	Arch             string           `json:"arch" example:"x86"`
+	RegionName       string           `json:"region_name" example:"us-central1"`
+	Continent        string           `json:"continent" example:"north-america"`
+	CpuCores         float64          `json:"cpu_cores" example:"16"`
+	MemoryGB         float64          `json:"memory_gb" example:"64"`
+	HourSpotPrice    float64          `json:"hour_spot_price" example:"0.16"`
+	HourPrice        float64          `json:"hour_price" example:"0.78"`
+	SpotDiscountPct  float64          `json:"spot_discount_pct" example:"79.5"`
+	SpotPricePerVCPU float64          `json:"spot_price_per_vcpu" example:"0.01" description:"Spot price per vCPU hour"`
+	SpotPricePerGB   float64          `json:"spot_price_per_gb" example:"0.0025" description:"Spot price per GB of memory per hour"`
+	Stability        *VolatilityStats `json:"stability,omitempty" description:"Only present when a stability window was requested"`
+}
+
+// CheapestSearchResponse lists the candidates matching a search, cheapest first.
+type CheapestSearchResponse struct {
+	Candidates []InstanceCandidate `json:"candidates"`
+	Count      int                 `json:"count"`
+}
+
+// UnitPrices normalizes hourly prices by the machine's vCPU count and memory size.
+// They are computed from the current machine specification.
+type UnitPrices struct {
+	SpotPerVCPU     float64 `json:"spot_per_vcpu" example:"0.0025" description:"Spot price per vCPU hour"`
+	SpotPerGB       float64 `json:"spot_per_gb" example:"0.0006" description:"Spot price per GB of memory per hour"`
+	OnDemandPerVCPU float64 `json:"on_demand_per_vcpu" example:"0.0119" description:"On-demand price per vCPU hour"`
+	OnDemandPerGB   float64 `json:"on_demand_per_gb" example:"0.003" description:"On-demand price per GB of memory per hour"`
+}
+
+// MachineSpec is the size of a machine type.
+type MachineSpec struct {
+	MachineType string  `json:"machine_type" example:"n2-standard-8"`
+	CpuCores    float64 `json:"cpu_cores" example:"8"`
+	MemoryGB    float64 `json:"memory_gb" example:"32"`
+}
+
+// FamilyUnitPricePoint aggregates the unit prices of all machine types and regions of a family in one period.
+type FamilyUnitPricePoint struct {
+	Date            string  `json:"date" example:"2024-01-01" description:"First day of the period"`
+	SpotPerVCPU     float64 `json:"spot_per_vcpu" example:"0.0031" description:"Average spot price per vCPU hour"`
+	MinSpotPerVCPU  float64 `json:"min_spot_per_vcpu" example:"0.0021" description:"Lowest spot price per vCPU hour"`
+	MaxSpotPerVCPU  float64 `json:"max_spot_per_vcpu" example:"0.0052" description:"Highest spot price per vCPU hour"`
+	SpotPerGB       float64 `json:"spot_per_gb" example:"0.0008" description:"Average spot price per GB of memory per hour"`
+	OnDemandPerVCPU float64 `json:"on_demand_per_vcpu" example:"0.0119" description:"Average on-demand price per vCPU hour"`
+	OnDemandPerGB   float64 `json:"on_demand_per_gb" example:"0.003" description:"Average on-demand price per GB of memory per hour"`
+	Series          int     `json:"series" example:"240" description:"Number of machine type/region series in the period"`
+}
+
+// FamilyUnitPriceSeries is the unit price series of one machine family.
+type FamilyUnitPriceSeries struct {
+	Family string                 `json:"family" example:"n2"`
+	Points []FamilyUnitPricePoint `json:"points"`
+}
+
+// FamilyUnitPriceResponse holds unit price series for several machine families.
+type FamilyUnitPriceResponse struct {
+	Resolution string                  `json:"resolution" example:"week"`
+	From       string                  `json:"from,omitempty" example:"2024-01-01"`
+	To         string                  `json:"to,omitempty" example:"2024-12-31"`
+	Families   []FamilyUnitPriceSeries `json:"families"`
+}
+
+// PriceChange is a snapshot in which the spot or on-demand price of a machine type in a region moved.
+type PriceChange struct {
+	ID                int64     `json:"id" example:"1523"`
+	MachineType       string    `json:"machine_type" example:"n2-standard-8"`
+	Family            string    `json:"family" example:"n2"`
+	RegionName        string    `json:"region_name" example:"us-central1"`
+	Timestamp         time.Time `json:"timestamp" example:"2024-03-01T06:42:47Z" description:"Snapshot in which the new prices were first observed"`
+	OldHourSpotPrice  float64   `json:"old_hour_spot_price" example:"0.08"`
+	NewHourSpotPrice  float64   `json:"new_hour_spot_price" example:"0.09"`
+	SpotChange        float64   `json:"spot_change" example:"0.01" description:"New minus old spot price"`
+	SpotChangePct     float64   `json:"spot_change_pct" example:"12.5" description:"Spot price change relative to the old price, in percent"`
+	OldHourPrice      float64   `json:"old_hour_price" example:"0.38" description:"On-demand price before the change"`
+	NewHourPrice      float64   `json:"new_hour_price" example:"0.38" description:"On-demand price after the change"`
+	OnDemandChange    float64   `json:"on_demand_change" example:"0" description:"New minus old on-demand price"`
+	OnDemandChangePct float64   `json:"on_demand_change_pct" example:"0" description:"On-demand price change relative to the old price, in percent"`
+}
+
+// PriceChangeListResponse is a page of price changes, newest first.
+type PriceChangeListResponse struct {
+	Changes    []PriceChange `json:"changes"`
+	Count      int           `json:"count"`
+	NextCursor string        `json:"next_cursor,omitempty" example:"1709275367:1523" description:"Pass as cursor to fetch the next page; absent on the last page"`
+}
+
+// SnapshotEvent announces a new snapshot on the change stream.
+type SnapshotEvent struct {
+	SnapshotAt time.Time `json:"snapshot_at" example:"2024-03-01T06:42:47Z" description:"Time of the newest snapshot"`
+}
+
+// ForecastPoint is the projected spot price of one day with its prediction interval.
+type ForecastPoint struct {
+	Date   string   `json:"date" example:"2024-04-01"`
+	Price  float64  `json:"price" example:"0.092"`
+	Lower  float64  `json:"lower" example:"0.081" description:"Lower bound of the prediction interval"`
+	Upper  float64  `json:"upper" example:"0.103" description:"Upper bound of the prediction interval"`
+	Actual *float64 `json:"actual,omitempty" example:"0.09" description:"Observed price, only present in backtests"`
+}
+
+// ForecastParameters describes the fitted model. Only the parameters of the selected model are set.
+type ForecastParameters struct {
+	Slope     *float64 `json:"slope,omitempty" example:"0.0001" description:"Linear trend in price per day"`
+	Intercept *float64 `json:"intercept,omitempty" example:"0.085" description:"Linear trend value on the first training day"`
+	Alpha     *float64 `json:"alpha,omitempty" example:"0.3" description:"Holt level smoothing factor"`
+	Beta      *float64 `json:"beta,omitempty" example:"0.05" description:"Holt trend smoothing factor"`
+	Sigma     float64  `json:"sigma" example:"0.004" description:"Standard deviation of the in-sample one-step errors"`
+}
+
+// ForecastBacktest compares a forecast made without the last days of history to what was observed.
+type ForecastBacktest struct {
+	HeldOutDays         int             `json:"held_out_days" example:"30"`
+	MAE                 float64         `json:"mae" example:"0.0042" description:"Mean absolute error"`
+	MAPE                float64         `json:"mape" example:"4.6" description:"Mean absolute percentage error"`
+	RMSE                float64         `json:"rmse" example:"0.0051" description:"Root mean squared error"`
+	IntervalCoveragePct float64         `json:"interval_coverage_pct" example:"93.3" description:"Share of observed prices inside the prediction interval, in percent"`
+	NaiveMAE            float64         `json:"naive_mae" example:"0.0049" description:"Mean absolute error of repeating the last training price"`
+	NaiveMAPE           float64         `json:"naive_mape" example:"5.3" description:"Mean absolute percentage error of repeating the last training price"`
+	Points              []ForecastPoint `json:"points"`
+}
+
+// SpotPriceForecast projects the daily spot price of a machine type in a region.
+type SpotPriceForecast struct {
+	MachineType  string             `json:"machine_type" example:"n2-standard-8"`
+	RegionName   string             `json:"region_name" example:"us-central1"`
+	Model        string             `json:"model" example:"holt" description:"linear or holt"`
+	Confidence   int                `json:"confidence" example:"95" description:"Confidence level of the prediction intervals, in percent"`
+	HorizonDays  int                `json:"horizon_days" example:"30"`
+	TrainingFrom string             `json:"training_from" example:"2023-10-04"`
+	TrainingTo   string             `json:"training_to" example:"2024-03-31"`
+	TrainingDays int                `json:"training_days" example:"180"`
+	LastPrice    float64            `json:"last_price" example:"0.09" description:"Spot price on the last training day"`
+	Parameters   ForecastParameters `json:"parameters"`
+	Points       []ForecastPoint    `json:"points"`
+	Backtest     *ForecastBacktest  `json:"backtest,omitempty" description:"Only present when a backtest was requested"`
+}
+
+// SavingsRequest describes a workload to price against the stored history.
+type SavingsRequest struct {
+	MachineType   string  `json:"machine_type" example:"n2-standard-8"`
+	RegionName    string  `json:"region_name" example:"europe-west1"`
+	InstanceCount int     `json:"instance_count" example:"10"`
+	HoursPerDay   float64 `json:"hours_per_day" example:"24" description:"Hours per day the instances run (0-24]"`
+	From          string  `json:"from" example:"2024-01-01" description:"First day (YYYY-MM-DD)"`
+	To            string  `json:"to" example:"2024-12-31" description:"Last day, inclusive (YYYY-MM-DD)"`
+}
+
+// SavingsPeriod is the cost of a workload over a month or the whole range.
+type SavingsPeriod struct {
+	Month          string   `json:"month,omitempty" example:"2024-01"`
+	Days           int      `json:"days" example:"31" description:"Days with prices"`
+	InstanceHours  float64  `json:"instance_hours" example:"7440"`
+	SpotCost       float64  `json:"spot_cost" example:"670.12"`
+	OnDemandCost   float64  `json:"on_demand_cost" example:"2827.2"`
+	Commit1yCost   *float64 `json:"commit_1y_cost" example:"1781.14" description:"Cost with a 1 year committed use discount, billed around the clock; null if any day lacks a 1 year commitment price"`
+	Commit3yCost   *float64 `json:"commit_3y_cost" example:"1272.24" description:"Cost with a 3 year committed use discount, billed around the clock; null if any day lacks a 3 year commitment price"`
+	SpotSavingsPct float64  `json:"spot_savings_pct" example:"76.3" description:"Spot savings relative to on-demand, in percent"`
+}
+
+// SavingsReport is what a workload would have cost on spot, on-demand and committed use pricing.
+type SavingsReport struct {
+	MachineType                 string          `json:"machine_type" example:"n2-standard-8"`
+	RegionName                  string          `json:"region_name" example:"europe-west1"`
+	InstanceCount               int             `json:"instance_count" example:"10"`
+	HoursPerDay                 float64         `json:"hours_per_day" example:"24"`
+	From                        string          `json:"from" example:"2024-01-01"`
+	To                          string          `json:"to" example:"2024-12-31"`
+	DaysWithoutPrices           int             `json:"days_without_prices" example:"0" description:"Days in the range before the machine type was first or after it was last observed, excluded from all costs"`
+	DaysWithoutCommitmentPrices int             `json:"days_without_commitment_prices" example:"0" description:"Days whose snapshot had no committed use prices; commitment costs of periods containing them are null"`
+	CheapestOption              string          `json:"cheapest_option,omitempty" example:"spot" description:"spot, on_demand, commit_1y or commit_3y; commitment options without a cost are not considered"`
+	Total                       SavingsPeriod   `json:"total"`
+	Months                      []SavingsPeriod `json:"months"`
+}
+
+// SnapshotPrice is the price of a machine type in a region in effect at a point in time.
+type SnapshotPrice struct {
+	RegionName      string    `json:"region_name" example:"europe-west1"`
+	MachineType     string    `json:"machine_type" example:"n2-standard-8"`
+	HourSpotPrice   float64   `json:"hour_spot_price" example:"0.09"`
+	HourPrice       float64   `json:"hour_price" example:"0.38"`
+	SpotDiscountPct float64   `json:"spot_discount_pct" example:"76.3"`
+	ObservedAt      time.Time `json:"observed_at" example:"2024-03-14T06:42:47Z" description:"Snapshot the prices come from, the latest at or before as_of"`
+	InSnapshot      bool      `json:"in_snapshot" description:"False if the machine type was missing from the snapshot in effect, so its prices come from an older one"`
+}
+
+// SnapshotResponse lists the prices in effect at a point in time.
+type SnapshotResponse struct {
+	AsOf       time.Time       `json:"as_of" example:"2024-03-15T23:59:59Z"`
+	SnapshotAt *time.Time      `json:"snapshot_at,omitempty" example:"2024-03-14T06:42:47Z" description:"Latest snapshot at or before as_of; absent if there is none"`
+	Prices     []SnapshotPrice `json:"prices"`
+	Count      int             `json:"count"`
+}
+
+// BatchPriceSeries selects a machine type in a region for a batch price query.
+type BatchPriceSeries struct {
+	RegionName  string `json:"region_name" example:"europe-west1"`
+	MachineType string `json:"machine_type" example:"n2-standard-8"`
+	From        string `json:"from,omitempty" example:"2024-01-01" description:"Start of the history window (YYYY-MM-DD or RFC 3339), open if empty"`
+	To          string `json:"to,omitempty" example:"2024-03-31" description:"End of the history window, inclusive; a date means the end of that day"`
+}
+
+// BatchPriceRequest asks for the prices of many machine types and regions at once.
+type BatchPriceRequest struct {
+	Series     []BatchPriceSeries `json:"series"`
+	History    bool               `json:"history" description:"Include the price history of every series within its window"`
+	Resolution string             `json:"resolution,omitempty" example:"day" description:"History resolution: raw (default), day, week or month"`
+	Units      bool               `json:"units" description:"Add prices per vCPU and per GB of memory"`
+}
+
+// BatchPriceResult holds the prices of one requested series, in request order.
+type BatchPriceResult struct {
+	RegionName  string         `json:"region_name" example:"europe-west1"`
+	MachineType string         `json:"machine_type" example:"n2-standard-8"`
+	Found       bool           `json:"found" description:"False if the machine type was never seen in the region"`
+	Current     *Machine       `json:"current,omitempty" description:"Current prices and summary statistics, absent if not found"`
+	History     []PriceHistory `json:"history,omitempty" description:"Price history within the window, only present when requested and not empty"`
+}
+
+// BatchPriceResponse answers a batch price query.
+type BatchPriceResponse struct {
+	Results []BatchPriceResult `json:"results"`
+	Count   int                `json:"count"`
+}
+
+// AlertRuleRequest creates or replaces an alert rule. Empty scope fields match every
+// machine type, family, region or continent.
+type AlertRuleRequest struct {
+	Name        string  `json:"name" example:"c3-standard-8 us-east1 above 0.12"`
+	MachineType string  `json:"machine_type,omitempty" example:"c3-standard-8"`
+	Family      string  `json:"family,omitempty" example:"c3"`
+	RegionName  string  `json:"region_name,omitempty" example:"us-east1"`
+	Continent   string  `json:"continent,omitempty" example:"north-america"`
+	Price       string  `json:"price,omitempty" example:"spot" description:"Price to watch: spot (default) or on_demand"`
+	Condition   string  `json:"condition" example:"above" description:"above or below fire when the price crosses threshold; change_pct fires when it changes by at least threshold percent"`
+	Threshold   float64 `json:"threshold" example:"0.12" description:"Hourly price in USD, or percent for change_pct"`
+	WebhookURL  string  `json:"webhook_url" example:"https://example.com/hooks/spot"`
+	Secret      string  `json:"secret,omitempty" description:"Key of the X-Signature-256 HMAC-SHA256 signature; generated on create and kept on update when empty"`
+	Enabled     *bool   `json:"enabled,omitempty" example:"true" description:"Defaults to true"`
+}
+
+// AlertRule is a stored alert rule.
+type AlertRule struct {
+	ID          int64     `json:"id" example:"3"`
+	Name        string    `json:"name" example:"c3-standard-8 us-east1 above 0.12"`
+	MachineType string    `json:"machine_type,omitempty" example:"c3-standard-8"`
+	Family      string    `json:"family,omitempty" example:"c3"`
+	RegionName  string    `json:"region_name,omitempty" example:"us-east1"`
+	Continent   string    `json:"continent,omitempty" example:"north-america"`
+	Price       string    `json:"price" example:"spot"`
+	Condition   string    `json:"condition" example:"above"`
+	Threshold   float64   `json:"threshold" example:"0.12"`
+	WebhookURL  string    `json:"webhook_url" example:"https://example.com/hooks/spot"`
+	Secret      string    `json:"secret,omitempty" description:"Only returned when the rule is created or its secret replaced"`
+	Enabled     bool      `json:"enabled" example:"true"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-03-01T06:42:47Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-03-01T06:42:47Z"`
+}
+
+// AlertRuleListResponse lists alert rules by ID.
+type AlertRuleListResponse struct {
+	Rules []AlertRule `json:"rules"`
+	Count int         `json:"count"`
+}
+
+// AlertDelivery is a webhook notification logged in alert_deliveries.
+type AlertDelivery struct {
+	ID             int64      `json:"id" example:"17"`
+	RuleID         int64      `json:"rule_id" example:"3"`
+	IngestionRunID int64      `json:"ingestion_run_id" example:"42"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-03-01T06:45:00Z"`
+	Status         string     `json:"status" example:"delivered" description:"pending, delivered or failed"`
+	EventCount     int        `json:"event_count" example:"2" description:"Price changes that triggered the rule"`
+	Attempts       int        `json:"attempts" example:"1"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty" example:"2024-03-01T06:45:00Z"`
+	ResponseStatus *int       `json:"response_status,omitempty" example:"200" description:"HTTP status of the last attempt, absent if the webhook did not respond"`
+	Error          string     `json:"error,omitempty" example:"webhook responded 503 Service Unavailable"`
+	Payload        string     `json:"payload,omitempty" description:"JSON body sent to the webhook"`
+}
+
+// AlertDeliveryListResponse lists the deliveries of an alert rule, newest first.
+type AlertDeliveryListResponse struct {
+	Deliveries []AlertDelivery `json:"deliveries"`
+	Count      int             `json:"count"`
 }
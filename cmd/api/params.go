@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+// dateParam validates an optional YYYY-MM-DD query parameter.
+func dateParam(name, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(time.DateOnly, value); err != nil {
+		return "", fuego.BadRequestError{Detail: name + " must be a date in YYYY-MM-DD format"}
+	}
+	return value, nil
+}
+
+// historyOptions builds the window and resolution of a price history request.
+func historyOptions(from, to, resolution string) (service.HistoryOptions, error) {
+	var opts service.HistoryOptions
+	var err error
+	if opts.From, err = timeutil.ParseTime("from", from, false); err != nil {
+		return opts, err
+	}
+	if opts.To, err = timeutil.ParseTime("to", to, true); err != nil {
+		return opts, err
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && opts.To.Before(opts.From) {
+		return opts, fmt.Errorf("to must not be before from")
+	}
+	if opts.Resolution, err = service.ValidateResolution(resolution); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// floatParam parses an optional numeric query parameter, 0 when absent.
+func floatParam(name, value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return f, nil
+}
+
+// listParam flattens a query parameter given repeatedly and/or as a comma separated list.
+func listParam(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+// regionFilter builds a continent/region filter from query parameter values.
+func regionFilter(continents, regions []string) (service.RegionFilter, error) {
+	filter := service.RegionFilter{
+		Continents: listParam(continents),
+		Regions:    listParam(regions),
+	}
+	return filter, filter.Validate()
+}
+
+// searchOptions builds the requirements of a cheapest instance search from its query parameters.
+func searchOptions(c fuego.ContextNoBody) (service.SearchOptions, error) {
+	opts := service.SearchOptions{
+		Arch:     c.QueryParam("arch"),
+		Families: listParam(c.QueryParamArr("family")),
+		Regions: service.RegionFilter{
+			Continents: listParam(c.QueryParamArr("continent")),
+			Regions:    listParam(c.QueryParamArr("regions")),
+		},
+		RankBy: c.QueryParam("rank_by"),
+		Limit:  c.QueryParamInt("limit"),
+	}
+	var err error
+	if opts.MinCPU, err = floatParam("min_cpu", c.QueryParam("min_cpu")); err != nil {
+		return opts, err
+	}
+	if opts.MinMemoryGB, err = floatParam("min_memory_gb", c.QueryParam("min_memory_gb")); err != nil {
+		return opts, err
+	}
+	if window := c.QueryParam("stability_window"); window != "" {
+		if opts.StabilityWindow, err = timeutil.ParseDuration(window); err != nil {
+			return opts, fmt.Errorf("invalid stability_window: %w", err)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// windowParam parses an optional volatility window such as 30d, defaulting to service.DefaultStatsWindow.
+func windowParam(value string) (time.Duration, error) {
+	if value == "" {
+		return service.DefaultStatsWindow, nil
+	}
+	window, err := timeutil.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window: %w", err)
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("window must be positive")
+	}
+	return window, nil
+}
+
+// forecastOptions builds the options of a spot price forecast from its query parameters.
+func forecastOptions(c fuego.ContextNoBody) (service.ForecastOptions, error) {
+	opts := service.ForecastOptions{
+		Model:      c.QueryParam("model"),
+		Confidence: c.QueryParamInt("confidence"),
+	}
+	var err error
+	if horizon := c.QueryParam("horizon"); horizon != "" {
+		if opts.Horizon, err = timeutil.ParseDuration(horizon); err != nil {
+			return opts, fmt.Errorf("invalid horizon: %w", err)
+		}
+	}
+	if history := c.QueryParam("history"); history != "" {
+		if opts.History, err = timeutil.ParseDuration(history); err != nil {
+			return opts, fmt.Errorf("invalid history: %w", err)
+		}
+	}
+	if opts.Backtest, err = c.QueryParamBoolErr("backtest"); err != nil {
+		return opts, fmt.Errorf("backtest must be true or false")
+	}
+	return opts, opts.Validate()
+}
+
+// batchOptions converts a batch price request body into service options.
+func batchOptions(req models.BatchPriceRequest) (service.BatchOptions, error) {
+	opts := service.BatchOptions{
+		Series:     make([]service.BatchSeries, len(req.Series)),
+		History:    req.History,
+		Resolution: req.Resolution,
+		Units:      req.Units,
+	}
+	for i, series := range req.Series {
+		opts.Series[i] = service.BatchSeries{RegionName: series.RegionName, MachineType: series.MachineType}
+		var err error
+		if opts.Series[i].From, err = timeutil.ParseTime("from", series.From, false); err != nil {
+			return opts, fmt.Errorf("series[%d]: %w", i, err)
+		}
+		if opts.Series[i].To, err = timeutil.ParseTime("to", series.To, true); err != nil {
+			return opts, fmt.Errorf("series[%d]: %w", i, err)
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
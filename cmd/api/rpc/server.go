@@ -0,0 +1,196 @@
+// Package rpc serves PricingService over gRPC, next to the JSON API.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pricingv1 "github.com/mgruszkiewicz/google-cloud-spot-price-history/api/pricing/v1"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+// NewServer returns a gRPC server exposing the pricing service, with server reflection
+// so tools like grpcurl can discover it. opts are passed to grpc.NewServer.
+func NewServer(pricing *service.PricingService, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	pricingv1.RegisterPricingServiceServer(s, &server{pricing: pricing})
+	reflection.Register(s)
+	return s
+}
+
+type server struct {
+	pricingv1.UnimplementedPricingServiceServer
+	pricing *service.PricingService
+}
+
+func (s *server) ListRegions(ctx context.Context, req *pricingv1.ListRegionsRequest) (*pricingv1.ListRegionsResponse, error) {
+	var filter service.RegionFilter
+	if req.GetContinent() != "" {
+		filter.Continents = []string{req.GetContinent()}
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	regions, err := s.pricing.GetAllRegions()
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	response := &pricingv1.ListRegionsResponse{}
+	for _, region := range regions {
+		if filter.Matches(region) {
+			response.Regions = append(response.Regions, &pricingv1.Region{Name: region, Continent: service.RegionContinent(region)})
+		}
+	}
+	return response, nil
+}
+
+func (s *server) ListMachines(ctx context.Context, req *pricingv1.ListMachinesRequest) (*pricingv1.ListMachinesResponse, error) {
+	if req.GetRegionName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "region_name is required")
+	}
+	opts := service.MachineListOptions{Units: req.GetUnits(), Sort: req.GetSort()}
+	if err := opts.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	machines, err := s.pricing.GetMachinesByRegion(req.GetRegionName(), opts)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	response := &pricingv1.ListMachinesResponse{Machines: make([]*pricingv1.Machine, len(machines))}
+	for i, machine := range machines {
+		response.Machines[i] = machineToProto(machine)
+	}
+	return response, nil
+}
+
+func (s *server) StreamPriceHistory(req *pricingv1.StreamPriceHistoryRequest, stream grpc.ServerStreamingServer[pricingv1.PricePoint]) error {
+	if req.GetRegionName() == "" || req.GetMachineType() == "" {
+		return status.Error(codes.InvalidArgument, "region_name and machine_type are required")
+	}
+	opts := service.HistoryOptions{Units: req.GetUnits()}
+	if req.GetFrom() != nil {
+		opts.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		opts.To = req.GetTo().AsTime()
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && opts.To.Before(opts.From) {
+		return status.Error(codes.InvalidArgument, "to must not be before from")
+	}
+	var err error
+	if opts.Resolution, err = service.ValidateResolution(req.GetResolution()); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Errors of Send are returned as they are; they already carry the status of the stream.
+	var sendErr error
+	ctx := stream.Context()
+	err = s.pricing.StreamPriceHistory(ctx, req.GetRegionName(), req.GetMachineType(), opts, func(point models.PriceHistory) error {
+		sendErr = stream.Send(&pricingv1.PricePoint{
+			Timestamp:       timestamppb.New(point.Timestamp),
+			Price:           point.Price,
+			MinPrice:        point.MinPrice,
+			MaxPrice:        point.MaxPrice,
+			OnDemandPrice:   point.OnDemandPrice,
+			SpotDiscountPct: point.SpotDiscountPct,
+			Resolution:      point.Resolution,
+			UnitPrices:      unitPricesToProto(point.UnitPrices),
+		})
+		return sendErr
+	})
+	switch {
+	case err == nil:
+		return nil
+	case sendErr != nil:
+		return sendErr
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return serviceError(err)
+}
+
+func (s *server) GetCurrentPrices(ctx context.Context, req *pricingv1.GetCurrentPricesRequest) (*pricingv1.GetCurrentPricesResponse, error) {
+	opts := service.BatchOptions{Series: make([]service.BatchSeries, len(req.GetSeries())), Units: req.GetUnits()}
+	for i, series := range req.GetSeries() {
+		opts.Series[i] = service.BatchSeries{RegionName: series.GetRegionName(), MachineType: series.GetMachineType()}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	batch, err := s.pricing.GetBatchPrices(opts)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	response := &pricingv1.GetCurrentPricesResponse{Prices: make([]*pricingv1.CurrentPrice, len(batch.Results))}
+	for i, result := range batch.Results {
+		price := &pricingv1.CurrentPrice{RegionName: result.RegionName, MachineType: result.MachineType, Found: result.Found}
+		if result.Current != nil {
+			price.Machine = machineToProto(*result.Current)
+		}
+		response.Prices[i] = price
+	}
+	return response, nil
+}
+
+// serviceError maps the typed errors of the pricing service to gRPC status codes.
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidParameter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func machineToProto(machine models.Machine) *pricingv1.Machine {
+	return &pricingv1.Machine{
+		MachineType:      machine.MachineType,
+		RegionName:       machine.RegionName,
+		HourSpotPrice:    machine.HourSpotPrice,
+		HourPrice:        machine.HourPrice,
+		SpotDiscountPct:  machine.SpotDiscountPct,
+		MinHourSpotPrice: machine.MinHourSpotPrice,
+		MaxHourSpotPrice: machine.MaxHourSpotPrice,
+		AvgHourSpotPrice: machine.AvgHourSpotPrice,
+		FirstSeen:        timestamppb.New(machine.FirstSeen),
+		LastSeen:         timestamppb.New(machine.LastSeen),
+		ChangeCount:      int64(machine.ChangeCount),
+		LastChangedAt:    optionalTimestamp(machine.LastChangedAt),
+		PreviousPrice:    machine.PreviousPrice,
+		ChangePct:        machine.ChangePct,
+		UnitPrices:       unitPricesToProto(machine.UnitPrices),
+	}
+}
+
+func unitPricesToProto(prices *models.UnitPrices) *pricingv1.UnitPrices {
+	if prices == nil {
+		return nil
+	}
+	return &pricingv1.UnitPrices{
+		SpotPerVcpu:     prices.SpotPerVCPU,
+		SpotPerGb:       prices.SpotPerGB,
+		OnDemandPerVcpu: prices.OnDemandPerVCPU,
+		OnDemandPerGb:   prices.OnDemandPerGB,
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/regions"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/webhook"
+)
+
+// Alert rules are evaluated by dataprocessing after each import against the price changes
+// it recorded; see cmd/dataprocessing/alerts.go.
+var (
+	alertConditions = []string{"above", "below", "change_pct"}
+	alertPrices     = []string{"spot", "on_demand"}
+)
+
+// MaxAlertDeliveries is the largest number of deliveries returned per request.
+const MaxAlertDeliveries = 500
+
+const alertRuleColumns = `
+	id, COALESCE(name, ''), COALESCE(machine_type, ''), COALESCE(family, ''),
+	COALESCE(region_name, ''), COALESCE(continent, ''), price, condition, threshold,
+	webhook_url, enabled, created_at, updated_at`
+
+// ValidateAlertRule checks an alert rule request and fills in its defaults.
+func ValidateAlertRule(req *models.AlertRuleRequest) error {
+	if req.Price == "" {
+		req.Price = "spot"
+	}
+	if !contains(alertPrices, req.Price) {
+		return invalidParameter("price", "price must be one of %s", strings.Join(alertPrices, ", "))
+	}
+	if !contains(alertConditions, req.Condition) {
+		return invalidParameter("condition", "condition must be one of %s", strings.Join(alertConditions, ", "))
+	}
+	if req.Threshold <= 0 {
+		return invalidParameter("threshold", "threshold must be positive")
+	}
+	if req.Continent != "" && !regions.IsContinent(req.Continent) {
+		return invalidParameter("continent", "unknown continent %q, expected one of %s", req.Continent, strings.Join(Continents(), ", "))
+	}
+	u, err := url.Parse(req.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidParameter("webhook_url", "webhook_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// CheckWebhookURL rejects webhook URLs whose host resolves to a loopback, link-local or
+// private address, which dataprocessing refuses to deliver to.
+func CheckWebhookURL(ctx context.Context, rawURL string) error {
+	if err := webhook.CheckURL(ctx, rawURL); err != nil {
+		return &InvalidParameterError{Param: "webhook_url", Message: err.Error()}
+	}
+	return nil
+}
+
+func alertRuleNotFound(id int64) error {
+	return &NotFoundError{Resource: "alert rule", Name: strconv.FormatInt(id, 10)}
+}
+
+func scanAlertRule(scan func(dest ...interface{}) error) (models.AlertRule, error) {
+	var rule models.AlertRule
+	var createdAt, updatedAt int64
+	if err := scan(
+		&rule.ID, &rule.Name, &rule.MachineType, &rule.Family, &rule.RegionName, &rule.Continent,
+		&rule.Price, &rule.Condition, &rule.Threshold, &rule.WebhookURL, &rule.Enabled,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return rule, err
+	}
+	rule.CreatedAt = time.Unix(createdAt, 0).UTC()
+	rule.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return rule, nil
+}
+
+// GetAlertRules returns all alert rules by ID. Secrets are not returned.
+func (s *PricingService) GetAlertRules() ([]models.AlertRule, error) {
+	rules := []models.AlertRule{}
+	err := s.querier.QueryRowsNamed("alert_rules", "SELECT "+alertRuleColumns+" FROM alert_rules ORDER BY id", func(rows *sql.Rows) error {
+		rule, err := scanAlertRule(rows.Scan)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetAlertRule returns an alert rule without its secret, or a NotFoundError if there is
+// none with that ID.
+func (s *PricingService) GetAlertRule(id int64) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := s.querier.QueryRowNamed("alert_rule", "SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", func(row *sql.Row) error {
+		var err error
+		rule, err = scanAlertRule(row.Scan)
+		return err
+	}, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, alertRuleNotFound(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rule %d: %w", id, err)
+	}
+	return &rule, nil
+}
+
+// CreateAlertRule stores a validated alert rule. The returned rule includes its secret,
+// which is generated when the request has none.
+func (s *PricingService) CreateAlertRule(req models.AlertRuleRequest) (*models.AlertRule, error) {
+	if req.Secret == "" {
+		secret, err := newAlertSecret()
+		if err != nil {
+			return nil, err
+		}
+		req.Secret = secret
+	}
+	now := time.Now().Unix()
+	result, err := s.querier.ExecNamed("create_alert_rule", `INSERT INTO alert_rules (
+		name, machine_type, family, region_name, continent, price, condition, threshold,
+		webhook_url, secret, enabled, created_at, updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.MachineType, req.Family, req.RegionName, req.Continent, req.Price, req.Condition, req.Threshold,
+		req.WebhookURL, req.Secret, req.Enabled == nil || *req.Enabled, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rule id: %w", err)
+	}
+	rule, err := s.GetAlertRule(id)
+	if err != nil {
+		return nil, err
+	}
+	rule.Secret = req.Secret
+	return rule, nil
+}
+
+// UpdateAlertRule replaces a validated alert rule, keeping its secret if the request has
+// none. It returns a NotFoundError if there is no rule with that ID.
+func (s *PricingService) UpdateAlertRule(id int64, req models.AlertRuleRequest) (*models.AlertRule, error) {
+	result, err := s.querier.ExecNamed("update_alert_rule", `UPDATE alert_rules SET
+		name = ?, machine_type = ?, family = ?, region_name = ?, continent = ?, price = ?,
+		condition = ?, threshold = ?, webhook_url = ?, secret = COALESCE(NULLIF(?, ''), secret),
+		enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		req.Name, req.MachineType, req.Family, req.RegionName, req.Continent, req.Price,
+		req.Condition, req.Threshold, req.WebhookURL, req.Secret,
+		req.Enabled == nil || *req.Enabled, time.Now().Unix(), id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update alert rule %d: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, alertRuleNotFound(id)
+	}
+	rule, err := s.GetAlertRule(id)
+	if err != nil {
+		return nil, err
+	}
+	rule.Secret = req.Secret
+	return rule, nil
+}
+
+// DeleteAlertRule deletes an alert rule and its delivery log. It returns a NotFoundError
+// if there is no rule with that ID.
+func (s *PricingService) DeleteAlertRule(id int64) error {
+	result, err := s.querier.ExecNamed("delete_alert_rule", "DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule %d: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return alertRuleNotFound(id)
+	}
+	if _, err := s.querier.ExecNamed("delete_alert_deliveries", "DELETE FROM alert_deliveries WHERE rule_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete deliveries of alert rule %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetAlertDeliveries returns the latest deliveries of an alert rule, newest first. It
+// returns a NotFoundError if there is no rule with that ID.
+func (s *PricingService) GetAlertDeliveries(ruleID int64, limit int) ([]models.AlertDelivery, error) {
+	if _, err := s.GetAlertRule(ruleID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			id,
+			rule_id,
+			ingestion_run_id,
+			created_at,
+			status,
+			event_count,
+			attempts,
+			last_attempt_at,
+			response_status,
+			COALESCE(error, ''),
+			COALESCE(payload, '')
+		FROM alert_deliveries
+		WHERE rule_id = ?
+		ORDER BY id DESC
+		LIMIT ?`
+
+	deliveries := []models.AlertDelivery{}
+	err := s.querier.QueryRowsNamed("alert_deliveries", query, func(rows *sql.Rows) error {
+		var d models.AlertDelivery
+		var createdAt int64
+		var lastAttemptAt, responseStatus sql.NullInt64
+		if err := rows.Scan(
+			&d.ID, &d.RuleID, &d.IngestionRunID, &createdAt, &d.Status, &d.EventCount, &d.Attempts,
+			&lastAttemptAt, &responseStatus, &d.Error, &d.Payload,
+		); err != nil {
+			return err
+		}
+		d.CreatedAt = time.Unix(createdAt, 0).UTC()
+		if lastAttemptAt.Valid {
+			t := time.Unix(lastAttemptAt.Int64, 0).UTC()
+			d.LastAttemptAt = &t
+		}
+		if responseStatus.Valid {
+			status := int(responseStatus.Int64)
+			d.ResponseStatus = &status
+		}
+		deliveries = append(deliveries, d)
+		return nil
+	}, ruleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func newAlertSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate alert secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// API keys are created and revoked with `dataprocessing apikey`; the API only looks them
+// up by hash and counts their requests.
+
+// APIKey is an active API key and its limits. A RateLimit or DailyQuota of 0 is unlimited.
+type APIKey struct {
+	ID         int64
+	Name       string
+	RateLimit  float64
+	Burst      int
+	DailyQuota int64
+	// Admin keys may also manage alert rules.
+	Admin bool
+}
+
+// GetAPIKey returns the unrevoked key with the given hash, or nil if there is none.
+func (s *PricingService) GetAPIKey(hash string) (*APIKey, error) {
+	var key APIKey
+	err := s.querier.QueryRowNamed("api_key", `
+		SELECT id, name, rate_limit, burst, daily_quota, admin
+		FROM api_keys
+		WHERE key_hash = ? AND revoked_at IS NULL`, func(row *sql.Row) error {
+		return row.Scan(&key.ID, &key.Name, &key.RateLimit, &key.Burst, &key.DailyQuota, &key.Admin)
+	}, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API key: %w", err)
+	}
+	return &key, nil
+}
+
+// RecordAPIKeyRequest counts a request of a key on the UTC day of now unless the key has
+// used its daily quota. It returns the requests counted that day and whether this one was.
+func (s *PricingService) RecordAPIKeyRequest(key *APIKey, now time.Time) (int64, bool, error) {
+	quota := key.DailyQuota
+	if quota == 0 {
+		quota = math.MaxInt64
+	}
+	day := now.UTC().Format(time.DateOnly)
+
+	var requests int64
+	err := s.querier.QueryRowNamed("record_api_key_request", `
+		INSERT INTO api_key_usage (key_id, day, requests, last_request_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = requests + 1, last_request_at = excluded.last_request_at
+		WHERE requests < ?
+		RETURNING requests`, func(row *sql.Row) error {
+		return row.Scan(&requests)
+	}, key.ID, day, now.Unix(), quota)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key.DailyQuota, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	return requests, true, nil
+}
+
+// RecordAPIKeyRejection counts a request of a key that was refused for exceeding its rate
+// limit or daily quota.
+func (s *PricingService) RecordAPIKeyRejection(key *APIKey, now time.Time) error {
+	_, err := s.querier.ExecNamed("record_api_key_rejection", `
+		INSERT INTO api_key_usage (key_id, day, rejected, last_request_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (key_id, day) DO UPDATE SET rejected = rejected + 1, last_request_at = excluded.last_request_at`,
+		key.ID, now.UTC().Format(time.DateOnly), now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record rejected API key request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// MaxBatchSeries limits the number of series in one batch price query.
+const MaxBatchSeries = 500
+
+// BatchSeries is a machine type in a region requested in a batch query.
+type BatchSeries struct {
+	RegionName  string
+	MachineType string
+	// From and To bound the history window (inclusive); zero values leave it open.
+	From time.Time
+	To   time.Time
+}
+
+// BatchOptions selects the series of a batch price query and what to return for them.
+type BatchOptions struct {
+	Series []BatchSeries
+	// History adds the price history of every series within its window.
+	History bool
+	// Resolution is raw (default), day, week or month.
+	Resolution string
+	// Units adds prices per vCPU and per GB of memory.
+	Units bool
+}
+
+// Validate checks the requested series and fills in defaults.
+func (o *BatchOptions) Validate() error {
+	if len(o.Series) == 0 {
+		return invalidParameter("series", "series must not be empty")
+	}
+	if len(o.Series) > MaxBatchSeries {
+		return invalidParameter("series", "at most %d series can be requested at once", MaxBatchSeries)
+	}
+	for i, series := range o.Series {
+		if series.RegionName == "" || series.MachineType == "" {
+			return invalidParameter("series", "series[%d]: region_name and machine_type are required", i)
+		}
+		if !series.From.IsZero() && !series.To.IsZero() && series.To.Before(series.From) {
+			return invalidParameter("series", "series[%d]: to must not be before from", i)
+		}
+	}
+	resolution, err := ValidateResolution(o.Resolution)
+	if err != nil {
+		return err
+	}
+	o.Resolution = resolution
+	return nil
+}
+
+// batchWindow is a requested series together with its history window in Unix seconds.
+type batchWindow struct {
+	series seriesID
+	fromTS int64
+	toTS   int64
+}
+
+// batchWindows deduplicates the requested series and windows. index maps every requested
+// series to its window.
+func batchWindows(requested []BatchSeries) (series []seriesID, windows []batchWindow, index []int) {
+	seenSeries := map[seriesID]bool{}
+	seenWindows := map[batchWindow]int{}
+	index = make([]int, len(requested))
+	for i, r := range requested {
+		id := seriesID{r.RegionName, r.MachineType}
+		if !seenSeries[id] {
+			seenSeries[id] = true
+			series = append(series, id)
+		}
+
+		window := batchWindow{series: id, fromTS: 0, toTS: math.MaxInt64}
+		if !r.From.IsZero() {
+			window.fromTS = r.From.Unix()
+		}
+		if !r.To.IsZero() {
+			window.toTS = r.To.Unix()
+		}
+		idx, ok := seenWindows[window]
+		if !ok {
+			idx = len(windows)
+			seenWindows[window] = idx
+			windows = append(windows, window)
+		}
+		index[i] = idx
+	}
+	return series, windows, index
+}
+
+// requestedWindows returns a CTE named requested(idx, region_name, machine_type, from_ts, to_ts)
+// listing the windows, idx being their position, and its arguments.
+func requestedWindows(windows []batchWindow) (string, []interface{}) {
+	values := make([]string, len(windows))
+	args := make([]interface{}, 0, 5*len(windows))
+	for i, window := range windows {
+		values[i] = "(?, ?, ?, ?, ?)"
+		args = append(args, i, window.series.regionName, window.series.machineType, window.fromTS, window.toTS)
+	}
+	return "WITH requested(idx, region_name, machine_type, from_ts, to_ts) AS (VALUES " + strings.Join(values, ", ") + ")", args
+}
+
+// GetBatchPrices returns the current prices, and optionally the price histories, of many
+// machine types and regions. Results follow the order of opts.Series. Instead of one query
+// per series, the distinct series are joined against price_summary and pricing_history as
+// a VALUES list, so the whole batch takes at most three queries.
+func (s *PricingService) GetBatchPrices(opts BatchOptions) (*models.BatchPriceResponse, error) {
+	series, windows, windowIndex := batchWindows(opts.Series)
+
+	current, err := s.batchCurrentPrices(series)
+	if err != nil {
+		return nil, err
+	}
+
+	var histories [][]models.PriceHistory
+	if opts.History {
+		if histories, err = s.batchHistories(windows); err != nil {
+			return nil, err
+		}
+	}
+
+	var specs map[string]machineSpec
+	if opts.Units {
+		machineTypes := make([]string, 0, len(current))
+		for _, machine := range current {
+			machineTypes = append(machineTypes, machine.MachineType)
+		}
+		if len(machineTypes) > 0 {
+			if specs, err = s.machineSpecs(machineTypes...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	response := &models.BatchPriceResponse{Results: make([]models.BatchPriceResult, len(opts.Series))}
+	for i, requested := range opts.Series {
+		result := models.BatchPriceResult{
+			RegionName:  requested.RegionName,
+			MachineType: requested.MachineType,
+		}
+		if machine, ok := current[seriesID{requested.RegionName, requested.MachineType}]; ok {
+			result.Found = true
+			spec, hasSpec := specs[machine.MachineType]
+			if hasSpec {
+				machine.UnitPrices = spec.unitPrices(machine.HourSpotPrice, machine.HourPrice)
+			}
+			result.Current = &machine
+
+			if opts.History {
+				// Requests for the same window share the raw history; each result gets
+				// its own copy before unit prices are set on it.
+				history := downsampleHistory(histories[windowIndex[i]], opts.Resolution)
+				result.History = make([]models.PriceHistory, len(history))
+				copy(result.History, history)
+				if hasSpec {
+					for j, point := range result.History {
+						result.History[j].UnitPrices = spec.unitPrices(point.Price, point.OnDemandPrice)
+					}
+				}
+			}
+		}
+		response.Results[i] = result
+	}
+	response.Count = len(response.Results)
+	return response, nil
+}
+
+// batchCurrentPrices looks up the price_summary rows of the given series.
+func (s *PricingService) batchCurrentPrices(series []seriesID) (map[seriesID]models.Machine, error) {
+	values := make([]string, len(series))
+	args := make([]interface{}, 0, 2*len(series))
+	for i, id := range series {
+		values[i] = "(?, ?)"
+		args = append(args, id.regionName, id.machineType)
+	}
+
+	query := `
+		WITH requested(region_name, machine_type) AS (VALUES ` + strings.Join(values, ", ") + `)
+		SELECT
+			ps.machine_type,
+			ps.min_spot_hour_price,
+			ps.max_spot_hour_price,
+			ps.avg_spot_hour_price,
+			ps.current_spot_hour_price,
+			ps.current_hour_price,
+			ps.first_seen_ts,
+			ps.last_seen_ts,
+			ps.change_count,
+			ps.last_change_ts,
+			ps.previous_spot_hour_price,
+			ps.region_name
+		FROM requested
+		JOIN price_summary ps ON ps.region_name = requested.region_name AND ps.machine_type = requested.machine_type`
+
+	current := map[seriesID]models.Machine{}
+	err := s.querier.QueryRowsNamed("batch_current_prices", query, func(rows *sql.Rows) error {
+		var machine models.Machine
+		if err := scanMachine(rows, &machine, &machine.RegionName); err != nil {
+			return err
+		}
+		current[seriesID{machine.RegionName, machine.MachineType}] = machine
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current prices: %w", err)
+	}
+	return current, nil
+}
+
+// batchHistories returns the raw price history of every window, indexed like windows.
+func (s *PricingService) batchHistories(windows []batchWindow) ([][]models.PriceHistory, error) {
+	requested, args := requestedWindows(windows)
+	query := requested + `
+		SELECT
+			h.spot_hour_price,
+			COALESCE(h.min_spot_hour_price, h.spot_hour_price),
+			COALESCE(h.max_spot_hour_price, h.spot_hour_price),
+			h.hour_price,
+			h.updated_ts,
+			h.resolution,
+			requested.idx
+		FROM requested
+		JOIN pricing_history h ON h.machine_type = requested.machine_type AND h.region_name = requested.region_name
+		WHERE h.updated_ts >= requested.from_ts AND h.updated_ts <= requested.to_ts
+		ORDER BY requested.idx, h.updated_ts ASC`
+
+	histories := make([][]models.PriceHistory, len(windows))
+	err := s.querier.QueryRowsNamed("batch_histories", query, func(rows *sql.Rows) error {
+		var idx int
+		point, err := scanPriceHistory(rows, &idx)
+		if err != nil {
+			return err
+		}
+		histories[idx] = append(histories[idx], point)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price histories: %w", err)
+	}
+	return histories, nil
+}
+
+// GetBatchChanges returns the latest price changes, newest first and at most limit each, of
+// many machine types and regions within their windows, indexed like series.
+func (s *PricingService) GetBatchChanges(series []BatchSeries, limit int) ([][]models.PriceChange, error) {
+	_, windows, windowIndex := batchWindows(series)
+	requested, args := requestedWindows(windows)
+	query := requested + `
+		SELECT 
+			id, 
+			machine_type, 
+			family, 
+			region_name, 
+			updated_ts, 
+			old_spot_hour_price, 
+			new_spot_hour_price, 
+			old_hour_price, 
+			new_hour_price, 
+			idx 
+		FROM (
+			SELECT c.*, requested.idx,
+				ROW_NUMBER() OVER (PARTITION BY requested.idx ORDER BY c.updated_ts DESC, c.id DESC) AS rn
+			FROM requested
+			JOIN price_changes c ON c.region_name = requested.region_name AND c.machine_type = requested.machine_type
+			WHERE c.updated_ts >= requested.from_ts AND c.updated_ts <= requested.to_ts
+		)
+		WHERE rn <= ?
+		ORDER BY idx, updated_ts DESC, id DESC`
+	args = append(args, limit)
+
+	changes := make([][]models.PriceChange, len(windows))
+	err := s.querier.QueryRowsNamed("batch_changes", query, func(rows *sql.Rows) error {
+		var idx int
+		change, err := scanPriceChange(rows, &idx)
+		if err != nil {
+			return err
+		}
+		changes[idx] = append(changes[idx], change)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price changes: %w", err)
+	}
+
+	result := make([][]models.PriceChange, len(series))
+	for i := range series {
+		result[i] = changes[windowIndex[i]]
+	}
+	return result, nil
+}
+
+// GetMachinesByRegions returns the current prices of all machine types in each of the
+// given regions, in the order of GetMachinesByRegion, with one query.
+func (s *PricingService) GetMachinesByRegions(regionNames []string) (map[string][]models.Machine, error) {
+	query := `
+		SELECT 
+			machine_type, 
+			min_spot_hour_price, 
+			max_spot_hour_price, 
+			avg_spot_hour_price, 
+			current_spot_hour_price, 
+			current_hour_price, 
+			first_seen_ts, 
+			last_seen_ts, 
+			change_count, 
+			last_change_ts, 
+			previous_spot_hour_price, 
+			region_name 
+		FROM price_summary 
+		WHERE region_name IN (` + placeholders(len(regionNames)) + `) 
+		ORDER BY region_name, machine_type DESC`
+	args := make([]interface{}, len(regionNames))
+	for i, regionName := range regionNames {
+		args[i] = regionName
+	}
+
+	machines := map[string][]models.Machine{}
+	err := s.querier.QueryRowsNamed("machines_by_regions", query, func(rows *sql.Rows) error {
+		var machine models.Machine
+		if err := scanMachine(rows, &machine, &machine.RegionName); err != nil {
+			return err
+		}
+		machines[machine.RegionName] = append(machines[machine.RegionName], machine)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query machines: %w", err)
+	}
+	return machines, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// ChangeFilter selects price change events. Zero values match everything.
+type ChangeFilter struct {
+	RegionName  string
+	Family      string
+	MachineType string
+	From        time.Time
+	To          time.Time
+	// Direction is "up" or "down" to only return spot price increases or decreases.
+	Direction string
+	// MinChangePct is the smallest absolute spot price change, in percent, to return.
+	MinChangePct float64
+	Limit        int
+	// Cursor continues after the last change of a previous page.
+	Cursor string
+}
+
+// Validate checks the filter.
+func (f ChangeFilter) Validate() error {
+	if f.Direction != "" && f.Direction != "up" && f.Direction != "down" {
+		return invalidParameter("direction", "direction must be up or down")
+	}
+	if f.MinChangePct < 0 {
+		return invalidParameter("min_change_pct", "min_change_pct must not be negative")
+	}
+	if f.Limit <= 0 || f.Limit > 500 {
+		return invalidParameter("limit", "limit must be between 1 and 500")
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return invalidParameter("to", "to must not be before from")
+	}
+	if _, _, err := parseChangeCursor(f.Cursor); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Change cursors are "<updated_ts>:<id>" of the last change of a page.
+func parseChangeCursor(cursor string) (int64, int64, error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+	tsPart, idPart, ok := strings.Cut(cursor, ":")
+	ts, tsErr := strconv.ParseInt(tsPart, 10, 64)
+	id, idErr := strconv.ParseInt(idPart, 10, 64)
+	if !ok || tsErr != nil || idErr != nil {
+		return 0, 0, invalidParameter("cursor", "invalid cursor %q", cursor)
+	}
+	return ts, id, nil
+}
+
+// GetPriceChanges returns price change events matching the filter, newest first.
+// Events are recorded by dataprocessing in price_changes.
+func (s *PricingService) GetPriceChanges(filter ChangeFilter) (*models.PriceChangeListResponse, error) {
+	query := `
+		SELECT 
+			id, 
+			machine_type, 
+			family, 
+			region_name, 
+			updated_ts, 
+			old_spot_hour_price, 
+			new_spot_hour_price, 
+			old_hour_price, 
+			new_hour_price 
+		FROM price_changes 
+		WHERE 1 = 1`
+	var args []interface{}
+	query, args = filter.appendConditions(query, args)
+	query += " ORDER BY updated_ts DESC, id DESC LIMIT ?"
+	// One extra row tells whether there is a next page.
+	args = append(args, filter.Limit+1)
+
+	result := &models.PriceChangeListResponse{Changes: []models.PriceChange{}}
+	err := s.querier.QueryRowsNamed("price_changes", query, func(rows *sql.Rows) error {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return err
+		}
+		result.Changes = append(result.Changes, change)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price changes: %w", err)
+	}
+
+	if len(result.Changes) > filter.Limit {
+		result.Changes = result.Changes[:filter.Limit]
+		last := result.Changes[len(result.Changes)-1]
+		result.NextCursor = fmt.Sprintf("%d:%d", last.Timestamp.Unix(), last.ID)
+	}
+	result.Count = len(result.Changes)
+	return result, nil
+}
+
+// scanPriceChange scans the price_changes columns selected by GetPriceChanges followed by
+// any extra columns into extra.
+func scanPriceChange(rows *sql.Rows, extra ...interface{}) (models.PriceChange, error) {
+	var change models.PriceChange
+	var ts int64
+	dest := []interface{}{
+		&change.ID,
+		&change.MachineType,
+		&change.Family,
+		&change.RegionName,
+		&ts,
+		&change.OldHourSpotPrice,
+		&change.NewHourSpotPrice,
+		&change.OldHourPrice,
+		&change.NewHourPrice,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return change, fmt.Errorf("failed to scan price change: %w", err)
+	}
+	change.Timestamp = time.Unix(ts, 0).UTC()
+	change.SpotChange, change.SpotChangePct = priceDelta(change.OldHourSpotPrice, change.NewHourSpotPrice)
+	change.OnDemandChange, change.OnDemandChangePct = priceDelta(change.OldHourPrice, change.NewHourPrice)
+	return change, nil
+}
+
+// appendConditions adds the filter's conditions to a price_changes query with a WHERE clause.
+func (f ChangeFilter) appendConditions(query string, args []interface{}) (string, []interface{}) {
+	if f.RegionName != "" {
+		query += " AND region_name = ?"
+		args = append(args, f.RegionName)
+	}
+	if f.Family != "" {
+		query += " AND family = ?"
+		args = append(args, f.Family)
+	}
+	if f.MachineType != "" {
+		query += " AND machine_type = ?"
+		args = append(args, f.MachineType)
+	}
+	if !f.From.IsZero() {
+		query += " AND updated_ts >= ?"
+		args = append(args, f.From.Unix())
+	}
+	if !f.To.IsZero() {
+		query += " AND updated_ts <= ?"
+		args = append(args, f.To.Unix())
+	}
+	switch f.Direction {
+	case "up":
+		query += " AND new_spot_hour_price > old_spot_hour_price"
+	case "down":
+		query += " AND new_spot_hour_price < old_spot_hour_price"
+	}
+	if f.MinChangePct > 0 {
+		query += " AND ABS(new_spot_hour_price - old_spot_hour_price) * 100 >= ? * old_spot_hour_price"
+		args = append(args, f.MinChangePct)
+	}
+	if ts, id, _ := parseChangeCursor(f.Cursor); f.Cursor != "" {
+		query += " AND (updated_ts < ? OR (updated_ts = ? AND id < ?))"
+		args = append(args, ts, ts, id)
+	}
+	return query, args
+}
+
+// priceDelta returns the absolute and relative (percent, two decimals) change from old to new.
+func priceDelta(oldPrice, newPrice float64) (float64, float64) {
+	delta := math.Round((newPrice-oldPrice)*1e6) / 1e6
+	if oldPrice == 0 {
+		return delta, 0
+	}
+	return delta, math.Round((newPrice-oldPrice)/oldPrice*10000) / 100
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/coverage"
+)
+
+// maxCoverageReports bounds the reports kept for one data version; every combination of
+// threshold and filters is a separate report.
+const maxCoverageReports = 64
+
+// coverageCache keeps coverage reports until the data version changes, since the analysis
+// reads every raw observation.
+type coverageCache struct {
+	mu      sync.Mutex
+	etag    string
+	reports map[coverage.Options]*coverage.Report
+}
+
+func (c *coverageCache) get(etag string, opts coverage.Options) (*coverage.Report, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.etag != etag {
+		return nil, false
+	}
+	report, ok := c.reports[opts]
+	return report, ok
+}
+
+func (c *coverageCache) put(etag string, opts coverage.Options, report *coverage.Report) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.etag != etag || len(c.reports) >= maxCoverageReports {
+		c.etag = etag
+		c.reports = map[coverage.Options]*coverage.Report{}
+	}
+	c.reports[opts] = report
+}
+
+// GetCoverage analyzes snapshot coverage over time and per region/machine series. Reports
+// are reused until the next import or compaction. Callers must not modify them.
+func (s *PricingService) GetCoverage(opts coverage.Options) (*coverage.Report, error) {
+	if opts.GapThreshold <= 0 {
+		opts.GapThreshold = coverage.DefaultGapThreshold
+	}
+	version, known := s.CurrentDataVersion()
+	if known {
+		if report, ok := s.coverage.get(version.ETag(), opts); ok {
+			return report, nil
+		}
+	}
+
+	report, err := coverage.Analyze(s.querier, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze coverage: %w", err)
+	}
+	// An import that finished during the analysis may be part of it.
+	if current, ok := s.CurrentDataVersion(); known && ok && current.ETag() == version.ETag() {
+		s.coverage.put(version.ETag(), opts, report)
+	}
+	return report, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/coverage"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+func TestGetCoverageReusesReports(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE pricing_history (machine_type varchar(64), region_name varchar(64), updated_ts INTEGER, resolution varchar(8) NOT NULL DEFAULT 'raw')",
+		"CREATE TABLE ingestion_runs (id INTEGER PRIMARY KEY, started_at INTEGER, finished_at INTEGER)",
+		"CREATE TABLE compaction_log (id INTEGER PRIMARY KEY, compacted_at INTEGER)",
+		"INSERT INTO pricing_history (machine_type, region_name, updated_ts) VALUES ('m1', 'r1', 0), ('m1', 'r1', 86400)",
+		"INSERT INTO ingestion_runs VALUES (1, 86400, 86500)",
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	querier := db.NewQuerier(sqlDB)
+	var analyses int
+	querier.SetObserver(func(name, operation string, duration time.Duration, err error) {
+		if name == "coverage_observations" {
+			analyses++
+		}
+	})
+	pricing := NewPricingService(querier)
+
+	getCoverage := func(opts coverage.Options) *coverage.Report {
+		t.Helper()
+		report, err := pricing.GetCoverage(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	first := getCoverage(coverage.Options{})
+	// The default threshold spelled out is the same report.
+	if again := getCoverage(coverage.Options{GapThreshold: coverage.DefaultGapThreshold}); again != first || analyses != 1 {
+		t.Errorf("second request analyzed again: %d analyses", analyses)
+	}
+	getCoverage(coverage.Options{RegionName: "r1"})
+	if analyses != 2 {
+		t.Errorf("filtered request: %d analyses, want 2", analyses)
+	}
+
+	// An import changes the data version and drops the reports.
+	if _, err := sqlDB.Exec("INSERT INTO ingestion_runs VALUES (2, 172800, 172900)"); err != nil {
+		t.Fatal(err)
+	}
+	if getCoverage(coverage.Options{}) == first || analyses != 3 {
+		t.Errorf("after an import: %d analyses, want 3", analyses)
+	}
+}
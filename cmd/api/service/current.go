@@ -0,0 +1,62 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CurrentPrice is the latest price of a machine type in a region.
+type CurrentPrice struct {
+	MachineType   string
+	Family        string
+	RegionName    string
+	HourSpotPrice float64
+	HourPrice     float64
+}
+
+// GetCurrentPrices returns the prices of every series in the latest snapshot and the time
+// of that snapshot, zero if there is none.
+func (s *PricingService) GetCurrentPrices() ([]CurrentPrice, time.Time, error) {
+	var latestTS sql.NullInt64
+	err := s.querier.QueryRowNamed("current_prices_latest", "SELECT MAX(last_seen_ts) FROM price_summary", func(row *sql.Row) error {
+		return row.Scan(&latestTS)
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to query latest snapshot: %w", err)
+	}
+	if !latestTS.Valid {
+		return nil, time.Time{}, nil
+	}
+
+	query := `
+		SELECT
+			machine_type,
+			region_name,
+			current_spot_hour_price,
+			current_hour_price
+		FROM price_summary
+		WHERE last_seen_ts = ?`
+
+	var prices []CurrentPrice
+	err = s.querier.QueryRowsNamed("current_prices", query, func(rows *sql.Rows) error {
+		var p CurrentPrice
+		if err := rows.Scan(&p.MachineType, &p.RegionName, &p.HourSpotPrice, &p.HourPrice); err != nil {
+			return err
+		}
+		p.Family = machineFamily(p.MachineType)
+		prices = append(prices, p)
+		return nil
+	}, latestTS.Int64)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to query current prices: %w", err)
+	}
+	return prices, time.Unix(latestTS.Int64, 0).UTC(), nil
+}
+
+// machineFamily derives the family of a machine type from its name, the way dataprocessing
+// does for price changes.
+func machineFamily(machineType string) string {
+	return strings.Split(machineType, "-")[0]
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// GetDailyPrices returns the materialized daily price series of a machine type in a region.
+// from and to are inclusive YYYY-MM-DD dates; empty values leave the range open.
+func (s *PricingService) GetDailyPrices(regionName, machineType, from, to string) (*models.DailyPriceSeries, error) {
+	if err := s.checkSeries(regionName, machineType); err != nil {
+		return nil, err
+	}
+
+	result := &models.DailyPriceSeries{
+		MachineType: machineType,
+		RegionName:  regionName,
+		From:        from,
+		To:          to,
+		Prices:      []models.DailyPrice{},
+	}
+
+	query := `
+		SELECT day, hour_price, spot_hour_price, observed_ts, carried_forward 
+		FROM daily_prices 
+		WHERE region_name = ? AND machine_type = ?`
+	args := []interface{}{regionName, machineType}
+	if from != "" {
+		query += " AND day >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND day <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY day ASC"
+
+	err := s.querier.QueryRowsNamed("daily_prices", query, func(rows *sql.Rows) error {
+		var price models.DailyPrice
+		var observedTS int64
+		if err := rows.Scan(&price.Date, &price.HourPrice, &price.HourSpotPrice, &observedTS, &price.CarriedForward); err != nil {
+			return fmt.Errorf("failed to scan daily price: %w", err)
+		}
+		price.ObservedAt = time.Unix(observedTS, 0).UTC()
+		result.Prices = append(result.Prices, price)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily prices: %w", err)
+	}
+
+	result.Count = len(result.Prices)
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+// Kinds of errors returned by PricingService, tested with errors.Is. The API maps them,
+// and ErrInsufficientHistory, to 404, 400 and 503 responses; other errors are internal.
+var (
+	// ErrNotFound is matched by NotFoundError.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidParameter is matched by InvalidParameterError.
+	ErrInvalidParameter = errors.New("invalid parameter")
+	// ErrUnavailable is wrapped by errors of queries that failed because the database
+	// cannot be used at the moment or has not been imported into.
+	ErrUnavailable = db.ErrUnavailable
+)
+
+// NotFoundError reports a region, machine type or alert rule that does not exist. Methods
+// reading one series or region return it when price_summary has no prices for it.
+type NotFoundError struct {
+	// Resource is the kind of thing that was looked up, e.g. "region".
+	Resource string
+	Name     string
+	// Region is set for machine types that exist, but not in this region.
+	Region string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Region != "" {
+		return fmt.Sprintf("%s %q not found in region %q", e.Resource, e.Name, e.Region)
+	}
+	return fmt.Sprintf("%s %q not found", e.Resource, e.Name)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// InvalidParameterError reports a request parameter that is malformed or out of range.
+type InvalidParameterError struct {
+	// Param is the name of the parameter, or empty if the error concerns several.
+	Param   string
+	Message string
+}
+
+func (e *InvalidParameterError) Error() string {
+	return e.Message
+}
+
+func (e *InvalidParameterError) Is(target error) bool {
+	return target == ErrInvalidParameter
+}
+
+// invalidParameter returns an InvalidParameterError with a formatted message.
+func invalidParameter(param, format string, args ...interface{}) error {
+	return &InvalidParameterError{Param: param, Message: fmt.Sprintf(format, args...)}
+}
+
+// checkSeries returns a NotFoundError unless the region, and the machine type in it, have
+// prices. Either may be empty to only check the other.
+func (s *PricingService) checkSeries(regionName, machineType string) error {
+	var regionFound, machineFound, seriesFound bool
+	err := s.querier.QueryRowNamed("check_series", `
+		SELECT
+			? = '' OR EXISTS (SELECT 1 FROM price_summary WHERE region_name = ?),
+			? = '' OR EXISTS (SELECT 1 FROM price_summary WHERE machine_type = ?),
+			EXISTS (SELECT 1 FROM price_summary WHERE (? = '' OR region_name = ?) AND (? = '' OR machine_type = ?))`,
+		func(row *sql.Row) error {
+			return row.Scan(&regionFound, &machineFound, &seriesFound)
+		}, regionName, regionName, machineType, machineType, regionName, regionName, machineType, machineType)
+	if err != nil {
+		return fmt.Errorf("failed to look up series: %w", err)
+	}
+	switch {
+	case !regionFound:
+		return &NotFoundError{Resource: "region", Name: regionName}
+	case !machineFound:
+		return &NotFoundError{Resource: "machine type", Name: machineType}
+	case !seriesFound:
+		return &NotFoundError{Resource: "machine type", Name: machineType, Region: regionName}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// feedPageSize is the number of price changes read per query when catching up.
+const feedPageSize = 500
+
+// subscriberBuffer is the number of events a subscriber may lag behind before it is
+// dropped; it can resume from its last event ID.
+const subscriberBuffer = 1024
+
+// FeedEvent is a new snapshot or a price change detected by a ChangeFeed.
+type FeedEvent struct {
+	// Change is set for price change events.
+	Change *models.PriceChange
+	// SnapshotAt is set for new snapshot events.
+	SnapshotAt time.Time
+}
+
+// StreamFilter selects the events of a change stream. Zero values match everything.
+type StreamFilter struct {
+	Regions  []string
+	Families []string
+	// MinChangePct is the smallest absolute spot price change, in percent, to return.
+	MinChangePct float64
+}
+
+// Validate checks the filter.
+func (f StreamFilter) Validate() error {
+	if f.MinChangePct < 0 {
+		return invalidParameter("min_change_pct", "min_change_pct must not be negative")
+	}
+	return nil
+}
+
+// Matches reports whether a price change passes the filter.
+func (f StreamFilter) Matches(change models.PriceChange) bool {
+	if len(f.Regions) > 0 && !contains(f.Regions, change.RegionName) {
+		return false
+	}
+	if len(f.Families) > 0 && !contains(f.Families, change.Family) {
+		return false
+	}
+	if f.MinChangePct > 0 {
+		pct := change.SpotChangePct
+		if pct < 0 {
+			pct = -pct
+		}
+		if pct < f.MinChangePct {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeFeed polls the database for new snapshots and price changes and broadcasts them
+// to its subscribers. dataprocessing writes to the database from another process, so
+// polling the newest updated_ts and price_changes ID is how the API learns about imports.
+type ChangeFeed struct {
+	pricing  *PricingService
+	interval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan FeedEvent]struct{}
+	lastTS      int64
+	lastID      int64
+}
+
+// NewChangeFeed returns a feed polling every interval. Call Run to start it.
+func NewChangeFeed(pricing *PricingService, interval time.Duration) *ChangeFeed {
+	return &ChangeFeed{
+		pricing:     pricing,
+		interval:    interval,
+		subscribers: map[chan FeedEvent]struct{}{},
+	}
+}
+
+// Run polls until ctx is done. Snapshots and changes present when it starts are not
+// broadcast.
+func (f *ChangeFeed) Run(ctx context.Context) {
+	var err error
+	if f.lastTS, f.lastID, err = f.pricing.latestChange(); err != nil {
+		slog.Error("failed to read change feed position", "error", err)
+	}
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.poll(); err != nil {
+				slog.Error("failed to poll price changes", "error", err)
+			}
+		}
+	}
+}
+
+// poll broadcasts snapshots newer than the last one seen and changes recorded since.
+// price_changes is written after the snapshot rows, so the two are tracked separately.
+// Change IDs are never reused, and rows are only rewritten in place when dataprocessing
+// replays a series, so a change is broadcast once, when it is first recorded.
+func (f *ChangeFeed) poll() error {
+	ts, id, err := f.pricing.latestChange()
+	if err != nil {
+		return err
+	}
+	if id < f.lastID {
+		// The newest changes were removed by a replay, or the database was replaced
+		f.lastID = id
+	}
+	if ts > f.lastTS {
+		f.broadcast(FeedEvent{SnapshotAt: time.Unix(ts, 0).UTC()})
+		f.lastTS = ts
+	}
+	for f.lastID < id {
+		changes, err := f.pricing.GetChangesSince(f.lastID, feedPageSize)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			break
+		}
+		for i := range changes {
+			f.broadcast(FeedEvent{Change: &changes[i]})
+		}
+		f.lastID = changes[len(changes)-1].ID
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber. The channel is closed when the subscriber falls too
+// far behind or cancel is called.
+func (f *ChangeFeed) Subscribe() (<-chan FeedEvent, func()) {
+	ch := make(chan FeedEvent, subscriberBuffer)
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() { f.unsubscribe(ch) }
+}
+
+func (f *ChangeFeed) unsubscribe(ch chan FeedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (f *ChangeFeed) broadcast(event FeedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// latestChange returns the newest snapshot timestamp and price change ID, 0 if there are none.
+func (s *PricingService) latestChange() (int64, int64, error) {
+	var ts, id int64
+	err := s.querier.QueryRowNamed("latest_change", `
+		SELECT
+			(SELECT COALESCE(MAX(updated_ts), 0) FROM pricing_history),
+			(SELECT COALESCE(MAX(id), 0) FROM price_changes)`, func(row *sql.Row) error {
+		return row.Scan(&ts, &id)
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query latest change: %w", err)
+	}
+	return ts, id, nil
+}
+
+// GetChangesSince returns up to limit price changes recorded after the change with ID
+// afterID, in the order they were recorded.
+func (s *PricingService) GetChangesSince(afterID int64, limit int) ([]models.PriceChange, error) {
+	query := `
+		SELECT
+			id,
+			machine_type,
+			family,
+			region_name,
+			updated_ts,
+			old_spot_hour_price,
+			new_spot_hour_price,
+			old_hour_price,
+			new_hour_price
+		FROM price_changes
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?`
+
+	var changes []models.PriceChange
+	err := s.querier.QueryRowsNamed("changes_since", query, func(rows *sql.Rows) error {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+		return nil
+	}, afterID, limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price changes: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/forecast"
+)
+
+// ErrInsufficientHistory is returned when a series is too short to forecast or backtest.
+var ErrInsufficientHistory = errors.New("not enough history")
+
+// z-scores of the supported prediction interval confidence levels.
+var confidenceZ = map[int]float64{
+	80: 1.2816,
+	90: 1.6449,
+	95: 1.96,
+	99: 2.5758,
+}
+
+// ForecastOptions controls a spot price forecast.
+type ForecastOptions struct {
+	// Model is holt (default) or linear.
+	Model string
+	// Horizon is how far ahead to forecast, rounded up to whole days.
+	Horizon time.Duration
+	// History is how much of the daily series, ending at its last day, to fit on.
+	History time.Duration
+	// Confidence is the prediction interval level in percent: 80, 90, 95 (default) or 99.
+	Confidence int
+	// Backtest additionally forecasts the last Horizon of the history from the days before it.
+	Backtest bool
+}
+
+// Validate checks the options and fills in defaults.
+func (o *ForecastOptions) Validate() error {
+	if o.Model == "" {
+		o.Model = forecast.ModelHolt
+	}
+	if o.Model != forecast.ModelHolt && o.Model != forecast.ModelLinear {
+		return invalidParameter("model", "model must be %s or %s", forecast.ModelHolt, forecast.ModelLinear)
+	}
+	if o.Horizon == 0 {
+		o.Horizon = 30 * 24 * time.Hour
+	}
+	if o.Horizon < 0 || o.Horizon > 365*24*time.Hour {
+		return invalidParameter("horizon", "horizon must be between 1d and 365d")
+	}
+	if o.History == 0 {
+		o.History = 180 * 24 * time.Hour
+	}
+	if o.History < forecast.MinTrainingPoints*24*time.Hour {
+		return invalidParameter("history", "history must cover at least %d days", forecast.MinTrainingPoints)
+	}
+	if o.Confidence == 0 {
+		o.Confidence = 95
+	}
+	if _, ok := confidenceZ[o.Confidence]; !ok {
+		return invalidParameter("confidence", "confidence must be 80, 90, 95 or 99")
+	}
+	return nil
+}
+
+// GetSpotPriceForecast projects the daily spot price of a machine type in a region beyond
+// its last observed day.
+func (s *PricingService) GetSpotPriceForecast(regionName, machineType string, opts ForecastOptions) (*models.SpotPriceForecast, error) {
+	if err := s.checkSeries(regionName, machineType); err != nil {
+		return nil, err
+	}
+
+	horizonDays := days(opts.Horizon)
+	historyDays := days(opts.History)
+
+	// The latest days of the series, newest first.
+	query := `
+		SELECT day, spot_hour_price 
+		FROM daily_prices 
+		WHERE region_name = ? AND machine_type = ? 
+		ORDER BY day DESC 
+		LIMIT ?`
+
+	var dates []string
+	var series []float64
+	err := s.querier.QueryRowsNamed("forecast_history", query, func(rows *sql.Rows) error {
+		var day string
+		var price float64
+		if err := rows.Scan(&day, &price); err != nil {
+			return fmt.Errorf("failed to scan daily price: %w", err)
+		}
+		dates = append(dates, day)
+		series = append(series, price)
+		return nil
+	}, regionName, machineType, historyDays)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily prices: %w", err)
+	}
+	// Oldest first for the models.
+	slices.Reverse(dates)
+	slices.Reverse(series)
+	if len(series) < forecast.MinTrainingPoints {
+		return nil, fmt.Errorf("%w: %s in %s has %d daily prices, at least %d are needed",
+			ErrInsufficientHistory, machineType, regionName, len(series), forecast.MinTrainingPoints)
+	}
+
+	z := confidenceZ[opts.Confidence]
+	fc, err := forecast.Forecast(series, opts.Model, horizonDays, z)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forecast: %w", err)
+	}
+
+	lastDay, err := time.Parse("2006-01-02", dates[len(dates)-1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse day: %w", err)
+	}
+	result := &models.SpotPriceForecast{
+		MachineType:  machineType,
+		RegionName:   regionName,
+		Model:        opts.Model,
+		Confidence:   opts.Confidence,
+		HorizonDays:  horizonDays,
+		TrainingFrom: dates[0],
+		TrainingTo:   dates[len(dates)-1],
+		TrainingDays: len(series),
+		LastPrice:    series[len(series)-1],
+		Parameters:   forecastParameters(fc),
+		Points:       forecastPoints(fc.Predictions, lastDay.AddDate(0, 0, 1), nil),
+	}
+
+	if opts.Backtest {
+		bt, err := forecast.Backtest(series, opts.Model, horizonDays, z)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInsufficientHistory, err)
+		}
+		heldOutFrom, err := time.Parse("2006-01-02", dates[len(dates)-horizonDays])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse day: %w", err)
+		}
+		result.Backtest = &models.ForecastBacktest{
+			HeldOutDays:         horizonDays,
+			MAE:                 roundUnitPrice(bt.MAE),
+			MAPE:                math.Round(bt.MAPE*100) / 100,
+			RMSE:                roundUnitPrice(bt.RMSE),
+			IntervalCoveragePct: math.Round(bt.Coverage*10) / 10,
+			NaiveMAE:            roundUnitPrice(bt.NaiveMAE),
+			NaiveMAPE:           math.Round(bt.NaiveMAPE*100) / 100,
+			Points:              forecastPoints(bt.Forecast.Predictions, heldOutFrom, bt.Actual),
+		}
+	}
+
+	return result, nil
+}
+
+// forecastPoints dates predictions from the first forecast day on, pairing them with actual prices if given.
+func forecastPoints(predictions []forecast.Prediction, firstDay time.Time, actual []float64) []models.ForecastPoint {
+	points := make([]models.ForecastPoint, len(predictions))
+	for i, p := range predictions {
+		points[i] = models.ForecastPoint{
+			Date:  firstDay.AddDate(0, 0, i).Format("2006-01-02"),
+			Price: roundUnitPrice(p.Value),
+			Lower: roundUnitPrice(p.Lower),
+			Upper: roundUnitPrice(p.Upper),
+		}
+		if actual != nil {
+			points[i].Actual = &actual[i]
+		}
+	}
+	return points
+}
+
+func forecastParameters(fc *forecast.Result) models.ForecastParameters {
+	params := models.ForecastParameters{Sigma: roundUnitPrice(fc.Params.Sigma)}
+	switch fc.Model {
+	case forecast.ModelLinear:
+		slope, intercept := math.Round(fc.Params.Slope*1e8)/1e8, roundUnitPrice(fc.Params.Intercept)
+		params.Slope, params.Intercept = &slope, &intercept
+	case forecast.ModelHolt:
+		alpha, beta := fc.Params.Alpha, fc.Params.Beta
+		params.Alpha, params.Beta = &alpha, &beta
+	}
+	return params
+}
+
+// days rounds a duration up to whole days.
+func days(d time.Duration) int {
+	return int(math.Ceil(d.Hours() / 24))
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+// Resolutions a price history can be requested at, from finest to coarsest.
+// Compacted rows may already be stored at day or week resolution.
+var resolutionRank = map[string]int{
+	"raw":   0,
+	"day":   1,
+	"week":  2,
+	"month": 3,
+}
+
+// HistoryOptions selects the time window and resolution of a price history.
+type HistoryOptions struct {
+	// From and To bound the window (inclusive); zero values leave it open.
+	From time.Time
+	To   time.Time
+	// Resolution is raw (default), day, week or month.
+	Resolution string
+	// Units adds prices per vCPU and per GB of memory.
+	Units bool
+}
+
+// ValidateResolution checks a requested history resolution, treating "" as raw.
+func ValidateResolution(resolution string) (string, error) {
+	if resolution == "" {
+		return "raw", nil
+	}
+	if _, ok := resolutionRank[resolution]; !ok {
+		return "", invalidParameter("resolution", "unknown resolution %q, expected raw, day, week or month", resolution)
+	}
+	return resolution, nil
+}
+
+// appendWindow adds the updated_ts bounds of the window to a query with a WHERE clause.
+func (o HistoryOptions) appendWindow(query string, args []interface{}) (string, []interface{}) {
+	if !o.From.IsZero() {
+		query += " AND updated_ts >= ?"
+		args = append(args, o.From.Unix())
+	}
+	if !o.To.IsZero() {
+		query += " AND updated_ts <= ?"
+		args = append(args, o.To.Unix())
+	}
+	return query, args
+}
+
+// downsampleHistory groups an ascending history into buckets of the given resolution.
+// Each bucket reports its last price, the min/max over the bucket and the bucket start
+// as timestamp. Points already stored at a coarser resolution are kept as they are.
+func downsampleHistory(history []models.PriceHistory, resolution string) []models.PriceHistory {
+	if resolution == "" || resolution == "raw" {
+		return history
+	}
+
+	var result []models.PriceHistory
+	d := &downsampler{resolution: resolution, emit: func(point models.PriceHistory) error {
+		result = append(result, point)
+		return nil
+	}}
+	for _, point := range history {
+		d.add(point)
+	}
+	d.flush()
+	return result
+}
+
+// downsampler groups an ascending history into buckets the way downsampleHistory does,
+// emitting each bucket once a later point shows it is complete.
+type downsampler struct {
+	resolution string
+	emit       func(point models.PriceHistory) error
+	// pending is the last bucket, not emitted yet. Later points of the same bucket
+	// extend it while open; coarser stored points are pending but never open.
+	pending    models.PriceHistory
+	hasPending bool
+	open       bool
+}
+
+// add adds the next point of the history, emitting the previous bucket if the point
+// does not belong to it.
+func (d *downsampler) add(point models.PriceHistory) error {
+	if d.resolution == "" || d.resolution == "raw" {
+		return d.emit(point)
+	}
+
+	if resolutionRank[point.Resolution] >= resolutionRank[d.resolution] {
+		if err := d.flush(); err != nil {
+			return err
+		}
+		d.pending, d.hasPending, d.open = point, true, false
+		return nil
+	}
+
+	start := timeutil.BucketStart(point.Timestamp, d.resolution)
+	if d.hasPending && d.open && start.Equal(d.pending.Timestamp) {
+		last := &d.pending
+		last.Price = point.Price
+		last.OnDemandPrice = point.OnDemandPrice
+		last.SpotDiscountPct = point.SpotDiscountPct
+		last.MinPrice = min(last.MinPrice, point.MinPrice)
+		last.MaxPrice = max(last.MaxPrice, point.MaxPrice)
+		return nil
+	}
+
+	if err := d.flush(); err != nil {
+		return err
+	}
+	point.Timestamp = start
+	point.Resolution = d.resolution
+	d.pending, d.hasPending, d.open = point, true, true
+	return nil
+}
+
+// flush emits the pending bucket, if any.
+func (d *downsampler) flush() error {
+	if !d.hasPending {
+		return nil
+	}
+	d.hasPending = false
+	return d.emit(d.pending)
+}
+
+// historyPageSize is the number of rows StreamPriceHistory reads per query.
+const historyPageSize = 1000
+
+// StreamPriceHistory passes the price history of a machine type in a region, oldest first
+// and downsampled like GetMachineDetail's, to send one point at a time. The history is read
+// in pages of historyPageSize rows, so neither the whole history nor a long-lived read
+// cursor is held while a slow client receives it. It stops with the error of ctx once ctx
+// is done, and with the error of send if send fails.
+func (s *PricingService) StreamPriceHistory(ctx context.Context, regionName, machineType string, opts HistoryOptions, send func(point models.PriceHistory) error) error {
+	if err := s.checkSeries(regionName, machineType); err != nil {
+		return err
+	}
+
+	var spec *machineSpec
+	if opts.Units {
+		specs, err := s.machineSpecs(machineType)
+		if err != nil {
+			return err
+		}
+		if found, ok := specs[machineType]; ok {
+			spec = &found
+		}
+	}
+
+	var sendErr error
+	d := &downsampler{resolution: opts.Resolution, emit: func(point models.PriceHistory) error {
+		if spec != nil {
+			point.UnitPrices = spec.unitPrices(point.Price, point.OnDemandPrice)
+		}
+		if err := send(point); err != nil {
+			sendErr = err
+			return err
+		}
+		return nil
+	}}
+
+	query := `
+		SELECT 
+			spot_hour_price, 
+			COALESCE(min_spot_hour_price, spot_hour_price), 
+			COALESCE(max_spot_hour_price, spot_hour_price), 
+			hour_price, 
+			updated_ts, 
+			resolution 
+		FROM pricing_history 
+		WHERE region_name = ? AND machine_type = ?`
+	window, windowArgs := opts.appendWindow("", nil)
+	query += window + " AND updated_ts > ? ORDER BY updated_ts ASC LIMIT ?"
+
+	after := int64(math.MinInt64)
+	for {
+		args := append([]interface{}{regionName, machineType}, windowArgs...)
+		args = append(args, after, historyPageSize)
+		rows := 0
+		err := s.querier.QueryRowsNamed("stream_history", query, func(r *sql.Rows) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			point, err := scanPriceHistory(r)
+			if err != nil {
+				return err
+			}
+			rows++
+			after = point.Timestamp.Unix()
+			return d.add(point)
+		}, args...)
+		if sendErr != nil {
+			return sendErr
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query price history: %w", err)
+		}
+		if rows < historyPageSize {
+			break
+		}
+	}
+	return d.flush()
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// point returns a history point of price, stored with its min/max at resolution.
+func point(at time.Time, resolution string, price, minPrice, maxPrice float64) models.PriceHistory {
+	return models.PriceHistory{
+		Price:      price,
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		Timestamp:  at,
+		Resolution: resolution,
+	}
+}
+
+func utc(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestDownsampleHistory(t *testing.T) {
+	raw := []models.PriceHistory{
+		point(utc(2024, 3, 14, 1), "raw", 3, 3, 3),
+		point(utc(2024, 3, 14, 13), "raw", 5, 5, 5),
+		point(utc(2024, 3, 15, 2), "raw", 4, 4, 4),
+	}
+
+	tests := []struct {
+		name       string
+		history    []models.PriceHistory
+		resolution string
+		want       []models.PriceHistory
+	}{
+		{name: "raw", history: raw, resolution: "raw", want: raw},
+		{
+			// Each day keeps its last price and the range of its points.
+			name:       "day",
+			history:    raw,
+			resolution: "day",
+			want: []models.PriceHistory{
+				point(utc(2024, 3, 14, 0), "day", 5, 3, 5),
+				point(utc(2024, 3, 15, 0), "day", 4, 4, 4),
+			},
+		},
+		{
+			// 2024-03-14 and 15 are in the week starting Monday the 11th.
+			name:       "week",
+			history:    raw,
+			resolution: "week",
+			want:       []models.PriceHistory{point(utc(2024, 3, 11, 0), "week", 4, 3, 5)},
+		},
+		{
+			// Points already stored as weeks are kept as they are.
+			name: "compacted weeks are kept",
+			history: append([]models.PriceHistory{
+				point(utc(2024, 2, 26, 0), "week", 2, 1, 6),
+				point(utc(2024, 3, 4, 0), "week", 3, 2, 3),
+			}, raw...),
+			resolution: "week",
+			want: []models.PriceHistory{
+				point(utc(2024, 2, 26, 0), "week", 2, 1, 6),
+				point(utc(2024, 3, 4, 0), "week", 3, 2, 3),
+				point(utc(2024, 3, 11, 0), "week", 4, 3, 5),
+			},
+		},
+		{
+			// Weeks are finer than months, so they are merged into the month they start in.
+			name: "month",
+			history: []models.PriceHistory{
+				point(utc(2024, 1, 29, 0), "week", 2, 1, 5),
+				point(utc(2024, 2, 3, 6), "raw", 3, 3, 3),
+				point(utc(2024, 2, 5, 0), "week", 4, 4, 8),
+			},
+			resolution: "month",
+			want: []models.PriceHistory{
+				point(utc(2024, 1, 1, 0), "month", 2, 1, 5),
+				point(utc(2024, 2, 1, 0), "month", 4, 3, 8),
+			},
+		},
+		{name: "empty", resolution: "day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := downsampleHistory(tt.history, tt.resolution); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("downsampleHistory = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownsamplerEmitError(t *testing.T) {
+	errStop := errors.New("client went away")
+	var emitted int
+	d := &downsampler{resolution: "day", emit: func(models.PriceHistory) error {
+		emitted++
+		return errStop
+	}}
+
+	if err := d.add(point(utc(2024, 3, 14, 1), "raw", 3, 3, 3)); err != nil {
+		t.Fatalf("first point: %v", err)
+	}
+	// The next day completes the first bucket, whose emit fails.
+	if err := d.add(point(utc(2024, 3, 15, 1), "raw", 4, 4, 4)); !errors.Is(err, errStop) {
+		t.Fatalf("second point: error %v, want %v", err, errStop)
+	}
+	if emitted != 1 {
+		t.Errorf("emitted %d buckets, want 1", emitted)
+	}
+}
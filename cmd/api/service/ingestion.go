@@ -0,0 +1,65 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// GetIngestionRuns returns the most recent ingestion runs, newest first.
+func (s *PricingService) GetIngestionRuns(limit int) ([]models.IngestionRun, error) {
+	query := `
+		SELECT 
+			id, started_at, finished_at, status, data_path, 
+			files_processed, files_skipped, files_failed, 
+			rows_inserted, rows_ignored, warning_count, error_count, 
+			warnings, errors 
+		FROM ingestion_runs 
+		ORDER BY id DESC 
+		LIMIT ?`
+
+	runs := []models.IngestionRun{}
+	err := s.querier.QueryRowsNamed("ingestion_runs", query, func(rows *sql.Rows) error {
+		var run models.IngestionRun
+		var startedAt int64
+		var finishedAt sql.NullInt64
+		var warnings, errs sql.NullString
+		if err := rows.Scan(
+			&run.ID, &startedAt, &finishedAt, &run.Status, &run.DataPath,
+			&run.FilesProcessed, &run.FilesSkipped, &run.FilesFailed,
+			&run.RowsInserted, &run.RowsIgnored, &run.WarningCount, &run.ErrorCount,
+			&warnings, &errs,
+		); err != nil {
+			return fmt.Errorf("failed to scan ingestion run: %w", err)
+		}
+
+		run.StartedAt = time.Unix(startedAt, 0).UTC()
+		if finishedAt.Valid {
+			t := time.Unix(finishedAt.Int64, 0).UTC()
+			run.FinishedAt = &t
+		}
+		run.Warnings = decodeMessages(warnings)
+		run.Errors = decodeMessages(errs)
+
+		runs = append(runs, run)
+		return nil
+	}, limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingestion runs: %w", err)
+	}
+	return runs, nil
+}
+
+// decodeMessages decodes a JSON array of messages stored by dataprocessing.
+func decodeMessages(raw sql.NullString) []string {
+	messages := []string{}
+	if raw.Valid && raw.String != "" {
+		// Messages are informational; a malformed value should not fail the listing.
+		_ = json.Unmarshal([]byte(raw.String), &messages)
+	}
+	return messages
+}
@@ -4,6 +4,9 @@ package service
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
@@ -12,7 +15,8 @@ import (
 
 // PricingService provides business logic for pricing data operations.
 type PricingService struct {
-	querier *db.Querier
+	querier  *db.Querier
+	coverage coverageCache
 }
 
 // NewPricingService creates a new PricingService instance.
@@ -23,8 +27,9 @@ func NewPricingService(querier *db.Querier) *PricingService {
 // GetAllRegions returns a list of all available regions.
 func (s *PricingService) GetAllRegions() ([]string, error) {
 	var regions []string
-	err := s.querier.QueryRows(
-		"SELECT DISTINCT(region_name) FROM pricing_history ORDER BY region_name",
+	err := s.querier.QueryRowsNamed(
+		"regions",
+		"SELECT DISTINCT(region_name) FROM price_summary ORDER BY region_name",
 		func(rows *sql.Rows) error {
 			var region string
 			if err := rows.Scan(&region); err != nil {
@@ -40,89 +45,359 @@ func (s *PricingService) GetAllRegions() ([]string, error) {
 	return regions, nil
 }
 
-// GetMachinesByRegion returns all machine types for a given region.
-func (s *PricingService) GetMachinesByRegion(regionName string) ([]models.Machine, error) {
-	query := `
+// MachineListOptions controls the optional parts and the order of a machine listing.
+type MachineListOptions struct {
+	// Units adds prices per vCPU and per GB of memory.
+	Units bool
+	// Stats adds volatility metrics over StatsWindow (DefaultStatsWindow if zero).
+	Stats       bool
+	StatsWindow time.Duration
+	// Sort is a sort key, prefixed with "-" for descending order. Sorting by a
+	// volatility metric implies Stats.
+	Sort string
+	// AsOf lists prices and statistics as they were at this instant; zero means now.
+	AsOf time.Time
+}
+
+// Sort keys of machine listings. Keys reading Volatility require volatility metrics.
+var machineSortKeys = map[string]func(m models.Machine) (float64, bool){
+	"hour_spot_price":          func(m models.Machine) (float64, bool) { return m.HourSpotPrice, true },
+	"hour_price":               func(m models.Machine) (float64, bool) { return m.HourPrice, true },
+	"spot_discount_pct":        func(m models.Machine) (float64, bool) { return m.SpotDiscountPct, true },
+	"avg_hour_spot_price":      func(m models.Machine) (float64, bool) { return m.AvgHourSpotPrice, true },
+	"change_count":             func(m models.Machine) (float64, bool) { return float64(m.ChangeCount), true },
+	"std_dev":                  volatilityKey(func(v *models.VolatilityStats) float64 { return v.StdDev }),
+	"coefficient_of_variation": volatilityKey(func(v *models.VolatilityStats) float64 { return v.CoefficientOfVariation }),
+	"max_drawdown_pct":         volatilityKey(func(v *models.VolatilityStats) float64 { return v.MaxDrawdownPct }),
+	"max_spike_pct":            volatilityKey(func(v *models.VolatilityStats) float64 { return v.MaxSpikePct }),
+	"stability_score":          volatilityKey(func(v *models.VolatilityStats) float64 { return v.StabilityScore }),
+	"mean_hours_between_changes": func(m models.Machine) (float64, bool) {
+		if m.Volatility == nil || m.Volatility.MeanHoursBetweenChanges == nil {
+			return 0, false
+		}
+		return *m.Volatility.MeanHoursBetweenChanges, true
+	},
+}
+
+// volatilitySortKeys are the sort keys that need volatility metrics.
+var volatilitySortKeys = map[string]bool{
+	"std_dev":                    true,
+	"coefficient_of_variation":   true,
+	"max_drawdown_pct":           true,
+	"max_spike_pct":              true,
+	"stability_score":            true,
+	"mean_hours_between_changes": true,
+}
+
+func volatilityKey(metric func(v *models.VolatilityStats) float64) func(m models.Machine) (float64, bool) {
+	return func(m models.Machine) (float64, bool) {
+		if m.Volatility == nil {
+			return 0, false
+		}
+		return metric(m.Volatility), true
+	}
+}
+
+// Validate checks the sort key and fills in defaults.
+func (o *MachineListOptions) Validate() error {
+	key := strings.TrimPrefix(o.Sort, "-")
+	if _, ok := machineSortKeys[key]; !ok && key != "" && key != "machine_type" {
+		keys := []string{"machine_type"}
+		for k := range machineSortKeys {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return invalidParameter("sort", "unknown sort key %q, expected one of %s (prefix with - for descending order)", key, strings.Join(keys, ", "))
+	}
+	if volatilitySortKeys[key] {
+		o.Stats = true
+	}
+	if o.StatsWindow < 0 {
+		return invalidParameter("window", "window must not be negative")
+	}
+	if o.StatsWindow == 0 {
+		o.StatsWindow = DefaultStatsWindow
+	}
+	return nil
+}
+
+// GetMachinesByRegion returns all machine types for a given region, optionally with unit
+// prices and volatility metrics. Without a sort key machines are listed by name, descending.
+// Statistics come from price_summary, which dataprocessing keeps up to date after every import,
+// or for point-in-time listings from the history up to opts.AsOf.
+func (s *PricingService) GetMachinesByRegion(regionName string, opts MachineListOptions) ([]models.Machine, error) {
+	if err := s.checkSeries(regionName, ""); err != nil {
+		return nil, err
+	}
+
+	query, args := `
 		SELECT 
-			DISTINCT(machine_type), 
-			MIN(spot_hour_price), 
-			MAX(spot_hour_price), 
-			spot_hour_price 
-		FROM pricing_history 
+			machine_type, 
+			min_spot_hour_price, 
+			max_spot_hour_price, 
+			avg_spot_hour_price, 
+			current_spot_hour_price, 
+			current_hour_price, 
+			first_seen_ts, 
+			last_seen_ts, 
+			change_count, 
+			last_change_ts, 
+			previous_spot_hour_price 
+		FROM price_summary 
 		WHERE region_name = ? 
-		GROUP BY machine_type 
-		ORDER BY machine_type DESC`
+		ORDER BY machine_type DESC`, []interface{}{regionName}
+	if !opts.AsOf.IsZero() {
+		query, args = machinesAsOfQuery, []interface{}{regionName, opts.AsOf.Unix(), regionName, opts.AsOf.Unix()}
+	}
 
 	var machines []models.Machine
-	err := s.querier.QueryRows(query, func(rows *sql.Rows) error {
-		var machine models.Machine
-		machine.RegionName = regionName
-		if err := rows.Scan(&machine.MachineType, &machine.MinHourSpotPrice, &machine.MaxHourSpotPrice, &machine.HourSpotPrice); err != nil {
-			return fmt.Errorf("failed to scan machine: %w", err)
+	err := s.querier.QueryRowsNamed("machines_by_region", query, func(rows *sql.Rows) error {
+		machine := models.Machine{RegionName: regionName}
+		if err := scanMachine(rows, &machine); err != nil {
+			return err
 		}
 		machines = append(machines, machine)
 		return nil
-	}, regionName)
+	}, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query machines: %w", err)
 	}
 
+	if opts.Units {
+		specs, err := s.machineSpecs()
+		if err != nil {
+			return nil, err
+		}
+		for i, machine := range machines {
+			if spec, ok := specs[machine.MachineType]; ok {
+				machines[i].UnitPrices = spec.unitPrices(machine.HourSpotPrice, machine.HourPrice)
+			}
+		}
+	}
+
+	if opts.Stats {
+		stats, err := s.seriesVolatility(seriesFilter{regionName: regionName, asOf: opts.AsOf}, opts.StatsWindow)
+		if err != nil {
+			return nil, err
+		}
+		for i, machine := range machines {
+			machines[i].Volatility = stats[seriesID{regionName, machine.MachineType}]
+		}
+	}
+
+	sortMachines(machines, opts.Sort)
 	return machines, nil
 }
 
 // GetMachineDetail returns detailed information about a specific machine type in a region.
-func (s *PricingService) GetMachineDetail(regionName, machineType string) (*models.MachineDetail, error) {
+// The history and its min/max statistics cover the window selected by opts.
+func (s *PricingService) GetMachineDetail(regionName, machineType string, opts HistoryOptions) (*models.MachineDetail, error) {
+	if err := s.checkSeries(regionName, machineType); err != nil {
+		return nil, err
+	}
+
 	result := &models.MachineDetail{
 		MachineType: machineType,
 		RegionName:  regionName,
 	}
+	if !opts.From.IsZero() {
+		from := opts.From.UTC()
+		result.From = &from
+	}
+	if !opts.To.IsZero() {
+		to := opts.To.UTC()
+		result.To = &to
+	}
 
 	// Get price history
 	historyQuery := `
-		SELECT spot_hour_price, updated_ts 
+		SELECT 
+			spot_hour_price, 
+			COALESCE(min_spot_hour_price, spot_hour_price), 
+			COALESCE(max_spot_hour_price, spot_hour_price), 
+			hour_price, 
+			updated_ts, 
+			resolution 
 		FROM pricing_history 
-		WHERE region_name = ? AND machine_type = ? 
-		ORDER BY updated_ts ASC`
+		WHERE region_name = ? AND machine_type = ?`
+	args := []interface{}{regionName, machineType}
+	historyQuery, args = opts.appendWindow(historyQuery, args)
+	historyQuery += " ORDER BY updated_ts ASC"
 
-	err := s.querier.QueryRows(historyQuery, func(rows *sql.Rows) error {
-		var price float64
-		var timestampUnix int64
-		if err := rows.Scan(&price, &timestampUnix); err != nil {
-			return fmt.Errorf("failed to scan price history: %w", err)
+	var history []models.PriceHistory
+	err := s.querier.QueryRowsNamed("machine_history", historyQuery, func(rows *sql.Rows) error {
+		point, err := scanPriceHistory(rows)
+		if err != nil {
+			return err
 		}
-
-		result.SpotHourPriceHistory = append(result.SpotHourPriceHistory, models.PriceHistory{
-			Price:     price,
-			Timestamp: time.Unix(timestampUnix, 0),
-		})
+		history = append(history, point)
 		return nil
-	}, regionName, machineType)
+	}, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query price history: %w", err)
 	}
 
-	// Get aggregate statistics
-	statsQuery := `
-		SELECT MIN(spot_hour_price), MAX(spot_hour_price), spot_hour_price 
-		FROM pricing_history 
-		WHERE region_name = ? AND machine_type = ? 
-		ORDER BY updated_ts DESC 
-		LIMIT 1`
+	// Statistics cover the selected window, computed before downsampling so
+	// compacted min/max ranges are taken into account.
+	for i, point := range history {
+		if i == 0 || point.MinPrice < result.MinHourSpotPrice {
+			result.MinHourSpotPrice = point.MinPrice
+		}
+		if i == 0 || point.MaxPrice > result.MaxHourSpotPrice {
+			result.MaxHourSpotPrice = point.MaxPrice
+		}
+	}
+
+	result.SpotHourPriceHistory = downsampleHistory(history, opts.Resolution)
+	result.Resolution = historyResolution(result.SpotHourPriceHistory)
 
-	var minPrice, maxPrice, spotHourPrice float64
-	err = s.querier.QueryRow(statsQuery, func(row *sql.Row) error {
-		return row.Scan(&minPrice, &maxPrice, &spotHourPrice)
+	// The current price is the latest snapshot, regardless of the window.
+	currentQuery := `
+		SELECT current_spot_hour_price, current_hour_price, last_change_ts, previous_spot_hour_price 
+		FROM price_summary 
+		WHERE region_name = ? AND machine_type = ?`
+
+	var lastChange sql.NullInt64
+	var previousPrice sql.NullFloat64
+	err = s.querier.QueryRowNamed("machine_current", currentQuery, func(row *sql.Row) error {
+		return row.Scan(&result.HourSpotPrice, &result.HourPrice, &lastChange, &previousPrice)
 	}, regionName, machineType)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query statistics: %w", err)
 	}
+	result.SpotDiscountPct = spotDiscountPct(result.HourSpotPrice, result.HourPrice)
+	result.LastChangedAt, result.PreviousPrice, result.ChangePct = lastPriceChange(lastChange, previousPrice, result.HourSpotPrice)
 
-	result.MinHourSpotPrice = minPrice
-	result.MaxHourSpotPrice = maxPrice
-	result.HourSpotPrice = spotHourPrice
+	if opts.Units {
+		specs, err := s.machineSpecs(machineType)
+		if err != nil {
+			return nil, err
+		}
+		if spec, ok := specs[machineType]; ok {
+			result.CpuCores, result.MemoryGB = spec.cpuCores, spec.memoryGB
+			result.UnitPrices = spec.unitPrices(result.HourSpotPrice, result.HourPrice)
+			for i, point := range result.SpotHourPriceHistory {
+				result.SpotHourPriceHistory[i].UnitPrices = spec.unitPrices(point.Price, point.OnDemandPrice)
+			}
+		}
+	}
 
 	return result, nil
 }
+
+// scanMachine scans the summary columns of a machine listing (machine_type, min, max and
+// avg spot price, current spot and on-demand price, first/last seen, change count, last
+// change and previous spot price) followed by any extra columns into extra.
+func scanMachine(rows *sql.Rows, machine *models.Machine, extra ...interface{}) error {
+	var firstSeen, lastSeen int64
+	var lastChange sql.NullInt64
+	var previousPrice sql.NullFloat64
+	dest := []interface{}{
+		&machine.MachineType,
+		&machine.MinHourSpotPrice,
+		&machine.MaxHourSpotPrice,
+		&machine.AvgHourSpotPrice,
+		&machine.HourSpotPrice,
+		&machine.HourPrice,
+		&firstSeen,
+		&lastSeen,
+		&machine.ChangeCount,
+		&lastChange,
+		&previousPrice,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("failed to scan machine: %w", err)
+	}
+	machine.SpotDiscountPct = spotDiscountPct(machine.HourSpotPrice, machine.HourPrice)
+	machine.FirstSeen = time.Unix(firstSeen, 0).UTC()
+	machine.LastSeen = time.Unix(lastSeen, 0).UTC()
+	machine.LastChangedAt, machine.PreviousPrice, machine.ChangePct = lastPriceChange(lastChange, previousPrice, machine.HourSpotPrice)
+	return nil
+}
+
+// scanPriceHistory scans a history point (spot price, min and max spot price, on-demand
+// price, updated_ts and resolution) followed by any extra columns into extra.
+func scanPriceHistory(rows *sql.Rows, extra ...interface{}) (models.PriceHistory, error) {
+	var point models.PriceHistory
+	var timestampUnix int64
+	dest := []interface{}{&point.Price, &point.MinPrice, &point.MaxPrice, &point.OnDemandPrice, &timestampUnix, &point.Resolution}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return point, fmt.Errorf("failed to scan price history: %w", err)
+	}
+	point.Timestamp = time.Unix(timestampUnix, 0)
+	point.SpotDiscountPct = spotDiscountPct(point.Price, point.OnDemandPrice)
+	return point, nil
+}
+
+// spotDiscountPct returns how much cheaper spot is than on-demand, in percent
+// rounded to two decimals. It is 0 when the on-demand price is unknown.
+func spotDiscountPct(spotPrice, onDemandPrice float64) float64 {
+	if onDemandPrice <= 0 {
+		return 0
+	}
+	return math.Round((1-spotPrice/onDemandPrice)*10000) / 100
+}
+
+// lastPriceChange converts the last change columns of price_summary into API fields.
+// All values are nil if the spot price never changed.
+func lastPriceChange(changedTS sql.NullInt64, previous sql.NullFloat64, current float64) (*time.Time, *float64, *float64) {
+	if !changedTS.Valid || !previous.Valid {
+		return nil, nil, nil
+	}
+	changedAt := time.Unix(changedTS.Int64, 0).UTC()
+	previousPrice := previous.Float64
+	var changePct float64
+	if previousPrice != 0 {
+		changePct = math.Round((current-previousPrice)/previousPrice*10000) / 100
+	}
+	return &changedAt, &previousPrice, &changePct
+}
+
+// historyResolution summarizes the resolution of a history: the common resolution
+// of all points, or "mixed" when compaction left several resolutions.
+func historyResolution(history []models.PriceHistory) string {
+	if len(history) == 0 {
+		return "raw"
+	}
+	resolution := history[0].Resolution
+	for _, point := range history[1:] {
+		if point.Resolution != resolution {
+			return "mixed"
+		}
+	}
+	return resolution
+}
+
+// sortMachines orders machines by a sort key, "-" prefixed for descending order.
+// Machines without a value for the key are listed last.
+func sortMachines(machines []models.Machine, sortKey string) {
+	key, descending := strings.CutPrefix(sortKey, "-")
+	if key == "" {
+		return
+	}
+	if key == "machine_type" {
+		sort.SliceStable(machines, func(i, j int) bool {
+			if descending {
+				return machines[i].MachineType > machines[j].MachineType
+			}
+			return machines[i].MachineType < machines[j].MachineType
+		})
+		return
+	}
+
+	value := machineSortKeys[key]
+	sort.SliceStable(machines, func(i, j int) bool {
+		a, okA := value(machines[i])
+		b, okB := value(machines[j])
+		if okA != okB {
+			return okA
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+}
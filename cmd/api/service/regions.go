@@ -0,0 +1,143 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/regions"
+)
+
+// Continents returns the continent names accepted by RegionFilter.
+func Continents() []string {
+	return regions.Continents()
+}
+
+// RegionContinent returns the continent of a region, derived from its name prefix
+// (europe-west1 is in europe, us-east1 and northamerica-northeast1 in north-america).
+func RegionContinent(regionName string) string {
+	return regions.Continent(regionName)
+}
+
+// RegionFilter restricts results to some continents and/or regions. Empty fields match everything.
+type RegionFilter struct {
+	Continents []string
+	Regions    []string
+}
+
+// Validate checks that all requested continents are known.
+func (f RegionFilter) Validate() error {
+	known := Continents()
+	for _, continent := range f.Continents {
+		if i := sort.SearchStrings(known, continent); i == len(known) || known[i] != continent {
+			return invalidParameter("continent", "unknown continent %q, expected one of %s", continent, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a region passes the filter.
+func (f RegionFilter) Matches(regionName string) bool {
+	if len(f.Regions) > 0 && !contains(f.Regions, regionName) {
+		return false
+	}
+	if len(f.Continents) > 0 && !contains(f.Continents, RegionContinent(regionName)) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareMachineAcrossRegions ranks the regions offering a machine type by current
+// spot price and by historical average spot price.
+func (s *PricingService) CompareMachineAcrossRegions(machineType string, filter RegionFilter) (*models.MachineRegionComparison, error) {
+	if err := s.checkSeries("", machineType); err != nil {
+		return nil, err
+	}
+
+	result := &models.MachineRegionComparison{
+		MachineType: machineType,
+		Regions:     []models.RegionPrice{},
+	}
+
+	query := `
+		SELECT 
+			region_name, 
+			current_spot_hour_price, 
+			current_hour_price, 
+			min_spot_hour_price, 
+			max_spot_hour_price, 
+			avg_spot_hour_price, 
+			last_seen_ts, 
+			last_seen_ts = (SELECT MAX(last_seen_ts) FROM price_summary) 
+		FROM price_summary 
+		WHERE machine_type = ?`
+
+	err := s.querier.QueryRowsNamed("compare_regions", query, func(rows *sql.Rows) error {
+		var region models.RegionPrice
+		var lastSeen int64
+		if err := rows.Scan(
+			&region.RegionName,
+			&region.HourSpotPrice,
+			&region.HourPrice,
+			&region.MinHourSpotPrice,
+			&region.MaxHourSpotPrice,
+			&region.AvgHourSpotPrice,
+			&lastSeen,
+			&region.InLatestSnapshot,
+		); err != nil {
+			return fmt.Errorf("failed to scan region price: %w", err)
+		}
+		if !filter.Matches(region.RegionName) {
+			return nil
+		}
+		region.Continent = RegionContinent(region.RegionName)
+		region.SpotDiscountPct = spotDiscountPct(region.HourSpotPrice, region.HourPrice)
+		region.LastSeen = time.Unix(lastSeen, 0).UTC()
+		result.Regions = append(result.Regions, region)
+		return nil
+	}, machineType)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query region prices: %w", err)
+	}
+
+	sort.SliceStable(result.Regions, func(i, j int) bool {
+		return result.Regions[i].AvgHourSpotPrice < result.Regions[j].AvgHourSpotPrice
+	})
+	for i := range result.Regions {
+		result.Regions[i].HistoricalRank = i + 1
+	}
+	if len(result.Regions) > 0 {
+		result.HistoricallyCheapestRegion = result.Regions[0].RegionName
+	}
+
+	// Regions no longer offering the machine are ranked after the current ones.
+	sort.SliceStable(result.Regions, func(i, j int) bool {
+		a, b := result.Regions[i], result.Regions[j]
+		if a.InLatestSnapshot != b.InLatestSnapshot {
+			return a.InLatestSnapshot
+		}
+		return a.HourSpotPrice < b.HourSpotPrice
+	})
+	for i := range result.Regions {
+		result.Regions[i].Rank = i + 1
+	}
+	if len(result.Regions) > 0 {
+		result.CheapestRegion = result.Regions[0].RegionName
+	}
+
+	result.Count = len(result.Regions)
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// ValidateSavingsRequest checks a workload description.
+func ValidateSavingsRequest(req models.SavingsRequest) error {
+	if req.MachineType == "" || req.RegionName == "" {
+		return invalidParameter("", "machine_type and region_name are required")
+	}
+	if req.InstanceCount <= 0 {
+		return invalidParameter("instance_count", "instance_count must be positive")
+	}
+	if req.HoursPerDay <= 0 || req.HoursPerDay > 24 {
+		return invalidParameter("hours_per_day", "hours_per_day must be greater than 0 and at most 24")
+	}
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return invalidParameter("from", "from must be a date in YYYY-MM-DD format")
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return invalidParameter("to", "to must be a date in YYYY-MM-DD format")
+	}
+	if to.Before(from) {
+		return invalidParameter("to", "to must not be before from")
+	}
+	return nil
+}
+
+// CalculateSavings replays the daily prices of the requested range and adds up what the
+// workload would have cost per month. Spot and on-demand instances are billed for the hours
+// they run; committed use discounts are billed around the clock.
+func (s *PricingService) CalculateSavings(req models.SavingsRequest) (*models.SavingsReport, error) {
+	if err := s.checkSeries(req.RegionName, req.MachineType); err != nil {
+		return nil, err
+	}
+
+	report := &models.SavingsReport{
+		MachineType:   req.MachineType,
+		RegionName:    req.RegionName,
+		InstanceCount: req.InstanceCount,
+		HoursPerDay:   req.HoursPerDay,
+		From:          req.From,
+		To:            req.To,
+		Months:        []models.SavingsPeriod{},
+	}
+
+	query := `
+		SELECT day, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price 
+		FROM daily_prices 
+		WHERE region_name = ? AND machine_type = ? AND day >= ? AND day <= ? 
+		ORDER BY day`
+
+	instances := float64(req.InstanceCount)
+	runHours := instances * req.HoursPerDay
+	committedHours := instances * 24
+	err := s.querier.QueryRowsNamed("savings_daily_prices", query, func(rows *sql.Rows) error {
+		var day string
+		var hourPrice, spotPrice float64
+		var commit1y, commit3y sql.NullFloat64
+		if err := rows.Scan(&day, &hourPrice, &spotPrice, &commit1y, &commit3y); err != nil {
+			return fmt.Errorf("failed to scan daily price: %w", err)
+		}
+		if !commit1y.Valid || !commit3y.Valid {
+			report.DaysWithoutCommitmentPrices++
+		}
+
+		month := day[:7]
+		if n := len(report.Months); n == 0 || report.Months[n-1].Month != month {
+			report.Months = append(report.Months, models.SavingsPeriod{
+				Month:        month,
+				Commit1yCost: new(float64),
+				Commit3yCost: new(float64),
+			})
+		}
+		period := &report.Months[len(report.Months)-1]
+		period.Days++
+		period.InstanceHours += runHours
+		period.SpotCost += spotPrice * runHours
+		period.OnDemandCost += hourPrice * runHours
+		period.Commit1yCost = addCommitmentCost(period.Commit1yCost, commit1y, committedHours)
+		period.Commit3yCost = addCommitmentCost(period.Commit3yCost, commit3y, committedHours)
+		return nil
+	}, req.RegionName, req.MachineType, req.From, req.To)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily prices: %w", err)
+	}
+
+	if len(report.Months) > 0 {
+		report.Total.Commit1yCost = new(float64)
+		report.Total.Commit3yCost = new(float64)
+	}
+	for i := range report.Months {
+		period := &report.Months[i]
+		report.Total.Days += period.Days
+		report.Total.InstanceHours += period.InstanceHours
+		report.Total.SpotCost += period.SpotCost
+		report.Total.OnDemandCost += period.OnDemandCost
+		report.Total.Commit1yCost = addCost(report.Total.Commit1yCost, period.Commit1yCost)
+		report.Total.Commit3yCost = addCost(report.Total.Commit3yCost, period.Commit3yCost)
+		roundSavingsPeriod(period)
+	}
+	roundSavingsPeriod(&report.Total)
+
+	from, _ := time.Parse("2006-01-02", req.From)
+	to, _ := time.Parse("2006-01-02", req.To)
+	report.DaysWithoutPrices = int(to.Sub(from).Hours()/24) + 1 - report.Total.Days
+
+	if report.Total.Days > 0 {
+		options := []struct {
+			name string
+			cost *float64
+		}{
+			{"spot", &report.Total.SpotCost},
+			{"on_demand", &report.Total.OnDemandCost},
+			{"commit_1y", report.Total.Commit1yCost},
+			{"commit_3y", report.Total.Commit3yCost},
+		}
+		cheapest := options[0]
+		for _, option := range options[1:] {
+			if option.cost != nil && *option.cost < *cheapest.cost {
+				cheapest = option
+			}
+		}
+		report.CheapestOption = cheapest.name
+	}
+	return report, nil
+}
+
+// addCommitmentCost adds a day of committed use to a running cost. A day without a
+// commitment price makes the cost unknown, so it stays nil from then on.
+func addCommitmentCost(cost *float64, price sql.NullFloat64, hours float64) *float64 {
+	if cost == nil || !price.Valid {
+		return nil
+	}
+	total := *cost + price.Float64*hours
+	return &total
+}
+
+// addCost adds two costs that are nil when unknown.
+func addCost(a, b *float64) *float64 {
+	if a == nil || b == nil {
+		return nil
+	}
+	total := *a + *b
+	return &total
+}
+
+// roundCost rounds a cost that is nil when unknown to cents.
+func roundCost(cost *float64) *float64 {
+	if cost == nil {
+		return nil
+	}
+	rounded := math.Round(*cost*100) / 100
+	return &rounded
+}
+
+// roundSavingsPeriod rounds costs to cents and fills in the spot savings.
+func roundSavingsPeriod(period *models.SavingsPeriod) {
+	if period.OnDemandCost > 0 {
+		period.SpotSavingsPct = math.Round((1-period.SpotCost/period.OnDemandCost)*10000) / 100
+	}
+	period.InstanceHours = math.Round(period.InstanceHours*100) / 100
+	period.SpotCost = math.Round(period.SpotCost*100) / 100
+	period.OnDemandCost = math.Round(period.OnDemandCost*100) / 100
+	period.Commit1yCost = roundCost(period.Commit1yCost)
+	period.Commit3yCost = roundCost(period.Commit3yCost)
+}
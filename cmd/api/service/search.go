@@ -0,0 +1,164 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// MachineArch returns the CPU architecture of a machine family, "arm" or "x86". Google
+// names the series of Arm machines with an "a" after the generation (t2a, c4a, n4a), so
+// the architecture follows from the family without a list that new series would outdate.
+func MachineArch(family string) string {
+	if n := len(family); n >= 2 && family[n-1] == 'a' && family[n-2] >= '0' && family[n-2] <= '9' {
+		return "arm"
+	}
+	return "x86"
+}
+
+// Candidate orderings accepted by SearchOptions.RankBy.
+var searchRankings = map[string]func(a, b models.InstanceCandidate) bool{
+	"price":    func(a, b models.InstanceCandidate) bool { return a.HourSpotPrice < b.HourSpotPrice },
+	"per_vcpu": func(a, b models.InstanceCandidate) bool { return a.SpotPricePerVCPU < b.SpotPricePerVCPU },
+	"per_gb":   func(a, b models.InstanceCandidate) bool { return a.SpotPricePerGB < b.SpotPricePerGB },
+}
+
+// SearchOptions describes the requirements of a cheapest instance search.
+type SearchOptions struct {
+	MinCPU      float64
+	MinMemoryGB float64
+	// Arch is "x86", "arm" or empty for both.
+	Arch     string
+	Families []string
+	Regions  RegionFilter
+	// RankBy is "price" (default), "per_vcpu" or "per_gb".
+	RankBy string
+	Limit  int
+	// StabilityWindow enables stability scoring over the daily prices of this period when non-zero.
+	StabilityWindow time.Duration
+}
+
+// Validate checks the options and fills in defaults.
+func (o *SearchOptions) Validate() error {
+	if o.MinCPU < 0 || o.MinMemoryGB < 0 {
+		return invalidParameter("", "min_cpu and min_memory_gb must not be negative")
+	}
+	if o.Arch != "" && o.Arch != "x86" && o.Arch != "arm" {
+		return invalidParameter("arch", "arch must be x86 or arm")
+	}
+	if o.RankBy == "" {
+		o.RankBy = "price"
+	}
+	if _, ok := searchRankings[o.RankBy]; !ok {
+		return invalidParameter("rank_by", "rank_by must be price, per_vcpu or per_gb")
+	}
+	if o.Limit <= 0 || o.Limit > 500 {
+		return invalidParameter("limit", "limit must be between 1 and 500")
+	}
+	if o.StabilityWindow < 0 {
+		return invalidParameter("stability_window", "stability_window must not be negative")
+	}
+	return o.Regions.Validate()
+}
+
+// SearchCheapest returns machine types in regions that meet the requirements, ranked by
+// current spot price (or unit price). Only series present in the latest snapshot are considered.
+func (s *PricingService) SearchCheapest(opts SearchOptions) (*models.CheapestSearchResponse, error) {
+	// machine_type keeps a row per distinct spec; the latest one describes the machine today.
+	query := `
+		SELECT 
+			ps.machine_type, 
+			mt.family, 
+			ps.region_name, 
+			mt.cpu_cores, 
+			mt.memory_gb, 
+			ps.current_spot_hour_price, 
+			ps.current_hour_price 
+		FROM price_summary ps 
+		JOIN machine_type mt ON mt.id = (SELECT MAX(id) FROM machine_type WHERE machine_type = ps.machine_type) 
+		WHERE ps.last_seen_ts = (SELECT MAX(last_seen_ts) FROM price_summary) 
+			AND ps.current_spot_hour_price > 0 
+			AND mt.cpu_cores >= ? 
+			AND mt.memory_gb >= ? 
+		ORDER BY ps.current_spot_hour_price ASC`
+
+	candidates := []models.InstanceCandidate{}
+	err := s.querier.QueryRowsNamed("search_cheapest", query, func(rows *sql.Rows) error {
+		var c models.InstanceCandidate
+		if err := rows.Scan(
+			&c.MachineType,
+			&c.Family,
+			&c.RegionName,
+			&c.CpuCores,
+			&c.MemoryGB,
+			&c.HourSpotPrice,
+			&c.HourPrice,
+		); err != nil {
+			return fmt.Errorf("failed to scan candidate: %w", err)
+		}
+		c.Arch = MachineArch(c.Family)
+		if opts.Arch != "" && c.Arch != opts.Arch {
+			return nil
+		}
+		if len(opts.Families) > 0 && !contains(opts.Families, c.Family) {
+			return nil
+		}
+		if !opts.Regions.Matches(c.RegionName) {
+			return nil
+		}
+		c.Continent = RegionContinent(c.RegionName)
+		c.SpotDiscountPct = spotDiscountPct(c.HourSpotPrice, c.HourPrice)
+		c.SpotPricePerVCPU = unitPrice(c.HourSpotPrice, c.CpuCores)
+		c.SpotPricePerGB = unitPrice(c.HourSpotPrice, c.MemoryGB)
+		candidates = append(candidates, c)
+		return nil
+	}, opts.MinCPU, opts.MinMemoryGB)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candidates: %w", err)
+	}
+
+	less := searchRankings[opts.RankBy]
+	sort.SliceStable(candidates, func(i, j int) bool { return less(candidates[i], candidates[j]) })
+	if len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+	}
+	for i := range candidates {
+		candidates[i].Rank = i + 1
+	}
+
+	if opts.StabilityWindow > 0 && len(candidates) > 0 {
+		if err := s.scoreStability(candidates, opts.StabilityWindow); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.CheapestSearchResponse{
+		Candidates: candidates,
+		Count:      len(candidates),
+	}, nil
+}
+
+// scoreStability attaches the volatility metrics over the window to each candidate.
+func (s *PricingService) scoreStability(candidates []models.InstanceCandidate, window time.Duration) error {
+	var filter seriesFilter
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if !seen[c.MachineType] {
+			seen[c.MachineType] = true
+			filter.machineTypes = append(filter.machineTypes, c.MachineType)
+		}
+	}
+
+	stats, err := s.seriesVolatility(filter, window)
+	if err != nil {
+		return err
+	}
+	for i, c := range candidates {
+		candidates[i].Stability = stats[seriesID{c.RegionName, c.MachineType}]
+	}
+	return nil
+}
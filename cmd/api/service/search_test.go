@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestMachineArch(t *testing.T) {
+	tests := []struct {
+		family string
+		want   string
+	}{
+		{"t2a", "arm"},
+		{"c4a", "arm"},
+		{"n4a", "arm"},
+		{"t2d", "x86"},
+		{"c3d", "x86"},
+		{"n2", "x86"},
+		{"a2", "x86"},
+		{"a3", "x86"},
+		{"e2", "x86"},
+		{"a", "x86"},
+		{"", "x86"},
+	}
+	for _, tt := range tests {
+		if got := MachineArch(tt.family); got != tt.want {
+			t.Errorf("MachineArch(%q) = %q, want %q", tt.family, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// machinesAsOfQuery computes the columns of a price_summary listing from the history of a
+// region up to an instant. Arguments: region, instant, region, instant.
+const machinesAsOfQuery = `
+	WITH h AS (
+		SELECT 
+			machine_type, 
+			hour_price, 
+			spot_hour_price, 
+			COALESCE(min_spot_hour_price, spot_hour_price) AS min_spot, 
+			COALESCE(max_spot_hour_price, spot_hour_price) AS max_spot, 
+			updated_ts 
+		FROM pricing_history 
+		WHERE region_name = ? AND updated_ts <= ?
+	), agg AS (
+		SELECT 
+			machine_type, 
+			MIN(min_spot) AS min_spot, 
+			MAX(max_spot) AS max_spot, 
+			AVG(spot_hour_price) AS avg_spot, 
+			MIN(updated_ts) AS first_ts, 
+			MAX(updated_ts) AS last_ts 
+		FROM h 
+		GROUP BY machine_type
+	), last_change AS (
+		SELECT machine_type, change_count, updated_ts, old_spot_hour_price FROM (
+			SELECT 
+				machine_type, 
+				updated_ts, 
+				old_spot_hour_price, 
+				COUNT(*) OVER (PARTITION BY machine_type) AS change_count, 
+				ROW_NUMBER() OVER (PARTITION BY machine_type ORDER BY updated_ts DESC, id DESC) AS rn 
+			FROM price_changes 
+			WHERE region_name = ? AND updated_ts <= ? AND new_spot_hour_price != old_spot_hour_price
+		) WHERE rn = 1
+	)
+	SELECT 
+		agg.machine_type, 
+		agg.min_spot, 
+		agg.max_spot, 
+		agg.avg_spot, 
+		h.spot_hour_price, 
+		h.hour_price, 
+		agg.first_ts, 
+		agg.last_ts, 
+		COALESCE(last_change.change_count, 0), 
+		last_change.updated_ts, 
+		last_change.old_spot_hour_price 
+	FROM agg 
+	JOIN h ON h.machine_type = agg.machine_type AND h.updated_ts = agg.last_ts 
+	LEFT JOIN last_change ON last_change.machine_type = agg.machine_type 
+	ORDER BY agg.machine_type DESC`
+
+// SnapshotFilter optionally restricts a snapshot to a region and/or machine type.
+type SnapshotFilter struct {
+	RegionName  string
+	MachineType string
+}
+
+// GetSnapshot returns the price of every machine type in every region as it was at the
+// given instant: the latest observation at or before it. Filtering on a region or
+// machine type that has never been priced is a NotFoundError.
+func (s *PricingService) GetSnapshot(asOf time.Time, filter SnapshotFilter) (*models.SnapshotResponse, error) {
+	if filter.RegionName != "" || filter.MachineType != "" {
+		if err := s.checkSeries(filter.RegionName, filter.MachineType); err != nil {
+			return nil, err
+		}
+	}
+	result := &models.SnapshotResponse{
+		AsOf:   asOf.UTC(),
+		Prices: []models.SnapshotPrice{},
+	}
+
+	var snapshotTS sql.NullInt64
+	err := s.querier.QueryRowNamed("snapshot_at", "SELECT MAX(updated_ts) FROM pricing_history WHERE updated_ts <= ?", func(row *sql.Row) error {
+		return row.Scan(&snapshotTS)
+	}, asOf.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot: %w", err)
+	}
+	if !snapshotTS.Valid {
+		return result, nil
+	}
+	snapshot := time.Unix(snapshotTS.Int64, 0).UTC()
+	result.SnapshotAt = &snapshot
+
+	// The latest observation of every series at or before the instant, read from the
+	// history itself through the (region_name, updated_ts) index one region at a time.
+	regionCondition := "region_name IN (SELECT DISTINCT region_name FROM pricing_history)"
+	var args []interface{}
+	if filter.RegionName != "" {
+		regionCondition = "region_name = ?"
+		args = append(args, filter.RegionName)
+	}
+	args = append(args, asOf.Unix())
+	query := `
+		WITH latest AS (
+			SELECT region_name, machine_type, MAX(updated_ts) AS updated_ts 
+			FROM pricing_history 
+			WHERE ` + regionCondition + ` AND updated_ts <= ?`
+	if filter.MachineType != "" {
+		query += " AND machine_type = ?"
+		args = append(args, filter.MachineType)
+	}
+	query += `
+			GROUP BY region_name, machine_type
+		)
+		SELECT 
+			p.region_name, 
+			p.machine_type, 
+			p.hour_price, 
+			p.spot_hour_price, 
+			p.updated_ts 
+		FROM latest 
+		JOIN pricing_history p ON p.machine_type = latest.machine_type AND p.region_name = latest.region_name AND p.updated_ts = latest.updated_ts 
+		ORDER BY p.region_name, p.machine_type`
+
+	err = s.querier.QueryRowsNamed("snapshot_prices", query, func(rows *sql.Rows) error {
+		var price models.SnapshotPrice
+		var observedTS int64
+		if err := rows.Scan(&price.RegionName, &price.MachineType, &price.HourPrice, &price.HourSpotPrice, &observedTS); err != nil {
+			return fmt.Errorf("failed to scan snapshot price: %w", err)
+		}
+		price.SpotDiscountPct = spotDiscountPct(price.HourSpotPrice, price.HourPrice)
+		price.ObservedAt = time.Unix(observedTS, 0).UTC()
+		price.InSnapshot = observedTS == snapshotTS.Int64
+		result.Prices = append(result.Prices, price)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot prices: %w", err)
+	}
+	result.Count = len(result.Prices)
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+func TestGetSnapshotFilters(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE price_summary (machine_type varchar(64), region_name varchar(64))",
+		"CREATE TABLE pricing_history (machine_type varchar(64), region_name varchar(64), updated_ts INTEGER)",
+		"INSERT INTO price_summary VALUES ('n2-standard-4', 'europe-west1'), ('c3-standard-8', 'us-east1')",
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pricing := NewPricingService(db.NewQuerier(sqlDB))
+
+	tests := []struct {
+		name         string
+		filter       SnapshotFilter
+		wantNotFound *NotFoundError
+	}{
+		{name: "no filter"},
+		{name: "known region", filter: SnapshotFilter{RegionName: "europe-west1"}},
+		{name: "known series", filter: SnapshotFilter{RegionName: "us-east1", MachineType: "c3-standard-8"}},
+		{
+			name:         "unknown region",
+			filter:       SnapshotFilter{RegionName: "mars-north1"},
+			wantNotFound: &NotFoundError{Resource: "region", Name: "mars-north1"},
+		},
+		{
+			name:         "unknown machine type",
+			filter:       SnapshotFilter{MachineType: "z9-huge"},
+			wantNotFound: &NotFoundError{Resource: "machine type", Name: "z9-huge"},
+		},
+		{
+			name:         "machine type not in region",
+			filter:       SnapshotFilter{RegionName: "us-east1", MachineType: "n2-standard-4"},
+			wantNotFound: &NotFoundError{Resource: "machine type", Name: "n2-standard-4", Region: "us-east1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pricing.GetSnapshot(time.Now(), tt.filter)
+			if tt.wantNotFound == nil {
+				if err != nil {
+					t.Fatalf("GetSnapshot: %v", err)
+				}
+				return
+			}
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || *notFound != *tt.wantNotFound {
+				t.Errorf("GetSnapshot error = %v, want %v", err, tt.wantNotFound)
+			}
+		})
+	}
+}
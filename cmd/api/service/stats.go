@@ -0,0 +1,184 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// DefaultStatsWindow is the volatility window used when none is requested.
+const DefaultStatsWindow = 90 * 24 * time.Hour
+
+// seriesFilter selects the series volatility is computed for. Empty fields match everything.
+type seriesFilter struct {
+	regionName   string
+	machineTypes []string
+	// asOf ends the window at the snapshot in effect at this instant instead of the latest one.
+	asOf time.Time
+}
+
+func (f seriesFilter) appendConditions(query string, args []interface{}) (string, []interface{}) {
+	if f.regionName != "" {
+		query += " AND region_name = ?"
+		args = append(args, f.regionName)
+	}
+	if len(f.machineTypes) > 0 {
+		query += " AND machine_type IN (" + placeholders(len(f.machineTypes)) + ")"
+		for _, machineType := range f.machineTypes {
+			args = append(args, machineType)
+		}
+	}
+	return query, args
+}
+
+type seriesID struct {
+	regionName  string
+	machineType string
+}
+
+// GetMachineStats returns the volatility metrics of a machine type in a region over the window.
+func (s *PricingService) GetMachineStats(regionName, machineType string, window time.Duration) (*models.MachineStats, error) {
+	if err := s.checkSeries(regionName, machineType); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.seriesVolatility(seriesFilter{regionName: regionName, machineTypes: []string{machineType}}, window)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.MachineStats{MachineType: machineType, RegionName: regionName}
+	if st, ok := stats[seriesID{regionName, machineType}]; ok {
+		result.Volatility = *st
+	} else {
+		result.Volatility = emptyVolatility(window)
+	}
+	return result, nil
+}
+
+// seriesVolatility computes volatility metrics for every series matching the filter from
+// daily_prices and price_changes. The window ends on the day of the latest snapshot (or the
+// one in effect at filter.asOf), so metrics stay meaningful for databases that are not
+// updated anymore.
+func (s *PricingService) seriesVolatility(filter seriesFilter, window time.Duration) (map[seriesID]*models.VolatilityStats, error) {
+	windowDays := max(int(math.Ceil(window.Hours()/24)), 1)
+
+	var latestTS sql.NullInt64
+	latestQuery, latestArgs := "SELECT MAX(last_seen_ts) FROM price_summary", []interface{}(nil)
+	if !filter.asOf.IsZero() {
+		latestQuery, latestArgs = "SELECT MAX(updated_ts) FROM pricing_history WHERE updated_ts <= ?", []interface{}{filter.asOf.Unix()}
+	}
+	err := s.querier.QueryRowNamed("volatility_latest", latestQuery, func(row *sql.Row) error {
+		return row.Scan(&latestTS)
+	}, latestArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest snapshot: %w", err)
+	}
+	result := map[seriesID]*models.VolatilityStats{}
+	if !latestTS.Valid {
+		return result, nil
+	}
+	lastDay := time.Unix(latestTS.Int64, 0).UTC().Truncate(24 * time.Hour)
+	firstDay := lastDay.AddDate(0, 0, 1-windowDays)
+
+	prices := map[seriesID][]float64{}
+	query, args := filter.appendConditions(
+		"SELECT region_name, machine_type, spot_hour_price FROM daily_prices WHERE day >= ? AND day <= ?",
+		[]interface{}{firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02")},
+	)
+	err = s.querier.QueryRowsNamed("volatility_daily", query+" ORDER BY region_name, machine_type, day", func(rows *sql.Rows) error {
+		var id seriesID
+		var price float64
+		if err := rows.Scan(&id.regionName, &id.machineType, &price); err != nil {
+			return fmt.Errorf("failed to scan daily price: %w", err)
+		}
+		prices[id] = append(prices[id], price)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily prices: %w", err)
+	}
+
+	changes := map[seriesID][]int64{}
+	query, args = filter.appendConditions(
+		"SELECT region_name, machine_type, updated_ts FROM price_changes WHERE new_spot_hour_price != old_spot_hour_price AND updated_ts >= ? AND updated_ts < ?",
+		[]interface{}{firstDay.Unix(), lastDay.AddDate(0, 0, 1).Unix()},
+	)
+	err = s.querier.QueryRowsNamed("volatility_history", query+" ORDER BY region_name, machine_type, updated_ts", func(rows *sql.Rows) error {
+		var id seriesID
+		var ts int64
+		if err := rows.Scan(&id.regionName, &id.machineType, &ts); err != nil {
+			return fmt.Errorf("failed to scan price change: %w", err)
+		}
+		changes[id] = append(changes[id], ts)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price changes: %w", err)
+	}
+
+	for id, series := range prices {
+		st := volatility(series, changes[id])
+		st.WindowDays = windowDays
+		st.From = firstDay.Format("2006-01-02")
+		st.To = lastDay.Format("2006-01-02")
+		result[id] = st
+	}
+	return result, nil
+}
+
+// volatility computes the metrics of an ascending daily price series and the
+// timestamps of the spot price changes in the same window.
+func volatility(prices []float64, changeTimes []int64) *models.VolatilityStats {
+	st := &models.VolatilityStats{
+		Days:        len(prices),
+		ChangeCount: len(changeTimes),
+	}
+
+	var sum float64
+	peak, trough := prices[0], prices[0]
+	st.MinHourSpotPrice, st.MaxHourSpotPrice = prices[0], prices[0]
+	for _, price := range prices {
+		sum += price
+		st.MinHourSpotPrice = min(st.MinHourSpotPrice, price)
+		st.MaxHourSpotPrice = max(st.MaxHourSpotPrice, price)
+
+		peak, trough = max(peak, price), min(trough, price)
+		if peak > 0 {
+			st.MaxDrawdownPct = max(st.MaxDrawdownPct, (peak-price)/peak*100)
+		}
+		if trough > 0 {
+			st.MaxSpikePct = max(st.MaxSpikePct, (price-trough)/trough*100)
+		}
+	}
+	mean := sum / float64(len(prices))
+
+	var squares float64
+	for _, price := range prices {
+		squares += (price - mean) * (price - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(len(prices)))
+
+	st.MeanHourSpotPrice = roundUnitPrice(mean)
+	st.StdDev = roundUnitPrice(stdDev)
+	if mean > 0 {
+		st.CoefficientOfVariation = math.Round(stdDev/mean*10000) / 10000
+	}
+	st.StabilityScore = math.Round(1/(1+10*st.CoefficientOfVariation)*1000) / 1000
+	st.MaxDrawdownPct = math.Round(st.MaxDrawdownPct*100) / 100
+	st.MaxSpikePct = math.Round(st.MaxSpikePct*100) / 100
+
+	if len(changeTimes) >= 2 {
+		hours := float64(changeTimes[len(changeTimes)-1]-changeTimes[0]) / 3600 / float64(len(changeTimes)-1)
+		hours = math.Round(hours*10) / 10
+		st.MeanHoursBetweenChanges = &hours
+	}
+	return st
+}
+
+// emptyVolatility describes a series without prices in the window.
+func emptyVolatility(window time.Duration) models.VolatilityStats {
+	return models.VolatilityStats{WindowDays: max(int(math.Ceil(window.Hours()/24)), 1)}
+}
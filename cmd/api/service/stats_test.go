@@ -0,0 +1,87 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+func TestVolatility(t *testing.T) {
+	hours := func(h float64) *float64 { return &h }
+
+	tests := []struct {
+		name        string
+		prices      []float64
+		changeTimes []int64
+		want        models.VolatilityStats
+	}{
+		{
+			// Mean 2.5, variance (0.25 + 2.25 + 2.25 + 0.25) / 4 = 1.25, so the standard
+			// deviation is sqrt(1.25) and the coefficient of variation sqrt(1.25) / 2.5.
+			// The price falls from 4 to 1 (75%) and rises from 1 to 3 (200%).
+			name:        "volatile",
+			prices:      []float64{2, 4, 1, 3},
+			changeTimes: []int64{0, 7200, 18000},
+			want: models.VolatilityStats{
+				Days:                    4,
+				MeanHourSpotPrice:       2.5,
+				MinHourSpotPrice:        1,
+				MaxHourSpotPrice:        4,
+				StdDev:                  1.118034,
+				CoefficientOfVariation:  0.4472,
+				ChangeCount:             3,
+				MeanHoursBetweenChanges: hours(2.5),
+				MaxDrawdownPct:          75,
+				MaxSpikePct:             200,
+				StabilityScore:          0.183,
+			},
+		},
+		{
+			name:        "constant",
+			prices:      []float64{0.5, 0.5, 0.5},
+			changeTimes: []int64{3600},
+			want: models.VolatilityStats{
+				Days:              3,
+				MeanHourSpotPrice: 0.5,
+				MinHourSpotPrice:  0.5,
+				MaxHourSpotPrice:  0.5,
+				ChangeCount:       1,
+				StabilityScore:    1,
+			},
+		},
+		{
+			// A zero price is no base for percentages: rising from it is no spike.
+			name:   "from zero",
+			prices: []float64{0, 1},
+			want: models.VolatilityStats{
+				Days:                   2,
+				MeanHourSpotPrice:      0.5,
+				MaxHourSpotPrice:       1,
+				StdDev:                 0.5,
+				CoefficientOfVariation: 1,
+				StabilityScore:         0.091,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := volatility(tt.prices, tt.changeTimes); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("volatility = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyVolatility(t *testing.T) {
+	for window, want := range map[time.Duration]int{
+		90 * 24 * time.Hour: 90,
+		36 * time.Hour:      2,
+		time.Hour:           1,
+	} {
+		if got := emptyVolatility(window).WindowDays; got != want {
+			t.Errorf("emptyVolatility(%v).WindowDays = %d, want %d", window, got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+)
+
+// machineSpec is the vCPU count and memory size of a machine type.
+type machineSpec struct {
+	cpuCores float64
+	memoryGB float64
+}
+
+// unitPrices normalizes a spot and on-demand price by the machine's specification.
+func (spec machineSpec) unitPrices(spotPrice, onDemandPrice float64) *models.UnitPrices {
+	return &models.UnitPrices{
+		SpotPerVCPU:     unitPrice(spotPrice, spec.cpuCores),
+		SpotPerGB:       unitPrice(spotPrice, spec.memoryGB),
+		OnDemandPerVCPU: unitPrice(onDemandPrice, spec.cpuCores),
+		OnDemandPerGB:   unitPrice(onDemandPrice, spec.memoryGB),
+	}
+}
+
+// machineSpecs returns the current specification of the given machine types, or of all
+// machine types if none are given. machine_type keeps a row per distinct specification
+// a machine type was seen with; the latest one wins.
+func (s *PricingService) machineSpecs(machineTypes ...string) (map[string]machineSpec, error) {
+	query := `
+		SELECT machine_type, cpu_cores, memory_gb 
+		FROM machine_type 
+		WHERE id IN (SELECT MAX(id) FROM machine_type GROUP BY machine_type)`
+	var args []interface{}
+	if len(machineTypes) > 0 {
+		query += " AND machine_type IN (" + placeholders(len(machineTypes)) + ")"
+		for _, machineType := range machineTypes {
+			args = append(args, machineType)
+		}
+	}
+
+	specs := map[string]machineSpec{}
+	err := s.querier.QueryRowsNamed("machine_specs", query, func(rows *sql.Rows) error {
+		var machineType string
+		var spec machineSpec
+		if err := rows.Scan(&machineType, &spec.cpuCores, &spec.memoryGB); err != nil {
+			return fmt.Errorf("failed to scan machine spec: %w", err)
+		}
+		specs[machineType] = spec
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query machine specs: %w", err)
+	}
+	return specs, nil
+}
+
+// GetMachineSpecs returns the vCPU count and memory size of the given machine types, or
+// of all machine types if none are given. Unknown machine types are left out.
+func (s *PricingService) GetMachineSpecs(machineTypes ...string) (map[string]models.MachineSpec, error) {
+	specs, err := s.machineSpecs(machineTypes...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]models.MachineSpec, len(specs))
+	for machineType, spec := range specs {
+		result[machineType] = models.MachineSpec{MachineType: machineType, CpuCores: spec.cpuCores, MemoryGB: spec.memoryGB}
+	}
+	return result, nil
+}
+
+// UnitPriceOptions selects the families, regions and period of family unit price series.
+type UnitPriceOptions struct {
+	Families []string
+	Regions  RegionFilter
+	// From and To are optional YYYY-MM-DD bounds (inclusive).
+	From string
+	To   string
+	// Resolution is day (default), week or month.
+	Resolution string
+}
+
+// Validate checks the options and fills in defaults.
+func (o *UnitPriceOptions) Validate() error {
+	if len(o.Families) == 0 {
+		return invalidParameter("family", "at least one family is required")
+	}
+	if o.Resolution == "" {
+		o.Resolution = "day"
+	}
+	if _, ok := familyBucketExpr[o.Resolution]; !ok {
+		return invalidParameter("resolution", "unknown resolution %q, expected day, week or month", o.Resolution)
+	}
+	return o.Regions.Validate()
+}
+
+// SQL expressions mapping daily_prices.day to the first day of its period. Weeks start on Monday.
+var familyBucketExpr = map[string]string{
+	"day":   "dp.day",
+	"week":  "date(dp.day, '-6 days', 'weekday 1')",
+	"month": "substr(dp.day, 1, 7) || '-01'",
+}
+
+// GetFamilyUnitPrices aggregates daily_prices into per family series of prices per vCPU and
+// per GB of memory, averaged over all machine types and regions of the family.
+func (s *PricingService) GetFamilyUnitPrices(opts UnitPriceOptions) (*models.FamilyUnitPriceResponse, error) {
+	result := &models.FamilyUnitPriceResponse{
+		Resolution: opts.Resolution,
+		From:       opts.From,
+		To:         opts.To,
+		Families:   []models.FamilyUnitPriceSeries{},
+	}
+
+	// Continents are derived from region names, so resolve the filter to a region list first.
+	var regions []string
+	if len(opts.Regions.Continents) > 0 || len(opts.Regions.Regions) > 0 {
+		all, err := s.GetAllRegions()
+		if err != nil {
+			return nil, err
+		}
+		for _, region := range all {
+			if opts.Regions.Matches(region) {
+				regions = append(regions, region)
+			}
+		}
+		if len(regions) == 0 {
+			return result, nil
+		}
+	}
+
+	query := `
+		SELECT 
+			mt.family, 
+			` + familyBucketExpr[opts.Resolution] + ` AS bucket, 
+			AVG(dp.spot_hour_price / mt.cpu_cores), 
+			MIN(dp.spot_hour_price / mt.cpu_cores), 
+			MAX(dp.spot_hour_price / mt.cpu_cores), 
+			AVG(dp.spot_hour_price / mt.memory_gb), 
+			AVG(dp.hour_price / mt.cpu_cores), 
+			AVG(dp.hour_price / mt.memory_gb), 
+			COUNT(DISTINCT dp.region_name || '/' || dp.machine_type) 
+		FROM daily_prices dp 
+		JOIN machine_type mt ON mt.id = (SELECT MAX(id) FROM machine_type WHERE machine_type = dp.machine_type) 
+		WHERE mt.cpu_cores > 0 AND mt.memory_gb > 0 
+			AND mt.family IN (` + placeholders(len(opts.Families)) + `)`
+	var args []interface{}
+	for _, family := range opts.Families {
+		args = append(args, family)
+	}
+	if len(regions) > 0 {
+		query += " AND dp.region_name IN (" + placeholders(len(regions)) + ")"
+		for _, region := range regions {
+			args = append(args, region)
+		}
+	}
+	if opts.From != "" {
+		query += " AND dp.day >= ?"
+		args = append(args, opts.From)
+	}
+	if opts.To != "" {
+		query += " AND dp.day <= ?"
+		args = append(args, opts.To)
+	}
+	query += " GROUP BY mt.family, bucket ORDER BY mt.family, bucket"
+
+	series := map[string]*models.FamilyUnitPriceSeries{}
+	err := s.querier.QueryRowsNamed("family_unit_prices", query, func(rows *sql.Rows) error {
+		var family string
+		var point models.FamilyUnitPricePoint
+		if err := rows.Scan(
+			&family,
+			&point.Date,
+			&point.SpotPerVCPU,
+			&point.MinSpotPerVCPU,
+			&point.MaxSpotPerVCPU,
+			&point.SpotPerGB,
+			&point.OnDemandPerVCPU,
+			&point.OnDemandPerGB,
+			&point.Series,
+		); err != nil {
+			return fmt.Errorf("failed to scan unit price: %w", err)
+		}
+		point.SpotPerVCPU = roundUnitPrice(point.SpotPerVCPU)
+		point.MinSpotPerVCPU = roundUnitPrice(point.MinSpotPerVCPU)
+		point.MaxSpotPerVCPU = roundUnitPrice(point.MaxSpotPerVCPU)
+		point.SpotPerGB = roundUnitPrice(point.SpotPerGB)
+		point.OnDemandPerVCPU = roundUnitPrice(point.OnDemandPerVCPU)
+		point.OnDemandPerGB = roundUnitPrice(point.OnDemandPerGB)
+
+		if series[family] == nil {
+			series[family] = &models.FamilyUnitPriceSeries{Family: family, Points: []models.FamilyUnitPricePoint{}}
+		}
+		series[family].Points = append(series[family].Points, point)
+		return nil
+	}, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query family unit prices: %w", err)
+	}
+
+	// Keep the requested order; families without data get an empty series.
+	for _, family := range opts.Families {
+		if series[family] == nil {
+			series[family] = &models.FamilyUnitPriceSeries{Family: family, Points: []models.FamilyUnitPricePoint{}}
+		}
+		result.Families = append(result.Families, *series[family])
+		delete(series, family)
+	}
+	return result, nil
+}
+
+// unitPrice divides a price by a number of units (vCPUs or GB), 0 if the unit count is unknown.
+func unitPrice(price, units float64) float64 {
+	if units <= 0 {
+		return 0
+	}
+	return roundUnitPrice(price / units)
+}
+
+func roundUnitPrice(price float64) float64 {
+	return math.Round(price*1e6) / 1e6
+}
+
+// placeholders returns n comma separated SQL placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DataVersion identifies the state of the imported data. Prices only change when
+// dataprocessing imports or compacts history, so responses can be cached until it changes.
+type DataVersion struct {
+	// SnapshotTS is the newest snapshot timestamp.
+	SnapshotTS int64
+	// RunID and RunFinishedTS identify the latest ingestion run; RunFinishedTS is 0 while it
+	// is running, so the version changes again once the derived tables are refreshed.
+	RunID         int64
+	RunFinishedTS int64
+	// CompactionID is the latest compaction_log entry.
+	CompactionID int64
+	// ModifiedAt is when the data last changed.
+	ModifiedAt time.Time
+}
+
+// ETag returns the version as a weak entity tag. Responses are byte-for-byte identical for
+// a version, but JSON and HTML routes share it, so it is not a strong validator.
+func (v DataVersion) ETag() string {
+	return fmt.Sprintf(`W/"%d-%d-%d-%d"`, v.SnapshotTS, v.RunID, v.RunFinishedTS, v.CompactionID)
+}
+
+// GetDataVersion returns the current version of the imported data.
+func (s *PricingService) GetDataVersion() (DataVersion, error) {
+	var v DataVersion
+	var runStartedTS, compactedTS int64
+	err := s.querier.QueryRowNamed("data_version", `
+		SELECT
+			(SELECT COALESCE(MAX(updated_ts), 0) FROM pricing_history),
+			COALESCE(r.id, 0),
+			COALESCE(r.started_at, 0),
+			COALESCE(r.finished_at, 0),
+			COALESCE(c.id, 0),
+			COALESCE(c.compacted_at, 0)
+		FROM (SELECT 1)
+		LEFT JOIN (SELECT id, started_at, finished_at FROM ingestion_runs ORDER BY id DESC LIMIT 1) r
+		LEFT JOIN (SELECT id, compacted_at FROM compaction_log ORDER BY id DESC LIMIT 1) c`, func(row *sql.Row) error {
+		return row.Scan(&v.SnapshotTS, &v.RunID, &runStartedTS, &v.RunFinishedTS, &v.CompactionID, &compactedTS)
+	})
+	if err != nil {
+		return DataVersion{}, fmt.Errorf("failed to query data version: %w", err)
+	}
+	v.ModifiedAt = time.Unix(max(v.SnapshotTS, runStartedTS, v.RunFinishedTS, compactedTS), 0).UTC()
+	return v, nil
+}
+
+// CurrentDataVersion is GetDataVersion for callers that treat an unreadable version as
+// unknown, such as the response cache.
+func (s *PricingService) CurrentDataVersion() (DataVersion, bool) {
+	v, err := s.GetDataVersion()
+	return v, err == nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/models"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+// keepaliveInterval is how often an idle stream sends a comment, so that proxies do not
+// close the connection. It is kept well below common proxy idle timeouts.
+var keepaliveInterval = 15 * time.Second
+
+// replayPageSize is the number of missed changes read per query on resume.
+const replayPageSize = 500
+
+// changeStreamHandler streams new price changes as server-sent events. Each change is
+// sent with its ID so that a reconnecting client resumes after it with Last-Event-ID.
+func changeStreamHandler(pricing *service.PricingService, feed *service.ChangeFeed) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := service.StreamFilter{
+			Regions:  listParam(query["regions"]),
+			Families: listParam(query["families"]),
+		}
+		var err error
+		if filter.MinChangePct, err = floatParam("min_change_pct", query.Get("min_change_pct")); err != nil {
+			sendError(w, r, badRequest(err))
+			return
+		}
+		if err := filter.Validate(); err != nil {
+			sendError(w, r, badRequest(err))
+			return
+		}
+		lastID, err := lastEventID(r)
+		if err != nil {
+			sendError(w, r, badRequest(err))
+			return
+		}
+
+		// Subscribe before catching up so that no change falls between the two; changes
+		// seen in both are skipped by ID.
+		events, cancel := feed.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		// The server's write timeout is meant for ordinary responses; a stream stays open
+		// until the client goes away.
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		if lastID > 0 {
+			for {
+				changes, err := pricing.GetChangesSince(lastID, replayPageSize)
+				if err != nil {
+					slog.Error("failed to replay price changes", "error", err)
+					return
+				}
+				for _, change := range changes {
+					if filter.Matches(change) {
+						if err := writeEvent(w, strconv.FormatInt(change.ID, 10), "change", change); err != nil {
+							return
+						}
+					}
+					lastID = change.ID
+				}
+				if err := rc.Flush(); err != nil {
+					return
+				}
+				if len(changes) < replayPageSize {
+					break
+				}
+			}
+		}
+
+		keepalive := time.NewTicker(keepaliveInterval)
+		defer keepalive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					// Dropped for falling behind; the client reconnects with Last-Event-ID.
+					return
+				}
+				switch {
+				case event.Change != nil:
+					if event.Change.ID <= lastID || !filter.Matches(*event.Change) {
+						continue
+					}
+					lastID = event.Change.ID
+					err = writeEvent(w, strconv.FormatInt(lastID, 10), "change", event.Change)
+				default:
+					err = writeEvent(w, "", "snapshot", models.SnapshotEvent{SnapshotAt: event.SnapshotAt})
+				}
+				if err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID returns the ID of the last change a client received, from the
+// Last-Event-ID header sent by EventSource on reconnect or the last_event_id query
+// parameter, 0 if there is none.
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("last event ID must be a change ID")
+	}
+	return id, nil
+}
+
+// writeEvent writes a server-sent event with a JSON payload; id may be empty.
+func writeEvent(w http.ResponseWriter, id, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
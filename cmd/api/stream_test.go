@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/cmd/api/service"
+)
+
+func TestChangeStreamOutlivesWriteTimeout(t *testing.T) {
+	defer func(interval time.Duration) { keepaliveInterval = interval }(keepaliveInterval)
+	keepaliveInterval = 50 * time.Millisecond
+
+	const writeTimeout = 200 * time.Millisecond
+	feed := service.NewChangeFeed(nil, time.Hour)
+	server := httptest.NewUnstartedServer(changeStreamHandler(nil, feed))
+	server.Config.WriteTimeout = writeTimeout
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Keepalives written after the server's write timeout still arrive.
+	start := time.Now()
+	lines := bufio.NewScanner(resp.Body)
+	for time.Since(start) < 3*writeTimeout {
+		if !lines.Scan() {
+			t.Fatalf("stream closed after %v: %v", time.Since(start), lines.Err())
+		}
+		if line := lines.Text(); line != "" && !strings.HasPrefix(line, ": keepalive") {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
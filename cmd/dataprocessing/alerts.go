@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/regions"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/schema"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/webhook"
+)
+
+// Alert conditions stored in alert_rules.condition. above and below fire when a price
+// crosses the threshold; change_pct fires when it changes by at least threshold percent.
+const (
+	conditionAbove     = "above"
+	conditionBelow     = "below"
+	conditionChangePct = "change_pct"
+)
+
+// Delivery statuses stored in alert_deliveries.status.
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+)
+
+const (
+	// maxAlertEvents caps the price changes listed in one notification; event_count is exact.
+	maxAlertEvents = 100
+	// maxDeliveryAttempts is how often a notification is sent before it is marked failed.
+	maxDeliveryAttempts = 4
+	// signatureHeader carries the hex HMAC-SHA256 of the body, keyed with the rule secret.
+	signatureHeader = "X-Signature-256"
+)
+
+// deliveryBackoff is the wait before the first retry; it doubles for every further retry.
+var deliveryBackoff = time.Second
+
+// webhookClient delivers notifications, refusing addresses that are not public unless
+// -allow-private-webhooks is set.
+var webhookClient = newWebhookClient(webhook.Control)
+
+func newWebhookClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if control != nil {
+		// A proxy would be dialed instead of the webhook host, so it is not used
+		transport.Proxy = nil
+	}
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, Control: control}).DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// initAlertTables creates the alert tables, which the API creates as well.
+func initAlertTables(client *sql.DB) {
+	if err := schema.InitAlertTables(client); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+}
+
+// alertRule is an enabled alert_rules row. Empty scope fields match every series.
+type alertRule struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	MachineType string  `json:"machine_type,omitempty"`
+	Family      string  `json:"family,omitempty"`
+	RegionName  string  `json:"region_name,omitempty"`
+	Continent   string  `json:"continent,omitempty"`
+	Price       string  `json:"price"`
+	Condition   string  `json:"condition"`
+	Threshold   float64 `json:"threshold"`
+	webhookURL  string
+	secret      string
+}
+
+// alertEvent is a price change that triggered a rule.
+type alertEvent struct {
+	ChangeID    int64     `json:"change_id"`
+	MachineType string    `json:"machine_type"`
+	Family      string    `json:"family"`
+	RegionName  string    `json:"region_name"`
+	Timestamp   time.Time `json:"timestamp"`
+	OldPrice    float64   `json:"old_price"`
+	NewPrice    float64   `json:"new_price"`
+	ChangePct   float64   `json:"change_pct"`
+}
+
+// alertPayload is the JSON body of a webhook notification: the price changes of one
+// import that triggered one rule.
+type alertPayload struct {
+	DeliveryID     int64        `json:"delivery_id"`
+	IngestionRunID int64        `json:"ingestion_run_id"`
+	TriggeredAt    time.Time    `json:"triggered_at"`
+	Rule           alertRule    `json:"rule"`
+	EventCount     int          `json:"event_count"`
+	Events         []alertEvent `json:"events"`
+}
+
+// match reports whether a price change triggers the rule, and the event it triggers.
+func (r alertRule) match(c priceChangeRow) (alertEvent, bool) {
+	if (r.MachineType != "" && r.MachineType != c.machineType) ||
+		(r.Family != "" && r.Family != c.family) ||
+		(r.RegionName != "" && r.RegionName != c.regionName) ||
+		(r.Continent != "" && r.Continent != regions.Continent(c.regionName)) {
+		return alertEvent{}, false
+	}
+
+	oldPrice, newPrice := c.oldSpotPrice, c.newSpotPrice
+	if r.Price == "on_demand" {
+		oldPrice, newPrice = c.oldHourPrice, c.newHourPrice
+	}
+	var changePct float64
+	if oldPrice > 0 {
+		changePct = math.Round((newPrice-oldPrice)/oldPrice*10000) / 100
+	}
+
+	var triggered bool
+	switch r.Condition {
+	case conditionAbove:
+		triggered = oldPrice <= r.Threshold && newPrice > r.Threshold
+	case conditionBelow:
+		triggered = oldPrice >= r.Threshold && newPrice < r.Threshold
+	case conditionChangePct:
+		triggered = oldPrice > 0 && math.Abs(changePct) >= r.Threshold
+	}
+	if !triggered {
+		return alertEvent{}, false
+	}
+	return alertEvent{
+		ChangeID:    c.id,
+		MachineType: c.machineType,
+		Family:      c.family,
+		RegionName:  c.regionName,
+		Timestamp:   time.Unix(c.ts, 0).UTC(),
+		OldPrice:    oldPrice,
+		NewPrice:    newPrice,
+		ChangePct:   changePct,
+	}, true
+}
+
+// priceChangeRow is a price_changes row read back for alert evaluation.
+type priceChangeRow struct {
+	id           int64
+	machineType  string
+	family       string
+	regionName   string
+	ts           int64
+	oldHourPrice float64
+	newHourPrice float64
+	oldSpotPrice float64
+	newSpotPrice float64
+}
+
+// evaluateAlerts matches the price changes recorded by the run against the enabled alert
+// rules and notifies each triggered rule's webhook once. Changes that a replay of a series
+// kept or rewrote were recorded by earlier runs and do not notify again.
+func evaluateAlerts(db *sql.DB, run *IngestionRun) {
+	rules, err := loadAlertRules(db)
+	if err != nil {
+		run.Errorf("loading alert rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	events := make([][]alertEvent, len(rules))
+	counts := make([]int, len(rules))
+	rows, err := db.Query(`SELECT
+		id, machine_type, family, region_name, updated_ts,
+		old_hour_price, new_hour_price, old_spot_hour_price, new_spot_hour_price
+		FROM price_changes WHERE ingestion_run_id = ? ORDER BY id`, run.ID)
+	if err != nil {
+		run.Errorf("reading price changes for alerts: %v", err)
+		return
+	}
+	for rows.Next() {
+		var c priceChangeRow
+		if err := rows.Scan(&c.id, &c.machineType, &c.family, &c.regionName, &c.ts,
+			&c.oldHourPrice, &c.newHourPrice, &c.oldSpotPrice, &c.newSpotPrice); err != nil {
+			rows.Close()
+			run.Errorf("reading price changes for alerts: %v", err)
+			return
+		}
+		for i, rule := range rules {
+			if event, ok := rule.match(c); ok {
+				counts[i]++
+				if len(events[i]) < maxAlertEvents {
+					events[i] = append(events[i], event)
+				}
+			}
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		run.Errorf("reading price changes for alerts: %v", err)
+		return
+	}
+
+	start := time.Now()
+	for i, rule := range rules {
+		if counts[i] == 0 {
+			continue
+		}
+		run.AlertsTriggered++
+		payload := alertPayload{
+			IngestionRunID: run.ID,
+			TriggeredAt:    time.Now().UTC(),
+			Rule:           rule,
+			EventCount:     counts[i],
+			Events:         events[i],
+		}
+		if err := deliverAlert(db, rule, payload); err != nil {
+			run.AlertDeliveriesFailed++
+			run.Warnf("alert rule %d: %v", rule.ID, err)
+		}
+	}
+	if run.AlertsTriggered > 0 {
+		fmt.Printf("Sent %d alert notifications (%d failed) in %v\n", run.AlertsTriggered, run.AlertDeliveriesFailed, time.Since(start))
+	}
+}
+
+func loadAlertRules(db *sql.DB) ([]alertRule, error) {
+	rows, err := db.Query(`SELECT
+		id, COALESCE(name, ''), COALESCE(machine_type, ''), COALESCE(family, ''),
+		COALESCE(region_name, ''), COALESCE(continent, ''),
+		price, condition, threshold, webhook_url, COALESCE(secret, '')
+		FROM alert_rules WHERE enabled = 1 ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []alertRule
+	for rows.Next() {
+		var r alertRule
+		if err := rows.Scan(&r.ID, &r.Name, &r.MachineType, &r.Family, &r.RegionName, &r.Continent,
+			&r.Price, &r.Condition, &r.Threshold, &r.webhookURL, &r.secret); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// deliverAlert logs a notification in alert_deliveries and posts it to the rule's
+// webhook, retrying with exponential backoff on network errors, 429 and 5xx responses.
+func deliverAlert(db *sql.DB, rule alertRule, payload alertPayload) error {
+	res, err := db.Exec(
+		"INSERT INTO alert_deliveries (rule_id, ingestion_run_id, created_at, status, event_count) VALUES (?, ?, ?, ?, ?)",
+		rule.ID, payload.IngestionRunID, payload.TriggeredAt.Unix(), deliveryPending, payload.EventCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	if payload.DeliveryID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to read delivery id: %w", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(rule.secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	backoff := deliveryBackoff
+	var lastErr error
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		responseStatus, retry, err := postWebhook(rule.webhookURL, body, signature, payload.DeliveryID)
+		status := deliveryDelivered
+		if err != nil {
+			status = deliveryPending
+			if !retry || attempt == maxDeliveryAttempts {
+				status = deliveryFailed
+			}
+		}
+		var errText sql.NullString
+		if err != nil {
+			errText = sql.NullString{String: err.Error(), Valid: true}
+		}
+		if _, dbErr := db.Exec(`UPDATE alert_deliveries SET
+			status = ?, attempts = ?, last_attempt_at = ?, response_status = ?, error = ?, payload = ?
+			WHERE id = ?`,
+			status, attempt, time.Now().Unix(), sql.NullInt64{Int64: int64(responseStatus), Valid: responseStatus != 0},
+			errText, string(body), payload.DeliveryID,
+		); dbErr != nil {
+			return fmt.Errorf("failed to update delivery %d: %w", payload.DeliveryID, dbErr)
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if status == deliveryFailed {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return fmt.Errorf("delivery %d failed: %w", payload.DeliveryID, lastErr)
+}
+
+// postWebhook sends one delivery attempt. It returns the response status, 0 if there was
+// no response, and whether a failed attempt is worth retrying.
+func postWebhook(url string, body []byte, signature string, deliveryID int64) (int, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "google-cloud-spot-price-history-alerts")
+	req.Header.Set(signatureHeader, signature)
+	req.Header.Set("X-Delivery-ID", fmt.Sprint(deliveryID))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, !errors.Is(err, webhook.ErrNotPublic), err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("webhook responded %s", resp.Status)
+}
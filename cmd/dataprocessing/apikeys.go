@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/apikey"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/schema"
+)
+
+// Defaults for new API keys. A rate of 0 or a quota of 0 means unlimited.
+const (
+	defaultKeyRate  = 10
+	defaultKeyBurst = 20
+	defaultKeyQuota = 10000
+)
+
+// initAPIKeyTables creates the API key tables, which the API creates as well.
+func initAPIKeyTables(client *sql.DB) {
+	if err := schema.InitAPIKeyTables(client); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+}
+
+const apiKeyUsage = `Usage: dataprocessing apikey <command> [flags]
+
+Commands:
+  create   Create a key and print it; it cannot be shown again
+  list     List keys with today's usage
+  update   Change the limits or admin flag of a key
+  revoke   Revoke a key
+  usage    Show requests per key and day
+`
+
+func runAPIKey(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, apiKeyUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "create":
+		runAPIKeyCreate(args[1:])
+	case "list":
+		runAPIKeyList(args[1:])
+	case "update":
+		runAPIKeyUpdate(args[1:])
+	case "revoke":
+		runAPIKeyRevoke(args[1:])
+	case "usage":
+		runAPIKeyUsage(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown apikey command %q\n\n%s", args[0], apiKeyUsage)
+		os.Exit(2)
+	}
+}
+
+// openAPIKeyDB opens the database of an apikey command, creating the tables if needed.
+func openAPIKeyDB(path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Fatalf("failed opening connection to sqlite: %v", err)
+	}
+	initDatabase(context.Background(), db)
+	return db
+}
+
+// keyLimits are the limits of a key, as set on the command line.
+type keyLimits struct {
+	rate  float64
+	burst int
+	quota int64
+}
+
+func (l keyLimits) validate() error {
+	if l.rate < 0 || l.burst < 0 || l.quota < 0 {
+		return fmt.Errorf("rate, burst and quota must not be negative")
+	}
+	if l.rate > 0 && l.burst < 1 {
+		return fmt.Errorf("burst must be at least 1 when rate is limited")
+	}
+	return nil
+}
+
+func runAPIKeyCreate(args []string) {
+	fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database")
+	name := fs.String("name", "", "Name of the key, e.g. the team using it")
+	rate := fs.Float64("rate", defaultKeyRate, "Sustained requests per second, 0 for unlimited")
+	burst := fs.Int("burst", defaultKeyBurst, "Requests allowed at once before the rate applies")
+	quota := fs.Int64("quota", defaultKeyQuota, "Requests per UTC day, 0 for unlimited")
+	admin := fs.Bool("admin", false, "Allow the key to manage alert rules")
+	fs.Parse(args)
+
+	if *name == "" {
+		log.Fatal("-name is required")
+	}
+	limits := keyLimits{rate: *rate, burst: *burst, quota: *quota}
+	if err := limits.validate(); err != nil {
+		log.Fatalf("Invalid limits: %v", err)
+	}
+
+	db := openAPIKeyDB(*databasePath)
+	defer db.Close()
+
+	key, err := apikey.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := db.Exec(`INSERT INTO api_keys (name, prefix, key_hash, rate_limit, burst, daily_quota, admin, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		*name, apikey.Display(key), apikey.Hash(key), limits.rate, limits.burst, limits.quota, *admin, time.Now().Unix())
+	if err != nil {
+		log.Fatalf("Failed to store API key: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Fatalf("Failed to read API key ID: %v", err)
+	}
+
+	fmt.Printf("Created API key %d (%s). Store it now, it cannot be shown again:\n%s\n", id, *name, key)
+}
+
+func runAPIKeyList(args []string) {
+	fs := flag.NewFlagSet("apikey list", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database")
+	showRevoked := fs.Bool("revoked", false, "Include revoked keys")
+	fs.Parse(args)
+
+	db := openAPIKeyDB(*databasePath)
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT k.id, k.name, COALESCE(k.prefix, ''), k.rate_limit, k.burst, k.daily_quota, k.admin, k.created_at,
+			COALESCE(k.revoked_at, 0), COALESCE(u.requests, 0), COALESCE(u.rejected, 0)
+		FROM api_keys k
+		LEFT JOIN api_key_usage u ON u.key_id = k.id AND u.day = ?
+		WHERE ? OR k.revoked_at IS NULL
+		ORDER BY k.id`, time.Now().UTC().Format(time.DateOnly), *showRevoked)
+	if err != nil {
+		log.Fatalf("Failed to query API keys: %v", err)
+	}
+	defer rows.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tKEY\tRATE/S\tBURST\tQUOTA/DAY\tADMIN\tTODAY\tREJECTED\tCREATED\tREVOKED")
+	for rows.Next() {
+		var (
+			id, quota, createdAt, revokedAt, requests, rejected int64
+			name, prefix                                        string
+			rate                                                float64
+			burst                                               int
+			admin                                               bool
+		)
+		if err := rows.Scan(&id, &name, &prefix, &rate, &burst, &quota, &admin, &createdAt, &revokedAt, &requests, &rejected); err != nil {
+			log.Fatalf("Failed to scan API key: %v", err)
+		}
+		revoked := "-"
+		if revokedAt > 0 {
+			revoked = time.Unix(revokedAt, 0).UTC().Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s...\t%s\t%d\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			id, name, prefix, formatLimit(rate), burst, formatLimit(float64(quota)), formatAdmin(admin),
+			requests, rejected, time.Unix(createdAt, 0).UTC().Format(time.DateOnly), revoked)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Failed to read API keys: %v", err)
+	}
+	w.Flush()
+}
+
+func formatLimit(v float64) string {
+	if v == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+func formatAdmin(admin bool) string {
+	if admin {
+		return "yes"
+	}
+	return "no"
+}
+
+func runAPIKeyUpdate(args []string) {
+	fs := flag.NewFlagSet("apikey update", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database")
+	id := fs.Int64("id", 0, "ID of the key to update")
+	rate := fs.Float64("rate", 0, "Sustained requests per second, 0 for unlimited")
+	burst := fs.Int("burst", 0, "Requests allowed at once before the rate applies")
+	quota := fs.Int64("quota", 0, "Requests per UTC day, 0 for unlimited")
+	admin := fs.Bool("admin", false, "Allow the key to manage alert rules")
+	fs.Parse(args)
+
+	if *id == 0 {
+		log.Fatal("-id is required")
+	}
+
+	db := openAPIKeyDB(*databasePath)
+	defer db.Close()
+
+	// Flags that are not given keep their stored value
+	var limits keyLimits
+	var isAdmin bool
+	err := db.QueryRow("SELECT rate_limit, burst, daily_quota, admin FROM api_keys WHERE id = ?", *id).
+		Scan(&limits.rate, &limits.burst, &limits.quota, &isAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Fatalf("API key %d not found", *id)
+	}
+	if err != nil {
+		log.Fatalf("Failed to query API key %d: %v", *id, err)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rate":
+			limits.rate = *rate
+		case "burst":
+			limits.burst = *burst
+		case "quota":
+			limits.quota = *quota
+		case "admin":
+			isAdmin = *admin
+		}
+	})
+	if err := limits.validate(); err != nil {
+		log.Fatalf("Invalid limits: %v", err)
+	}
+
+	if _, err := db.Exec("UPDATE api_keys SET rate_limit = ?, burst = ?, daily_quota = ?, admin = ? WHERE id = ?",
+		limits.rate, limits.burst, limits.quota, isAdmin, *id); err != nil {
+		log.Fatalf("Failed to update API key %d: %v", *id, err)
+	}
+	fmt.Printf("Updated API key %d: rate %s, burst %d, daily quota %s, admin %s\n",
+		*id, formatLimit(limits.rate), limits.burst, formatLimit(float64(limits.quota)), formatAdmin(isAdmin))
+}
+
+func runAPIKeyRevoke(args []string) {
+	fs := flag.NewFlagSet("apikey revoke", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database")
+	id := fs.Int64("id", 0, "ID of the key to revoke")
+	fs.Parse(args)
+
+	if *id == 0 {
+		log.Fatal("-id is required")
+	}
+
+	db := openAPIKeyDB(*databasePath)
+	defer db.Close()
+
+	res, err := db.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().Unix(), *id)
+	if err != nil {
+		log.Fatalf("Failed to revoke API key %d: %v", *id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Fatalf("API key %d not found or already revoked", *id)
+	}
+	fmt.Printf("Revoked API key %d\n", *id)
+}
+
+func runAPIKeyUsage(args []string) {
+	fs := flag.NewFlagSet("apikey usage", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database")
+	id := fs.Int64("id", 0, "Only show this key")
+	days := fs.Int("days", 30, "Number of days to show, ending today")
+	fs.Parse(args)
+
+	db := openAPIKeyDB(*databasePath)
+	defer db.Close()
+
+	since := time.Now().UTC().AddDate(0, 0, -(*days - 1)).Format(time.DateOnly)
+	rows, err := db.Query(`
+		SELECT u.day, k.id, k.name, u.requests, u.rejected, COALESCE(u.last_request_at, 0)
+		FROM api_key_usage u
+		JOIN api_keys k ON k.id = u.key_id
+		WHERE u.day >= ? AND (? = 0 OR k.id = ?)
+		ORDER BY u.day DESC, k.id`, since, *id, *id)
+	if err != nil {
+		log.Fatalf("Failed to query API key usage: %v", err)
+	}
+	defer rows.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tID\tNAME\tREQUESTS\tREJECTED\tLAST REQUEST")
+	for rows.Next() {
+		var (
+			day, name                                string
+			keyID, requests, rejected, lastRequestAt int64
+		)
+		if err := rows.Scan(&day, &keyID, &name, &requests, &rejected, &lastRequestAt); err != nil {
+			log.Fatalf("Failed to scan API key usage: %v", err)
+		}
+		last := "-"
+		if lastRequestAt > 0 {
+			last = time.Unix(lastRequestAt, 0).UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\n", day, keyID, name, requests, rejected, last)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Failed to read API key usage: %v", err)
+	}
+	w.Flush()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// priceChangesSchema defines price_changes. IDs are AUTOINCREMENT and rows are only
+// rewritten when a replay finds them different, so an ID keeps naming the same change:
+// the API serves them as feed cursors and SSE event IDs.
+const priceChangesSchema = `(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	machine_type varchar(64),
+	family varchar(64),
+	region_name varchar(64),
+	updated_ts INTEGER,
+	old_hour_price REAL,
+	new_hour_price REAL,
+	old_spot_hour_price REAL,
+	new_spot_hour_price REAL
+)`
+
+// initPriceChangesTable creates price_changes, which holds one row per snapshot in
+// which the spot or on-demand price of a series differs from its previous observation.
+func initPriceChangesTable(client *sql.DB) {
+	var schema sql.NullString
+	if err := client.QueryRow("SELECT MAX(sql) FROM sqlite_master WHERE type = 'table' AND name = 'price_changes'").Scan(&schema); err != nil {
+		log.Fatalf("Failed to inspect schema: %v", err)
+	}
+
+	if _, err := client.Exec("CREATE TABLE IF NOT EXISTS price_changes " + priceChangesSchema); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+	if schema.Valid && !strings.Contains(schema.String, "AUTOINCREMENT") {
+		migratePriceChanges(client)
+	}
+	// ingestion_run_id is the run that recorded a change, NULL for changes recorded by
+	// compaction or before the column existed. Alerts are evaluated on a run's changes.
+	ensureColumn(client, "price_changes", "ingestion_run_id", "INTEGER")
+	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_price_changes_ts ON price_changes(updated_ts, id)"); err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+	if _, err := client.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_price_changes_series ON price_changes(region_name, machine_type, updated_ts)"); err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_price_changes_run ON price_changes(ingestion_run_id)"); err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+
+	// Changes are derived while maintaining price_summary. Clearing the summary when the
+	// table is new makes the next import rebuild both from pricing_history.
+	if !schema.Valid {
+		if _, err := client.Exec("DELETE FROM price_summary"); err != nil {
+			log.Fatalf("Failed to reset price summary: %v", err)
+		}
+	}
+}
+
+// migratePriceChanges recreates a price_changes table from before AUTOINCREMENT, keeping
+// its IDs. Without it SQLite reuses the IDs of deleted rows.
+func migratePriceChanges(client *sql.DB) {
+	tx, err := client.Begin()
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range []string{
+		"CREATE TABLE price_changes_new " + priceChangesSchema,
+		`INSERT INTO price_changes_new
+			SELECT id, machine_type, family, region_name, updated_ts,
+				old_hour_price, new_hour_price, old_spot_hour_price, new_spot_hour_price
+			FROM price_changes WHERE id IN (
+				SELECT MIN(id) FROM price_changes GROUP BY region_name, machine_type, updated_ts
+			) ORDER BY id`,
+		"DROP TABLE price_changes",
+		"ALTER TABLE price_changes_new RENAME TO price_changes",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			log.Fatalf("Failed to migrate price changes: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Failed to commit transaction: %v", err)
+	}
+}
+
+// priceChange is a pending price_changes row.
+type priceChange struct {
+	key          seriesKey
+	ts           int64
+	oldHourPrice float64
+	newHourPrice float64
+	oldSpotPrice float64
+	newSpotPrice float64
+}
+
+// machineFamily derives the family from a machine type name, as the import does for machine_type.
+func machineFamily(machineType string) string {
+	return strings.Split(machineType, "-")[0]
+}
+
+// changeKey identifies a price change; a series changes at most once per snapshot.
+type changeKey struct {
+	seriesKey
+	ts int64
+}
+
+// insertPriceChanges inserts changes recorded by the ingestion run runID, 0 for none, and
+// returns the number of rows inserted. Changes already recorded are left as they are.
+func insertPriceChanges(tx *sql.Tx, runID int64, changes []priceChange) (int, error) {
+	stmt, err := tx.Prepare(`INSERT INTO price_changes (
+		machine_type, family, region_name, updated_ts,
+		old_hour_price, new_hour_price, old_spot_hour_price, new_spot_hour_price, ingestion_run_id
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(region_name, machine_type, updated_ts) DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, c := range changes {
+		res, err := stmt.Exec(
+			c.key.machineType, machineFamily(c.key.machineType), c.key.regionName, c.ts,
+			c.oldHourPrice, c.newHourPrice, c.oldSpotPrice, c.newSpotPrice,
+			sql.NullInt64{Int64: runID, Valid: runID != 0},
+		)
+		if err != nil {
+			return inserted, fmt.Errorf("failed to insert price change: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
+// replacePriceChanges makes the price changes matching where equal to changes, the
+// result of replaying those series. Rows that did not change keep their ID; the others
+// are updated in place, inserted (as recorded by runID) or deleted. It returns the number
+// of rows inserted.
+func replacePriceChanges(tx *sql.Tx, runID int64, where string, args []interface{}, changes []priceChange) (int, error) {
+	existing := map[changeKey]priceChange{}
+	ids := map[changeKey]int64{}
+	rows, err := tx.Query(`SELECT
+		id, region_name, machine_type, updated_ts,
+		old_hour_price, new_hour_price, old_spot_hour_price, new_spot_hour_price
+		FROM price_changes`+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query price changes: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		var c priceChange
+		if err := rows.Scan(&id, &c.key.regionName, &c.key.machineType, &c.ts,
+			&c.oldHourPrice, &c.newHourPrice, &c.oldSpotPrice, &c.newSpotPrice); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		k := changeKey{c.key, c.ts}
+		existing[k] = c
+		ids[k] = id
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("error iterating price changes: %w", err)
+	}
+
+	var added []priceChange
+	update, err := tx.Prepare(`UPDATE price_changes SET
+		old_hour_price = ?, new_hour_price = ?, old_spot_hour_price = ?, new_spot_hour_price = ?
+		WHERE id = ?`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer update.Close()
+	for _, c := range changes {
+		k := changeKey{c.key, c.ts}
+		old, ok := existing[k]
+		if !ok {
+			added = append(added, c)
+			continue
+		}
+		delete(existing, k)
+		if old == c {
+			continue
+		}
+		if _, err := update.Exec(c.oldHourPrice, c.newHourPrice, c.oldSpotPrice, c.newSpotPrice, ids[k]); err != nil {
+			return 0, fmt.Errorf("failed to update price change: %w", err)
+		}
+	}
+
+	for k := range existing {
+		if _, err := tx.Exec("DELETE FROM price_changes WHERE id = ?", ids[k]); err != nil {
+			return 0, fmt.Errorf("failed to delete price change: %w", err)
+		}
+	}
+	return insertPriceChanges(tx, runID, added)
+}
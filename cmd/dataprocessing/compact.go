@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+const defaultRetentionPolicy = "raw:90d,day:365d,week"
+
+// Resolutions a pricing_history row can be stored at, from finest to coarsest.
+var resolutionRank = map[string]int{
+	"raw":  0,
+	"day":  1,
+	"week": 2,
+}
+
+// RetentionTier keeps history at Resolution for data younger than MaxAge.
+// A zero MaxAge means the tier applies to everything older than the previous tier.
+type RetentionTier struct {
+	Resolution string
+	MaxAge     time.Duration
+}
+
+// parseRetentionPolicy parses a policy such as "raw:90d,day:365d,week".
+// Tiers must be ordered from finest to coarsest resolution, and only the last tier may omit its age.
+func parseRetentionPolicy(policy string) ([]RetentionTier, error) {
+	var tiers []RetentionTier
+	parts := strings.Split(policy, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		resolution, age, hasAge := strings.Cut(part, ":")
+
+		rank, ok := resolutionRank[resolution]
+		if !ok {
+			return nil, fmt.Errorf("unknown resolution %q in tier %q", resolution, part)
+		}
+		if i == 0 && resolution != "raw" {
+			return nil, fmt.Errorf("first tier must be raw, got %q", resolution)
+		}
+		if i > 0 && rank <= resolutionRank[tiers[i-1].Resolution] {
+			return nil, fmt.Errorf("tier %q must be coarser than %q", part, tiers[i-1].Resolution)
+		}
+
+		tier := RetentionTier{Resolution: resolution}
+		if hasAge {
+			d, err := timeutil.ParseDuration(age)
+			if err != nil {
+				return nil, fmt.Errorf("invalid age in tier %q: %w", part, err)
+			}
+			if i > 0 && d <= tiers[i-1].MaxAge {
+				return nil, fmt.Errorf("tier %q must cover a longer age than the previous tier", part)
+			}
+			tier.MaxAge = d
+		} else if i != len(parts)-1 {
+			return nil, fmt.Errorf("only the last tier may omit its age, got %q", part)
+		}
+		tiers = append(tiers, tier)
+	}
+	if len(tiers) < 2 {
+		return nil, fmt.Errorf("policy needs at least one tier after raw")
+	}
+	return tiers, nil
+}
+
+// bucketExpr returns the SQL expression grouping updated_ts into buckets of the given resolution.
+// Weeks start on Monday (the Unix epoch was a Thursday).
+func bucketExpr(resolution string) string {
+	switch resolution {
+	case "week":
+		return "((updated_ts / 86400) + 3) / 7"
+	default:
+		return "updated_ts / 86400"
+	}
+}
+
+func runCompact(args []string) {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database to compact")
+	policy := fs.String("policy", defaultRetentionPolicy, "Comma separated retention tiers as resolution:age, finest first (resolutions: raw, day, week)")
+	vacuum := fs.Bool("vacuum", true, "Run VACUUM after compaction to reclaim disk space")
+	fs.Parse(args)
+
+	tiers, err := parseRetentionPolicy(*policy)
+	if err != nil {
+		log.Fatalf("Invalid retention policy: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", *databasePath)
+	if err != nil {
+		log.Fatalf("failed opening connection to sqlite: %v", err)
+	}
+	defer db.Close()
+
+	initDatabase(context.Background(), db)
+
+	rowsBefore, err := countPricingRows(db)
+	if err != nil {
+		log.Fatalf("Failed to count rows: %v", err)
+	}
+
+	// boundaries[i] separates tier i from the coarser tier i+1. It is aligned to the
+	// coarser tier's buckets so no bucket straddles two tiers.
+	now := time.Now()
+	boundaries := make([]int64, len(tiers)-1)
+	for i := range boundaries {
+		boundaries[i] = timeutil.BucketStart(now.Add(-tiers[i].MaxAge), tiers[i+1].Resolution).Unix()
+	}
+
+	var oldestRemoved int64
+	for i := 1; i < len(tiers); i++ {
+		tier := tiers[i]
+		newerBound := boundaries[i-1]
+		var olderBound int64
+		if i < len(boundaries) {
+			olderBound = boundaries[i]
+		}
+
+		start := time.Now()
+		removed, oldest, err := compactRange(db, tier.Resolution, olderBound, newerBound)
+		if err != nil {
+			log.Fatalf("Failed to compact %s tier: %v", tier.Resolution, err)
+		}
+		if removed > 0 && (oldestRemoved == 0 || oldest < oldestRemoved) {
+			oldestRemoved = oldest
+		}
+		fmt.Printf("Compacted %s tier (%s to %s): removed %d rows in %v\n",
+			tier.Resolution,
+			time.Unix(olderBound, 0).UTC().Format("2006-01-02"),
+			time.Unix(newerBound, 0).UTC().Format("2006-01-02"),
+			removed, time.Since(start))
+	}
+
+	rowsAfter, err := countPricingRows(db)
+	if err != nil {
+		log.Fatalf("Failed to count rows: %v", err)
+	}
+
+	if oldestRemoved > 0 {
+		refreshCompactedTables(db, oldestRemoved)
+	}
+
+	if _, err := db.Exec(
+		"INSERT INTO compaction_log (compacted_at, policy, raw_before, rows_before, rows_after) VALUES (?, ?, ?, ?, ?)",
+		now.Unix(), *policy, boundaries[0], rowsBefore, rowsAfter,
+	); err != nil {
+		log.Fatalf("Failed to record compaction: %v", err)
+	}
+
+	if *vacuum {
+		start := time.Now()
+		if _, err := db.Exec("VACUUM"); err != nil {
+			log.Fatalf("Failed to vacuum database: %v", err)
+		}
+		fmt.Printf("Vacuumed database in %v\n", time.Since(start))
+	}
+
+	fmt.Printf("Compaction finished: %d rows before, %d rows after\n", rowsBefore, rowsAfter)
+}
+
+// refreshCompactedTables brings the tables derived from pricing_history in line with it
+// after compaction deleted rows from sinceTS on. Series that lost rows no longer match
+// their summary, so they are recomputed like after an interrupted import; their price
+// changes are reconciled without an ingestion run and trigger no alerts.
+func refreshCompactedTables(db *sql.DB, sinceTS int64) {
+	start := time.Now()
+	summary, err := loadSummaryTracker(db, 0)
+	if err != nil {
+		log.Fatalf("Failed to load price summary: %v", err)
+	}
+	summaryRows, err := summary.save(db)
+	if err != nil {
+		log.Fatalf("Failed to update price summary: %v", err)
+	}
+	fmt.Printf("Updated %d price summary rows in %v\n", summaryRows, time.Since(start))
+
+	start = time.Now()
+	dailyRows, err := refreshDailyPrices(db, sinceTS)
+	if err != nil {
+		log.Fatalf("Failed to refresh daily prices: %v", err)
+	}
+	fmt.Printf("Refreshed %d daily price rows in %v\n", dailyRows, time.Since(start))
+}
+
+// compactRange downsamples rows with olderBound <= updated_ts < newerBound to the given resolution.
+// Each bucket keeps its latest row, which is annotated with the bucket's spot price min/max,
+// and the other rows in the bucket are deleted. It returns the number of deleted rows and
+// the oldest timestamp among them.
+func compactRange(db *sql.DB, resolution string, olderBound, newerBound int64) (int64, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DROP TABLE IF EXISTS temp.compact_plan"); err != nil {
+		return 0, 0, fmt.Errorf("failed to reset compaction plan: %w", err)
+	}
+
+	// Only buckets that hold more than one row or a finer resolution need rewriting.
+	planQuery := fmt.Sprintf(`
+		CREATE TEMP TABLE compact_plan AS
+		SELECT id, rn = 1 AS keep, min_spot, max_spot FROM (
+			SELECT
+				id,
+				resolution,
+				ROW_NUMBER() OVER w AS rn,
+				COUNT(*) OVER b AS bucket_rows,
+				MIN(COALESCE(min_spot_hour_price, spot_hour_price)) OVER b AS min_spot,
+				MAX(COALESCE(max_spot_hour_price, spot_hour_price)) OVER b AS max_spot
+			FROM (SELECT *, %s AS bucket FROM pricing_history WHERE updated_ts >= ? AND updated_ts < ?)
+			WINDOW
+				b AS (PARTITION BY machine_type, region_name, bucket),
+				w AS (PARTITION BY machine_type, region_name, bucket ORDER BY updated_ts DESC)
+		)
+		WHERE bucket_rows > 1 OR resolution != ?`, bucketExpr(resolution))
+	if _, err := tx.Exec(planQuery, olderBound, newerBound, resolution); err != nil {
+		return 0, 0, fmt.Errorf("failed to plan compaction: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE pricing_history
+		SET resolution = ?, min_spot_hour_price = plan.min_spot, max_spot_hour_price = plan.max_spot
+		FROM compact_plan AS plan
+		WHERE pricing_history.id = plan.id AND plan.keep`, resolution); err != nil {
+		return 0, 0, fmt.Errorf("failed to update bucket rows: %w", err)
+	}
+
+	var oldest sql.NullInt64
+	if err := tx.QueryRow(`SELECT MIN(updated_ts) FROM pricing_history
+		WHERE id IN (SELECT id FROM compact_plan WHERE NOT keep)`).Scan(&oldest); err != nil {
+		return 0, 0, fmt.Errorf("failed to read compacted range: %w", err)
+	}
+	res, err := tx.Exec("DELETE FROM pricing_history WHERE id IN (SELECT id FROM compact_plan WHERE NOT keep)")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete compacted rows: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count deleted rows: %w", err)
+	}
+
+	if _, err := tx.Exec("DROP TABLE compact_plan"); err != nil {
+		return 0, 0, fmt.Errorf("failed to drop compaction plan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return removed, oldest.Int64, nil
+}
+
+func countPricingRows(db *sql.DB) (int64, error) {
+	var n int64
+	err := db.QueryRow("SELECT COUNT(*) FROM pricing_history").Scan(&n)
+	return n, err
+}
+
+// getCompactedBefore returns the timestamp before which history has been compacted, or 0 if never.
+func getCompactedBefore(db *sql.DB) (int64, error) {
+	var ts sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(raw_before) FROM compaction_log").Scan(&ts); err != nil {
+		return 0, err
+	}
+	return ts.Int64, nil
+}
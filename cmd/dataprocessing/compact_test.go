@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		policy  string
+		want    []RetentionTier
+		wantErr bool
+	}{
+		{
+			policy: defaultRetentionPolicy,
+			want:   []RetentionTier{{"raw", 90 * day}, {"day", 365 * day}, {"week", 0}},
+		},
+		{
+			policy: " raw:36h , week:8w",
+			want:   []RetentionTier{{"raw", 36 * time.Hour}, {"week", 56 * day}},
+		},
+		{policy: "raw:30d,day", want: []RetentionTier{{"raw", 30 * day}, {"day", 0}}},
+		{policy: "raw:30d", wantErr: true},
+		{policy: "day:30d,week", wantErr: true},
+		{policy: "raw:30d,month", wantErr: true},
+		{policy: "raw:30d,week:52w,day", wantErr: true},
+		{policy: "raw:30d,day:30d,week", wantErr: true},
+		{policy: "raw:30d,day,week", wantErr: true},
+		{policy: "raw:soon,day", wantErr: true},
+		{policy: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRetentionPolicy(tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRetentionPolicy(%q) error = %v, want error %v", tt.policy, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRetentionPolicy(%q) = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestBucketExpr(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	// Every hour of three weeks around a year boundary: SQL buckets must change exactly
+	// where the tier boundaries computed in Go do.
+	start := time.Date(2023, 12, 20, 0, 30, 0, 0, time.UTC)
+	for _, resolution := range []string{"day", "week"} {
+		var prevBucket int64
+		var prevStart time.Time
+		for i := 0; i < 21*24; i++ {
+			ts := start.Add(time.Duration(i) * time.Hour)
+			var bucket int64
+			if err := sqlDB.QueryRow("SELECT "+bucketExpr(resolution)+" FROM (SELECT ? AS updated_ts)", ts.Unix()).Scan(&bucket); err != nil {
+				t.Fatal(err)
+			}
+			bucketStart := timeutil.BucketStart(ts, resolution)
+			if i > 0 && (bucket != prevBucket) != !bucketStart.Equal(prevStart) {
+				t.Fatalf("%s: at %v SQL bucket %d -> %d but bucket start %v -> %v", resolution, ts, prevBucket, bucket, prevStart, bucketStart)
+			}
+			prevBucket, prevStart = bucket, bucketStart
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/coverage"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/timeutil"
+)
+
+func runCoverage(args []string) {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	databasePath := fs.String("dbpath", "db.sqlite3", "Location of sqlite3 database to analyze")
+	threshold := fs.String("threshold", "7d", "Report intervals between snapshots longer than this (e.g. 36h, 7d)")
+	region := fs.String("region", "", "Only analyze series in this region")
+	machineType := fs.String("machine", "", "Only analyze series of this machine type")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	gapThreshold, err := timeutil.ParseDuration(*threshold)
+	if err != nil {
+		log.Fatalf("Invalid threshold: %v", err)
+	}
+
+	sqlDB, err := sql.Open("sqlite3", *databasePath)
+	if err != nil {
+		log.Fatalf("failed opening connection to sqlite: %v", err)
+	}
+	defer sqlDB.Close()
+
+	report, err := coverage.Analyze(db.NewQuerier(sqlDB), coverage.Options{
+		GapThreshold: gapThreshold,
+		RegionName:   *region,
+		MachineType:  *machineType,
+	})
+	if err != nil {
+		log.Fatalf("Coverage analysis failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		return
+	}
+	fmt.Print(coverage.FormatText(report))
+}
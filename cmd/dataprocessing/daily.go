@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func initDailyPricesTable(client *sql.DB) {
+	if _, err := client.Exec(`CREATE TABLE IF NOT EXISTS daily_prices (
+		machine_type varchar(64),
+		region_name varchar(64),
+		day varchar(10),
+		hour_price REAL,
+		spot_hour_price REAL,
+		observed_ts INTEGER,
+		carried_forward INTEGER,
+		commit_1y_hour_price REAL,
+		commit_3y_hour_price REAL,
+		PRIMARY KEY(region_name, machine_type, day)
+	)`); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+
+	// The table is derived data; clearing it after adding the commitment prices makes
+	// the next import rebuild it with them.
+	added1y := ensureColumn(client, "daily_prices", "commit_1y_hour_price", "REAL")
+	added3y := ensureColumn(client, "daily_prices", "commit_3y_hour_price", "REAL")
+	if added1y || added3y {
+		if _, err := client.Exec("DELETE FROM daily_prices"); err != nil {
+			log.Fatalf("Failed to reset daily prices: %v", err)
+		}
+	}
+}
+
+type dailyObservation struct {
+	machineType       string
+	regionName        string
+	hourPrice         float64
+	hourSpotPrice     float64
+	commit1yHourPrice sql.NullFloat64
+	commit3yHourPrice sql.NullFloat64
+	updatedTS         int64
+}
+
+// refreshDailyPrices rebuilds daily_prices from the day of sinceTS onwards.
+// Each machine/region gets one row per calendar day (UTC) between its first and
+// last observation, holding the last price observed at or before the end of that day.
+// A sinceTS of 0 rebuilds the whole table.
+//
+// Carrying each series forward from its latest earlier observation keeps the
+// incremental result identical to a full rebuild. The days between that observation and
+// sinceTS are rewritten as well: they are missing if the series had no later
+// observation, and unchanged otherwise.
+func refreshDailyPrices(db *sql.DB, sinceTS int64) (int64, error) {
+	fromDay := time.Unix(sinceTS, 0).UTC().Truncate(24 * time.Hour)
+	if sinceTS == 0 {
+		fromDay = time.Unix(0, 0).UTC()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM daily_prices WHERE day >= ?", fromDay.Format(time.DateOnly)); err != nil {
+		return 0, fmt.Errorf("failed to clear daily prices: %w", err)
+	}
+
+	// The latest observation before fromDay seeds the carried forward value,
+	// followed by every observation from fromDay on.
+	rows, err := tx.Query(`
+		SELECT machine_type, region_name, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price, updated_ts FROM (
+			SELECT machine_type, region_name, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price, updated_ts,
+				ROW_NUMBER() OVER (PARTITION BY machine_type, region_name ORDER BY updated_ts DESC) AS rn
+			FROM pricing_history
+			WHERE updated_ts < ?
+		) WHERE rn = 1
+		UNION ALL
+		SELECT machine_type, region_name, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price, updated_ts
+		FROM pricing_history
+		WHERE updated_ts >= ?
+		ORDER BY region_name, machine_type, updated_ts`,
+		fromDay.Unix(), fromDay.Unix(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pricing history: %w", err)
+	}
+
+	// Rows are buffered per series so inserts do not interleave with the open cursor.
+	var series [][]dailyObservation
+	for rows.Next() {
+		var obs dailyObservation
+		if err := rows.Scan(&obs.machineType, &obs.regionName, &obs.hourPrice, &obs.hourSpotPrice, &obs.commit1yHourPrice, &obs.commit3yHourPrice, &obs.updatedTS); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pricing history: %w", err)
+		}
+		last := len(series) - 1
+		if last < 0 || series[last][0].machineType != obs.machineType || series[last][0].regionName != obs.regionName {
+			series = append(series, nil)
+			last++
+		}
+		series[last] = append(series[last], obs)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating pricing history: %w", err)
+	}
+	rows.Close()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO daily_prices
+		(machine_type, region_name, day, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price, observed_ts, carried_forward)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var inserted int64
+	for _, observations := range series {
+		last := observations[len(observations)-1]
+		lastDay := time.Unix(last.updatedTS, 0).UTC().Truncate(24 * time.Hour)
+
+		// A seed from before fromDay already has rows up to its own day; carry it
+		// forward from the following day so the days up to the new data get filled.
+		day := time.Unix(observations[0].updatedTS, 0).UTC().Truncate(24 * time.Hour)
+		if observations[0].updatedTS < fromDay.Unix() {
+			day = day.AddDate(0, 0, 1)
+		}
+
+		next := 0
+		var current *dailyObservation
+		for ; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+			endOfDay := day.AddDate(0, 0, 1).Unix()
+			observedToday := false
+			for next < len(observations) && observations[next].updatedTS < endOfDay {
+				current = &observations[next]
+				observedToday = !time.Unix(current.updatedTS, 0).Before(day)
+				next++
+			}
+			if current == nil {
+				continue
+			}
+
+			if _, err := stmt.Exec(
+				current.machineType,
+				current.regionName,
+				day.Format(time.DateOnly),
+				current.hourPrice,
+				current.hourSpotPrice,
+				current.commit1yHourPrice,
+				current.commit3yHourPrice,
+				current.updatedTS,
+				!observedToday,
+			); err != nil {
+				return 0, fmt.Errorf("failed to insert daily price: %w", err)
+			}
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inserted, nil
+}
+
+// dailyPricesSince returns the timestamp daily_prices must be refreshed from after an
+// import: 0 for a full rebuild if the table is still empty, otherwise the oldest snapshot
+// that brought new rows. The boolean is false when no refresh is needed.
+func dailyPricesSince(db *sql.DB, minNewTS int64) (int64, bool, error) {
+	var hasDaily, hasHistory bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM daily_prices)").Scan(&hasDaily); err != nil {
+		return 0, false, err
+	}
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pricing_history)").Scan(&hasHistory); err != nil {
+		return 0, false, err
+	}
+	switch {
+	case !hasHistory:
+		return 0, false, nil
+	case !hasDaily:
+		return 0, true, nil
+	case minNewTS > 0:
+		return minNewTS, true, nil
+	default:
+		return 0, false, nil
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -19,10 +20,20 @@ type PricingHistory struct {
 	RegionName    string
 	HourSpotPrice float64
 	HourPrice     float64
-	UpdatedTS     int
-	Updated       time.Time
+	// Commit1yHourPrice and Commit3yHourPrice are the hourly prices with a 1 or 3 year
+	// committed use discount, when the snapshot lists them.
+	Commit1yHourPrice sql.NullFloat64
+	Commit3yHourPrice sql.NullFloat64
+	UpdatedTS         int
+	Updated           time.Time
 }
 
+// hoursPerMonth is the number of hours pricing.yml bases its monthly prices on.
+const hoursPerMonth = 730
+
+// errSnapshotCompacted is returned for snapshots that fall into already compacted history.
+var errSnapshotCompacted = errors.New("snapshot is older than compacted history")
+
 type MachineType struct {
 	Family      string
 	MachineType string
@@ -31,10 +42,29 @@ type MachineType struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compact":
+			runCompact(os.Args[2:])
+			return
+		case "coverage":
+			runCoverage(os.Args[2:])
+			return
+		case "apikey":
+			runAPIKey(os.Args[2:])
+			return
+		}
+	}
+
 	database_path := flag.String("dbpath", "db.sqlite3", "Desired location of sqlite3 database")
 	data_path := flag.String("data", "data/", "Location of pricing.yml history files")
 	batch_size := flag.Int("batch", 2000, "Batch size for database inserts")
+	report_path := flag.String("report", "", "Optional path to write a JSON summary of the ingestion run")
+	allowPrivateWebhooks := flag.Bool("allow-private-webhooks", false, "Deliver alert webhooks to loopback, link-local and private addresses")
 	flag.Parse()
+	if *allowPrivateWebhooks {
+		webhookClient = newWebhookClient(nil)
+	}
 	db, err := sql.Open("sqlite3", *database_path)
 	if err != nil {
 		log.Fatalf("failed opening connection to sqlite: %v", err)
@@ -51,23 +81,106 @@ func main() {
 
 	initDatabase(context.Background(), db)
 
-	files, err := os.ReadDir(*data_path)
+	run, err := startIngestionRun(db, *data_path)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	summary, err := loadSummaryTracker(db, run.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n := len(summary.recompute); n > 0 {
+		run.Warnf("price summary of %d series is behind pricing_history, recomputing them", n)
+	}
+
+	runErr := ingestFiles(db, run, summary, *data_path, *batch_size)
+	if runErr == nil {
+		refreshDerivedTables(db, run, summary)
+		evaluateAlerts(db, run)
+	}
+	if err := run.Finish(runErr); err != nil {
+		log.Printf("Failed to record ingestion run: %v", err)
+	}
+
+	fmt.Printf("Ingestion run %d %s: %d files processed, %d skipped, %d failed, %d rows inserted, %d ignored, %d warnings, %d errors\n",
+		run.ID, run.Status, run.FilesProcessed, run.FilesSkipped, run.FilesFailed,
+		run.RowsInserted, run.RowsIgnored, run.WarningCount, run.ErrorCount)
+
+	if *report_path != "" {
+		if err := run.WriteReport(*report_path); err != nil {
+			log.Printf("Failed to write report: %v", err)
+		}
+	}
+
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
+}
+
+// ingestFiles imports every file in dataPath. Per-file problems are recorded on the run;
+// only errors that stop the whole import are returned.
+func ingestFiles(db *sql.DB, run *IngestionRun, summary *summaryTracker, dataPath string, batchSize int) error {
+	compactedBefore, err := getCompactedBefore(db)
+	if err != nil {
+		return fmt.Errorf("failed to read compaction state: %w", err)
+	}
+
+	files, err := os.ReadDir(dataPath)
+	if err != nil {
+		return err
+	}
+
 	for _, file := range files {
 		start := time.Now()
 		fmt.Printf("Processing file %s\n", file.Name())
 
-		if err := processFile(*data_path, file.Name(), db, *batch_size); err != nil {
-			log.Printf("Error processing file %s: %v", file.Name(), err)
+		inserted, err := processFile(dataPath, file.Name(), db, batchSize, compactedBefore, run)
+		summary.observe(inserted)
+		if errors.Is(err, errSnapshotCompacted) {
+			run.FilesSkipped++
+			continue
+		}
+		if err != nil {
+			run.FilesFailed++
+			run.Errorf("processing file %s: %v", file.Name(), err)
 			continue
 		}
 
+		run.FilesProcessed++
 		fmt.Printf("Completed %s in %v\n", file.Name(), time.Since(start))
 	}
+	return nil
+}
+
+// refreshDerivedTables updates tables computed from pricing_history after an import.
+func refreshDerivedTables(db *sql.DB, run *IngestionRun, summary *summaryTracker) {
+	start := time.Now()
+	summaryRows, err := summary.save(db)
+	if err != nil {
+		run.Errorf("updating price summary: %v", err)
+	} else {
+		run.PriceChanges = summary.changesWritten
+		fmt.Printf("Updated %d price summary rows and recorded %d price changes in %v\n", summaryRows, summary.changesWritten, time.Since(start))
+	}
+
+	since, needed, err := dailyPricesSince(db, run.minNewTS)
+	if err != nil {
+		run.Errorf("checking daily prices: %v", err)
+		return
+	}
+	if !needed {
+		return
+	}
 
+	start = time.Now()
+	rows, err := refreshDailyPrices(db, since)
+	if err != nil {
+		run.Errorf("refreshing daily prices: %v", err)
+		return
+	}
+	run.DailyRows = rows
+	fmt.Printf("Refreshed %d daily price rows in %v\n", rows, time.Since(start))
 }
 
 func initDatabase(ctx context.Context, client *sql.DB) {
@@ -79,6 +192,9 @@ func initDatabase(ctx context.Context, client *sql.DB) {
 		spot_hour_price REAL, 
 		updated_ts INTEGER, 
 		updated varchar(64),
+		resolution varchar(8) NOT NULL DEFAULT 'raw',
+		min_spot_hour_price REAL,
+		max_spot_hour_price REAL,
 		UNIQUE(machine_type, region_name, updated_ts)
 	)`)
 
@@ -107,38 +223,119 @@ func initDatabase(ctx context.Context, client *sql.DB) {
 		log.Fatalf("Failed to execute create table: %v", err)
 	}
 
+	// Columns added after the initial schema; older databases are upgraded in place
+	ensureColumn(client, "pricing_history", "resolution", "varchar(8) NOT NULL DEFAULT 'raw'")
+	ensureColumn(client, "pricing_history", "min_spot_hour_price", "REAL")
+	ensureColumn(client, "pricing_history", "max_spot_hour_price", "REAL")
+	ensureColumn(client, "pricing_history", "commit_1y_hour_price", "REAL")
+	ensureColumn(client, "pricing_history", "commit_3y_hour_price", "REAL")
+
+	if _, err := client.Exec(`CREATE TABLE IF NOT EXISTS compaction_log (
+		id INTEGER PRIMARY KEY,
+		compacted_at INTEGER,
+		policy varchar(128),
+		raw_before INTEGER,
+		rows_before INTEGER,
+		rows_after INTEGER
+	)`); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+
+	initIngestionRunsTable(client)
+	initDailyPricesTable(client)
+	initPriceSummaryTable(client)
+	initPriceChangesTable(client)
+	initAlertTables(client)
+	initAPIKeyTables(client)
+
 	// Create index for better query performance
 	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_machine_region ON pricing_history(machine_type, region_name)"); err != nil {
 		log.Printf("Failed to create index: %v", err)
 	}
+	// Point-in-time queries resolve the snapshot in effect and scan a region's history up to an instant
+	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_updated_ts ON pricing_history(updated_ts)"); err != nil {
+		log.Printf("Failed to create index: %v", err)
+	}
+	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_region_updated_ts ON pricing_history(region_name, updated_ts)"); err != nil {
+		log.Printf("Failed to create index: %v", err)
+	}
+	// The API looks up the latest spec of a machine type by name, without its family
+	if _, err := client.Exec("CREATE INDEX IF NOT EXISTS idx_machine_type_name ON machine_type(machine_type)"); err != nil {
+		log.Printf("Failed to create index: %v", err)
+	}
+
+}
+
+// ensureColumn adds a column to an existing table unless it is already present.
+// It reports whether the column was added.
+func ensureColumn(client *sql.DB, table, column, definition string) bool {
+	rows, err := client.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		log.Fatalf("Failed to inspect table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			log.Fatalf("Failed to scan table info for %s: %v", table, err)
+		}
+		if name == column {
+			return false
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Failed to inspect table %s: %v", table, err)
+	}
 
+	if _, err := client.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		log.Fatalf("Failed to add column %s.%s: %v", table, column, err)
+	}
+	return true
 }
 
-func processFile(dataPath, fileName string, db *sql.DB, batchSize int) error {
+// processFile imports one pricing.yml revision and returns the records that were new.
+func processFile(dataPath, fileName string, db *sql.DB, batchSize int, compactedBefore int64, run *IngestionRun) ([]PricingHistory, error) {
 	fileData, err := os.ReadFile(fmt.Sprintf("%s/%s", dataPath, fileName))
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	var data map[string]interface{}
 	if err := yaml.Unmarshal(fileData, &data); err != nil {
-		return fmt.Errorf("failed to unmarshal YAML: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
 	}
 
 	timestamp, timestampOk := getTimestamp(data)
 	if !timestampOk {
-		return fmt.Errorf("no valid timestamp found")
+		return nil, fmt.Errorf("no valid timestamp found")
+	}
+
+	// Snapshots older than the last compaction were already downsampled; re-inserting
+	// them would bring back the raw rows the retention policy removed.
+	if int64(timestamp) < compactedBefore {
+		fmt.Printf("Skipping snapshot from %s, history before %s is compacted\n",
+			convertTimestampToDate(timestamp).UTC().Format(time.RFC3339),
+			time.Unix(compactedBefore, 0).UTC().Format(time.RFC3339))
+		return nil, errSnapshotCompacted
 	}
 
 	// Extract and validate structure once
 	compute, ok := data["compute"].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("invalid compute structure")
+		return nil, fmt.Errorf("invalid compute structure")
 	}
 
 	instances, ok := compute["instance"].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("invalid instance structure")
+		return nil, fmt.Errorf("invalid instance structure")
 	}
 
 	// Collect all records first
@@ -176,7 +373,7 @@ func processFile(dataPath, fileName string, db *sql.DB, batchSize int) error {
 				case float64:
 					memoryGB = v
 				default:
-					log.Printf("Warning: Unexpected type for RAM for machine %s: %T. Skipping record.", machineTypeName, v)
+					run.Warnf("%s: unexpected type for RAM for machine %s: %T, skipping record", fileName, machineTypeName, v)
 					continue // Skip this record if RAM type is not int or float64
 				}
 				switch v := instanceMap["cpu"].(type) {
@@ -185,7 +382,7 @@ func processFile(dataPath, fileName string, db *sql.DB, batchSize int) error {
 				case float64:
 					cpuCores = v
 				default:
-					log.Printf("Warning: Unexpected type for CPU for machine %s: %T. Skipping record.", machineTypeName, v)
+					run.Warnf("%s: unexpected type for CPU for machine %s: %T, skipping record", fileName, machineTypeName, v)
 					continue // Skip this record if RAM type is not int or float64
 				}
 
@@ -196,12 +393,14 @@ func processFile(dataPath, fileName string, db *sql.DB, batchSize int) error {
 					MemoryGB:    memoryGB,
 				})
 				records = append(records, PricingHistory{
-					MachineType:   machineTypeName,
-					RegionName:    regionName,
-					HourSpotPrice: hourSpotPrice,
-					HourPrice:     hourPrice,
-					UpdatedTS:     timestamp,
-					Updated:       updated,
+					MachineType:       machineTypeName,
+					RegionName:        regionName,
+					HourSpotPrice:     hourSpotPrice,
+					HourPrice:         hourPrice,
+					Commit1yHourPrice: monthlyToHourly(regionMap["month_1y"]),
+					Commit3yHourPrice: monthlyToHourly(regionMap["month_3y"]),
+					UpdatedTS:         timestamp,
+					Updated:           updated,
 				})
 			}
 		}
@@ -209,9 +408,17 @@ func processFile(dataPath, fileName string, db *sql.DB, batchSize int) error {
 
 	fmt.Printf("Found %d records to process (duplicates will be skipped)\n", len(records))
 
-	insertMachineTypeRecordsInBatches(db, machine_types, batchSize)
+	if err := insertMachineTypeRecordsInBatches(db, machine_types, batchSize); err != nil {
+		run.Warnf("%s: failed to insert machine types: %v", fileName, err)
+	}
 	// Insert in batches with transactions
-	return insertRecordsInBatches(db, records, batchSize)
+	inserted, backfilled, err := insertRecordsInBatches(db, records, batchSize)
+	if err != nil {
+		run.recordSnapshot(int64(timestamp), int64(len(inserted)), 0, backfilled)
+		return inserted, err
+	}
+	run.recordSnapshot(int64(timestamp), int64(len(inserted)), int64(len(records)-len(inserted)), backfilled)
+	return inserted, nil
 }
 
 func insertMachineTypeRecordsInBatches(db *sql.DB, records []MachineType, batchSize int) error {
@@ -258,7 +465,13 @@ func insertMachineTypeRecordsInBatches(db *sql.DB, records []MachineType, batchS
 	return nil
 }
 
-func insertRecordsInBatches(db *sql.DB, records []PricingHistory, batchSize int) error {
+// insertRecordsInBatches inserts pricing records and returns the ones that were new.
+// Records already present (same machine, region and timestamp) are ignored, except that
+// they fill in committed use prices the stored row lacks, e.g. because it was imported
+// before they were stored. It also returns the number of rows backfilled that way.
+func insertRecordsInBatches(db *sql.DB, records []PricingHistory, batchSize int) ([]PricingHistory, int64, error) {
+	var inserted []PricingHistory
+	var backfilled int64
 	for i := 0; i < len(records); i += batchSize {
 		end := i + batchSize
 		if end > len(records) {
@@ -268,40 +481,76 @@ func insertRecordsInBatches(db *sql.DB, records []PricingHistory, batchSize int)
 		// Begin transaction for this batch
 		tx, err := db.Begin()
 		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
+			return inserted, backfilled, fmt.Errorf("failed to begin transaction: %w", err)
 		}
 
-		stmt, err := tx.Prepare("INSERT OR IGNORE INTO pricing_history (machine_type, region_name, hour_price, spot_hour_price, updated_ts, updated) VALUES (?, ?, ?, ?, ?, ?)")
+		var batchInserted []PricingHistory
+		var batchBackfilled int64
+		stmt, err := tx.Prepare("INSERT OR IGNORE INTO pricing_history (machine_type, region_name, hour_price, spot_hour_price, commit_1y_hour_price, commit_3y_hour_price, updated_ts, updated) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
 		if err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to prepare statement: %w", err)
+			return inserted, backfilled, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		backfill, err := tx.Prepare(`UPDATE pricing_history SET
+			commit_1y_hour_price = COALESCE(commit_1y_hour_price, ?1),
+			commit_3y_hour_price = COALESCE(commit_3y_hour_price, ?2)
+			WHERE machine_type = ?3 AND region_name = ?4 AND updated_ts = ?5
+			AND ((commit_1y_hour_price IS NULL AND ?1 IS NOT NULL) OR (commit_3y_hour_price IS NULL AND ?2 IS NOT NULL))`)
+		if err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return inserted, backfilled, fmt.Errorf("failed to prepare statement: %w", err)
 		}
 
 		for j := i; j < end; j++ {
 			record := records[j]
-			if _, err := stmt.Exec(
+			res, err := stmt.Exec(
 				record.MachineType,
 				record.RegionName,
 				record.HourPrice,
 				record.HourSpotPrice,
+				record.Commit1yHourPrice,
+				record.Commit3yHourPrice,
 				record.UpdatedTS,
 				record.Updated,
-			); err != nil {
+			)
+			if err != nil {
 				stmt.Close()
+				backfill.Close()
 				tx.Rollback()
-				return fmt.Errorf("failed to insert record: %w", err)
+				return inserted, backfilled, fmt.Errorf("failed to insert record: %w", err)
+			}
+			if n, err := res.RowsAffected(); err == nil && n > 0 {
+				batchInserted = append(batchInserted, record)
+				continue
+			}
+			if !record.Commit1yHourPrice.Valid && !record.Commit3yHourPrice.Valid {
+				continue
+			}
+			res, err = backfill.Exec(record.Commit1yHourPrice, record.Commit3yHourPrice, record.MachineType, record.RegionName, record.UpdatedTS)
+			if err != nil {
+				stmt.Close()
+				backfill.Close()
+				tx.Rollback()
+				return inserted, backfilled, fmt.Errorf("failed to backfill record: %w", err)
+			}
+			if n, err := res.RowsAffected(); err == nil {
+				batchBackfilled += n
 			}
 		}
 
 		stmt.Close()
+		backfill.Close()
 		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
+			return inserted, backfilled, fmt.Errorf("failed to commit transaction: %w", err)
 		}
+		inserted = append(inserted, batchInserted...)
+		backfilled += batchBackfilled
 
-		fmt.Printf("Processed batch of %d pricing records (new inserted, duplicates ignored)\n", end-i)
+		fmt.Printf("Processed batch of %d pricing records (%d new inserted, %d backfilled, duplicates ignored)\n", end-i, len(batchInserted), batchBackfilled)
 	}
 
-	return nil
+	return inserted, backfilled, nil
 }
 
 func getTimestamp(data map[string]interface{}) (int, bool) {
@@ -319,3 +568,15 @@ func getTimestamp(data map[string]interface{}) (int, bool) {
 func convertTimestampToDate(timestamp int) time.Time {
 	return time.Unix(int64(timestamp), 0)
 }
+
+// monthlyToHourly converts an optional monthly price from pricing.yml to an hourly one.
+func monthlyToHourly(value interface{}) sql.NullFloat64 {
+	switch v := value.(type) {
+	case int:
+		return sql.NullFloat64{Float64: float64(v) / hoursPerMonth, Valid: true}
+	case float64:
+		return sql.NullFloat64{Float64: v / hoursPerMonth, Valid: true}
+	default:
+		return sql.NullFloat64{}
+	}
+}
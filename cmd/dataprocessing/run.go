@@ -0,0 +1,173 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// maxRunMessages caps how many warning/error messages are kept per run; counts are always exact.
+const maxRunMessages = 200
+
+// Ingestion run statuses stored in ingestion_runs.status.
+const (
+	runStatusRunning             = "running"
+	runStatusCompleted           = "completed"
+	runStatusCompletedWithErrors = "completed_with_errors"
+	runStatusFailed              = "failed"
+)
+
+// IngestionRun records the outcome of one dataprocessing import.
+// It is persisted to the ingestion_runs table and optionally written as a JSON report.
+type IngestionRun struct {
+	ID             int64     `json:"id"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Status         string    `json:"status"`
+	DataPath       string    `json:"data_path"`
+	FilesProcessed int       `json:"files_processed"`
+	FilesSkipped   int       `json:"files_skipped"`
+	FilesFailed    int       `json:"files_failed"`
+	RowsInserted   int64     `json:"rows_inserted"`
+	RowsIgnored    int64     `json:"rows_ignored"`
+	WarningCount   int       `json:"warning_count"`
+	ErrorCount     int       `json:"error_count"`
+	Warnings       []string  `json:"warnings"`
+	Errors         []string  `json:"errors"`
+	DailyRows      int64     `json:"daily_rows_refreshed"`
+	PriceChanges   int       `json:"price_changes_recorded"`
+	// CommitPricesBackfilled counts existing rows that got their committed use prices.
+	CommitPricesBackfilled int64 `json:"commit_prices_backfilled"`
+	// AlertsTriggered counts the alert rules notified after the import.
+	AlertsTriggered       int `json:"alerts_triggered"`
+	AlertDeliveriesFailed int `json:"alert_deliveries_failed"`
+
+	// minNewTS is the oldest snapshot timestamp that inserted or backfilled rows, 0 if none did.
+	minNewTS int64
+	db       *sql.DB
+}
+
+func initIngestionRunsTable(client *sql.DB) {
+	if _, err := client.Exec(`CREATE TABLE IF NOT EXISTS ingestion_runs (
+		id INTEGER PRIMARY KEY,
+		started_at INTEGER,
+		finished_at INTEGER,
+		status varchar(32),
+		data_path TEXT,
+		files_processed INTEGER DEFAULT 0,
+		files_skipped INTEGER DEFAULT 0,
+		files_failed INTEGER DEFAULT 0,
+		rows_inserted INTEGER DEFAULT 0,
+		rows_ignored INTEGER DEFAULT 0,
+		warning_count INTEGER DEFAULT 0,
+		error_count INTEGER DEFAULT 0,
+		warnings TEXT,
+		errors TEXT
+	)`); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+}
+
+// startIngestionRun inserts a "running" row so crashed imports remain visible.
+func startIngestionRun(db *sql.DB, dataPath string) (*IngestionRun, error) {
+	run := &IngestionRun{
+		StartedAt: time.Now().UTC(),
+		Status:    runStatusRunning,
+		DataPath:  dataPath,
+		Warnings:  []string{},
+		Errors:    []string{},
+		db:        db,
+	}
+	res, err := db.Exec(
+		"INSERT INTO ingestion_runs (started_at, status, data_path) VALUES (?, ?, ?)",
+		run.StartedAt.Unix(), run.Status, run.DataPath,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record ingestion run: %w", err)
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to read ingestion run id: %w", err)
+	}
+	return run, nil
+}
+
+// Warnf logs a warning and records it on the run.
+func (r *IngestionRun) Warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("Warning: %s", msg)
+	r.WarningCount++
+	if len(r.Warnings) < maxRunMessages {
+		r.Warnings = append(r.Warnings, msg)
+	}
+}
+
+// Errorf logs an error and records it on the run.
+func (r *IngestionRun) Errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("Error: %s", msg)
+	r.ErrorCount++
+	if len(r.Errors) < maxRunMessages {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// recordSnapshot accounts the rows one snapshot inserted, ignored and backfilled.
+func (r *IngestionRun) recordSnapshot(timestamp int64, inserted, ignored, backfilled int64) {
+	r.RowsInserted += inserted
+	r.RowsIgnored += ignored
+	r.CommitPricesBackfilled += backfilled
+	if (inserted > 0 || backfilled > 0) && (r.minNewTS == 0 || timestamp < r.minNewTS) {
+		r.minNewTS = timestamp
+	}
+}
+
+// Finish stores the final state of the run. A non-nil fatalErr marks the run as failed.
+func (r *IngestionRun) Finish(fatalErr error) error {
+	r.FinishedAt = time.Now().UTC()
+	switch {
+	case fatalErr != nil:
+		r.Errorf("%v", fatalErr)
+		r.Status = runStatusFailed
+	case r.ErrorCount > 0:
+		r.Status = runStatusCompletedWithErrors
+	default:
+		r.Status = runStatusCompleted
+	}
+
+	warnings, err := json.Marshal(r.Warnings)
+	if err != nil {
+		return fmt.Errorf("failed to encode warnings: %w", err)
+	}
+	errs, err := json.Marshal(r.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to encode errors: %w", err)
+	}
+
+	_, err = r.db.Exec(`UPDATE ingestion_runs SET
+		finished_at = ?, status = ?, files_processed = ?, files_skipped = ?, files_failed = ?,
+		rows_inserted = ?, rows_ignored = ?, warning_count = ?, error_count = ?, warnings = ?, errors = ?
+		WHERE id = ?`,
+		r.FinishedAt.Unix(), r.Status, r.FilesProcessed, r.FilesSkipped, r.FilesFailed,
+		r.RowsInserted, r.RowsIgnored, r.WarningCount, r.ErrorCount, string(warnings), string(errs),
+		r.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update ingestion run: %w", err)
+	}
+	return nil
+}
+
+// WriteReport writes the run summary as indented JSON.
+func (r *IngestionRun) WriteReport(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+func initPriceSummaryTable(client *sql.DB) {
+	if _, err := client.Exec(`CREATE TABLE IF NOT EXISTS price_summary (
+		machine_type varchar(64),
+		region_name varchar(64),
+		current_hour_price REAL,
+		current_spot_hour_price REAL,
+		min_spot_hour_price REAL,
+		max_spot_hour_price REAL,
+		avg_spot_hour_price REAL,
+		first_seen_ts INTEGER,
+		last_seen_ts INTEGER,
+		observations INTEGER,
+		change_count INTEGER,
+		last_change_ts INTEGER,
+		previous_spot_hour_price REAL,
+		PRIMARY KEY(region_name, machine_type)
+	)`); err != nil {
+		log.Fatalf("Failed to execute create table: %v", err)
+	}
+
+	// Summaries written before previous_spot_hour_price existed lack the value;
+	// clearing them makes the next import rebuild the table from pricing_history.
+	if ensureColumn(client, "price_summary", "previous_spot_hour_price", "REAL") {
+		if _, err := client.Exec("DELETE FROM price_summary"); err != nil {
+			log.Fatalf("Failed to reset price summary: %v", err)
+		}
+	}
+}
+
+type seriesKey struct {
+	regionName  string
+	machineType string
+}
+
+// priceSummary mirrors one price_summary row.
+type priceSummary struct {
+	currentHourPrice float64
+	currentSpotPrice float64
+	minSpotPrice     float64
+	maxSpotPrice     float64
+	avgSpotPrice     float64
+	firstSeenTS      int64
+	lastSeenTS       int64
+	observations     int64
+	changeCount      int64
+	lastChangeTS     sql.NullInt64
+	// previousSpotPrice is the spot price before the last change.
+	previousSpotPrice sql.NullFloat64
+
+	dirty bool
+}
+
+// summaryTracker keeps price_summary and price_changes up to date during an import.
+// Rows are loaded once, updated in memory as new pricing records are inserted and
+// written back at the end. Records older than a series' latest observation cannot be
+// applied incrementally, so those series are recomputed from pricing_history instead, as
+// are series whose summary missed rows of an earlier import.
+type summaryTracker struct {
+	rows      map[seriesKey]*priceSummary
+	recompute map[seriesKey]bool
+	// changes are price changes detected since the last save.
+	changes []priceChange
+	// changesWritten counts the price_changes rows written by save.
+	changesWritten int
+	// rebuild is set when price_summary is empty while pricing_history is not,
+	// e.g. for databases created before the table existed.
+	rebuild bool
+	// runID is the ingestion run new price changes are recorded by, 0 outside imports.
+	runID int64
+}
+
+func loadSummaryTracker(db *sql.DB, runID int64) (*summaryTracker, error) {
+	t := &summaryTracker{
+		rows:      map[seriesKey]*priceSummary{},
+		recompute: map[seriesKey]bool{},
+		runID:     runID,
+	}
+
+	rows, err := db.Query(`SELECT
+		region_name, machine_type, current_hour_price, current_spot_hour_price,
+		min_spot_hour_price, max_spot_hour_price, avg_spot_hour_price,
+		first_seen_ts, last_seen_ts, observations, change_count, last_change_ts,
+		previous_spot_hour_price
+		FROM price_summary`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price summary: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key seriesKey
+		s := &priceSummary{}
+		if err := rows.Scan(
+			&key.regionName, &key.machineType, &s.currentHourPrice, &s.currentSpotPrice,
+			&s.minSpotPrice, &s.maxSpotPrice, &s.avgSpotPrice,
+			&s.firstSeenTS, &s.lastSeenTS, &s.observations, &s.changeCount, &s.lastChangeTS,
+			&s.previousSpotPrice,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan price summary: %w", err)
+		}
+		t.rows[key] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price summary: %w", err)
+	}
+
+	if len(t.rows) == 0 {
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pricing_history)").Scan(&t.rebuild); err != nil {
+			return nil, fmt.Errorf("failed to check pricing history: %w", err)
+		}
+		return t, nil
+	}
+	if err := t.findDrift(db); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// findDrift marks the series whose summary does not account for every pricing_history
+// row for recompute. The summary is saved after the pricing rows are committed, so an
+// import that crashed or failed to save in between leaves rows that the next import
+// ignores as duplicates. Every row is one observation, so comparing the totals is
+// enough to tell whether the summary is complete.
+func (t *summaryTracker) findDrift(db *sql.DB) error {
+	var observations, rowCount, lastSeen, lastUpdated int64
+	if err := db.QueryRow(`SELECT
+		(SELECT COALESCE(SUM(observations), 0) FROM price_summary),
+		(SELECT COUNT(*) FROM pricing_history),
+		(SELECT COALESCE(MAX(last_seen_ts), 0) FROM price_summary),
+		(SELECT COALESCE(MAX(updated_ts), 0) FROM pricing_history)`,
+	).Scan(&observations, &rowCount, &lastSeen, &lastUpdated); err != nil {
+		return fmt.Errorf("failed to compare price summary with pricing history: %w", err)
+	}
+	if observations == rowCount && lastSeen == lastUpdated {
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT h.region_name, h.machine_type FROM (
+			SELECT region_name, machine_type, COUNT(*) AS n, MAX(updated_ts) AS last_ts
+			FROM pricing_history GROUP BY region_name, machine_type
+		) AS h
+		LEFT JOIN price_summary AS s ON s.region_name = h.region_name AND s.machine_type = h.machine_type
+		WHERE s.observations IS NOT h.n OR s.last_seen_ts IS NOT h.last_ts`)
+	if err != nil {
+		return fmt.Errorf("failed to find outdated price summaries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key seriesKey
+		if err := rows.Scan(&key.regionName, &key.machineType); err != nil {
+			return fmt.Errorf("failed to scan outdated price summary: %w", err)
+		}
+		t.recompute[key] = true
+	}
+	return rows.Err()
+}
+
+// observe applies newly inserted records.
+func (t *summaryTracker) observe(records []PricingHistory) {
+	if t.rebuild {
+		return
+	}
+	for _, record := range records {
+		key := seriesKey{regionName: record.RegionName, machineType: record.MachineType}
+		if t.recompute[key] {
+			continue
+		}
+		s := t.rows[key]
+		ts := int64(record.UpdatedTS)
+		if s != nil && ts < s.lastSeenTS {
+			t.recompute[key] = true
+			continue
+		}
+		if s == nil {
+			s = &priceSummary{}
+			t.rows[key] = s
+		}
+		t.apply(key, s, ts, record.HourPrice, record.HourSpotPrice, record.HourSpotPrice, record.HourSpotPrice)
+	}
+}
+
+// apply adds an observation to a series' summary and records a price change if the
+// spot or on-demand price differs from the previous observation.
+func (t *summaryTracker) apply(key seriesKey, s *priceSummary, ts int64, hourPrice, spot, minSpot, maxSpot float64) {
+	if s.observations > 0 && (hourPrice != s.currentHourPrice || spot != s.currentSpotPrice) {
+		t.changes = append(t.changes, priceChange{
+			key:          key,
+			ts:           ts,
+			oldHourPrice: s.currentHourPrice,
+			newHourPrice: hourPrice,
+			oldSpotPrice: s.currentSpotPrice,
+			newSpotPrice: spot,
+		})
+	}
+	s.apply(ts, hourPrice, spot, minSpot, maxSpot)
+}
+
+// apply adds an observation that is newer than every observation applied before.
+// minSpot/maxSpot differ from spot only for compacted rows.
+func (s *priceSummary) apply(ts int64, hourPrice, spot, minSpot, maxSpot float64) {
+	s.dirty = true
+	if s.observations == 0 {
+		s.firstSeenTS = ts
+		s.minSpotPrice = minSpot
+		s.maxSpotPrice = maxSpot
+	} else if spot != s.currentSpotPrice {
+		s.changeCount++
+		s.lastChangeTS = sql.NullInt64{Int64: ts, Valid: true}
+		s.previousSpotPrice = sql.NullFloat64{Float64: s.currentSpotPrice, Valid: true}
+	}
+
+	s.minSpotPrice = min(s.minSpotPrice, minSpot)
+	s.maxSpotPrice = max(s.maxSpotPrice, maxSpot)
+	s.avgSpotPrice = (s.avgSpotPrice*float64(s.observations) + spot) / float64(s.observations+1)
+	s.observations++
+	s.currentHourPrice = hourPrice
+	s.currentSpotPrice = spot
+	s.lastSeenTS = ts
+}
+
+// save writes changed rows and recomputes series that received out-of-order records.
+// Summaries and their price changes are written in one transaction. It returns the
+// number of summary rows written.
+func (t *summaryTracker) save(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Changes of recomputed series are replayed from pricing_history with the rest.
+	pending := t.changes[:0]
+	for _, change := range t.changes {
+		if !t.rebuild && !t.recompute[change.key] {
+			pending = append(pending, change)
+		}
+	}
+	t.changes = pending
+
+	written, recorded := 0, 0
+	if t.rebuild {
+		t.rows = map[seriesKey]*priceSummary{}
+		t.recompute = map[seriesKey]bool{}
+		if written, recorded, err = t.recomputeFromHistory(tx, "", nil); err != nil {
+			return 0, err
+		}
+	}
+	for key := range t.recompute {
+		delete(t.rows, key)
+		n, inserted, err := t.recomputeFromHistory(tx, " WHERE region_name = ? AND machine_type = ?", []interface{}{key.regionName, key.machineType})
+		if err != nil {
+			return 0, err
+		}
+		written += n
+		recorded += inserted
+	}
+
+	n, err := t.upsertDirty(tx)
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := insertPriceChanges(tx, t.runID, t.changes)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	t.changesWritten += recorded + inserted
+	t.changes = nil
+	t.recompute = map[seriesKey]bool{}
+	t.rebuild = false
+	return written + n, nil
+}
+
+// recomputeFromHistory replays pricing_history (optionally filtered) into summary rows
+// and reconciles the price changes of the replayed series with the replay. It returns
+// the number of summary rows written and of price changes inserted.
+// Series being replayed must not be present in t.rows beforehand.
+func (t *summaryTracker) recomputeFromHistory(tx *sql.Tx, where string, args []interface{}) (int, int, error) {
+	pending := t.changes
+	t.changes = nil
+	defer func() { t.changes = pending }()
+
+	rows, err := tx.Query(`SELECT
+		region_name, machine_type, updated_ts, hour_price, spot_hour_price,
+		COALESCE(min_spot_hour_price, spot_hour_price), COALESCE(max_spot_hour_price, spot_hour_price)
+		FROM pricing_history`+where+`
+		ORDER BY region_name, machine_type, updated_ts`, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query pricing history: %w", err)
+	}
+
+	replayed := map[seriesKey]*priceSummary{}
+	for rows.Next() {
+		var key seriesKey
+		var ts int64
+		var hourPrice, spot, minSpot, maxSpot float64
+		if err := rows.Scan(&key.regionName, &key.machineType, &ts, &hourPrice, &spot, &minSpot, &maxSpot); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan pricing history: %w", err)
+		}
+		s := t.rows[key]
+		if s == nil {
+			s = &priceSummary{}
+			t.rows[key] = s
+			replayed[key] = s
+		}
+		t.apply(key, s, ts, hourPrice, spot, minSpot, maxSpot)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, 0, fmt.Errorf("error iterating pricing history: %w", err)
+	}
+	rows.Close()
+
+	n, err := upsertSummaries(tx, replayed)
+	if err != nil {
+		return 0, 0, err
+	}
+	inserted, err := replacePriceChanges(tx, t.runID, where, args, t.changes)
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, inserted, nil
+}
+
+// upsertDirty writes the summaries changed since they were loaded or last written.
+func (t *summaryTracker) upsertDirty(tx *sql.Tx) (int, error) {
+	return upsertSummaries(tx, t.rows)
+}
+
+func upsertSummaries(tx *sql.Tx, summaries map[seriesKey]*priceSummary) (int, error) {
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO price_summary (
+		region_name, machine_type, current_hour_price, current_spot_hour_price,
+		min_spot_hour_price, max_spot_hour_price, avg_spot_hour_price,
+		first_seen_ts, last_seen_ts, observations, change_count, last_change_ts,
+		previous_spot_hour_price
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	written := 0
+	for key, s := range summaries {
+		if !s.dirty {
+			continue
+		}
+		if _, err := stmt.Exec(
+			key.regionName, key.machineType, s.currentHourPrice, s.currentSpotPrice,
+			s.minSpotPrice, s.maxSpotPrice, s.avgSpotPrice,
+			s.firstSeenTS, s.lastSeenTS, s.observations, s.changeCount, s.lastChangeTS,
+			s.previousSpotPrice,
+		); err != nil {
+			return written, fmt.Errorf("failed to upsert price summary: %w", err)
+		}
+		s.dirty = false
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,39 @@
+// Package apikey generates API keys and the hashes they are stored and looked up by.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Prefix starts every key, so leaked keys are easy to recognize.
+const Prefix = "sph_"
+
+// displayLength is how much of a key is kept in clear text to tell keys apart.
+const displayLength = len(Prefix) + 8
+
+// Generate returns a new random key. Only its Hash is stored; the key itself is shown once.
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex SHA-256 of a key. Keys are random, so they need no salt or slow hash.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Display returns the start of a key, which identifies it in listings and logs.
+func Display(key string) string {
+	if len(key) <= displayLength {
+		return key
+	}
+	return key[:displayLength]
+}
@@ -0,0 +1,53 @@
+package apikey
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		key, err := Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, ok := strings.CutPrefix(key, Prefix)
+		if !ok {
+			t.Fatalf("key %q lacks the %q prefix", key, Prefix)
+		}
+		if b, err := base64.RawURLEncoding.DecodeString(encoded); err != nil || len(b) != 32 {
+			t.Fatalf("key %q does not encode 32 random bytes", key)
+		}
+		if seen[key] {
+			t.Fatalf("key %q generated twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestHash(t *testing.T) {
+	// SHA-256 test vector of FIPS 180-2.
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := Hash("abc"); got != want {
+		t.Errorf("Hash(abc) = %s, want %s", got, want)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"sph_0123456789abcdef", "sph_01234567"},
+		{"sph_01234567", "sph_01234567"},
+		{"short", "short"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Display(tt.key); got != tt.want {
+			t.Errorf("Display(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
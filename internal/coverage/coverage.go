@@ -0,0 +1,251 @@
+// Package coverage analyzes how evenly pricing snapshots cover time.
+//
+// Upstream pricing.yml commits are irregular, so the history contains periods
+// without any snapshot, and individual machines can be missing from snapshots
+// that otherwise contain their region. Both look like stable prices to a reader
+// of the raw series; this package makes them visible.
+package coverage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+// DefaultGapThreshold is the interval between snapshots reported as a gap when none is configured.
+const DefaultGapThreshold = 7 * 24 * time.Hour
+
+// Options controls the coverage analysis.
+type Options struct {
+	// GapThreshold is the longest interval between two snapshots, or between two
+	// observations of a series, that is not reported as a gap.
+	GapThreshold time.Duration
+	// RegionName and MachineType optionally restrict the per-series analysis.
+	RegionName  string
+	MachineType string
+}
+
+// Gap is a period without observations. For the overall report it is an interval
+// without any snapshot; for a series it spans snapshots the series is missing from.
+type Gap struct {
+	Start            time.Time `json:"start" example:"2024-01-01T00:00:00Z"`
+	End              time.Time `json:"end" example:"2024-01-15T00:00:00Z"`
+	DurationHours    float64   `json:"duration_hours" example:"336"`
+	MissingSnapshots int       `json:"missing_snapshots" example:"2" description:"Snapshots taken during the gap that do not contain the series"`
+}
+
+// SeriesCoverage describes the coverage of one machine type in one region.
+// Only snapshots between FirstSeen and LastSeen are expected to contain the series.
+type SeriesCoverage struct {
+	RegionName        string    `json:"region_name" example:"us-central1"`
+	MachineType       string    `json:"machine_type" example:"n2-standard-8"`
+	FirstSeen         time.Time `json:"first_seen"`
+	LastSeen          time.Time `json:"last_seen"`
+	ObservedSnapshots int       `json:"observed_snapshots" example:"118"`
+	ExpectedSnapshots int       `json:"expected_snapshots" example:"120"`
+	CoverageRatio     float64   `json:"coverage_ratio" example:"0.983"`
+	Gaps              []Gap     `json:"gaps"`
+}
+
+// Report is the result of a coverage analysis.
+type Report struct {
+	FirstSnapshot     *time.Time       `json:"first_snapshot,omitempty"`
+	LastSnapshot      *time.Time       `json:"last_snapshot,omitempty"`
+	SnapshotCount     int              `json:"snapshot_count" example:"850"`
+	GapThresholdHours float64          `json:"gap_threshold_hours" example:"168"`
+	Gaps              []Gap            `json:"gaps" description:"Periods longer than the threshold without any snapshot"`
+	SeriesAnalyzed    int              `json:"series_analyzed" example:"12000"`
+	SeriesWithGaps    int              `json:"series_with_gaps" example:"35" description:"Series with at least one gap longer than the threshold"`
+	SeriesCoverage    []SeriesCoverage `json:"series" description:"Series with at least one gap or missing snapshot"`
+	CompactedHistory  bool             `json:"compacted_history" description:"True if part of the history was downsampled and excluded from the analysis"`
+}
+
+// Analyze computes the coverage report. Only raw (not compacted) rows are
+// considered, since downsampled buckets intentionally drop snapshots.
+func Analyze(q *db.Querier, opts Options) (*Report, error) {
+	if opts.GapThreshold <= 0 {
+		opts.GapThreshold = DefaultGapThreshold
+	}
+
+	report := &Report{
+		GapThresholdHours: opts.GapThreshold.Hours(),
+		Gaps:              []Gap{},
+		SeriesCoverage:    []SeriesCoverage{},
+	}
+
+	var snapshots []int64
+	err := q.QueryRowsNamed(
+		"coverage_snapshots",
+		"SELECT DISTINCT updated_ts FROM pricing_history WHERE resolution = 'raw' ORDER BY updated_ts",
+		func(rows *sql.Rows) error {
+			var ts int64
+			if err := rows.Scan(&ts); err != nil {
+				return fmt.Errorf("failed to scan snapshot: %w", err)
+			}
+			snapshots = append(snapshots, ts)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+
+	err = q.QueryRowNamed(
+		"coverage_compaction",
+		"SELECT EXISTS(SELECT 1 FROM pricing_history WHERE resolution != 'raw')",
+		func(row *sql.Row) error { return row.Scan(&report.CompactedHistory) },
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query compaction state: %w", err)
+	}
+
+	report.SnapshotCount = len(snapshots)
+	if len(snapshots) == 0 {
+		return report, nil
+	}
+	first, last := time.Unix(snapshots[0], 0).UTC(), time.Unix(snapshots[len(snapshots)-1], 0).UTC()
+	report.FirstSnapshot, report.LastSnapshot = &first, &last
+
+	threshold := int64(opts.GapThreshold.Seconds())
+	for i := 1; i < len(snapshots); i++ {
+		if snapshots[i]-snapshots[i-1] > threshold {
+			report.Gaps = append(report.Gaps, newGap(snapshots[i-1], snapshots[i], 0))
+		}
+	}
+
+	query := `
+		SELECT region_name, machine_type, updated_ts
+		FROM pricing_history
+		WHERE resolution = 'raw'`
+	var args []interface{}
+	if opts.RegionName != "" {
+		query += " AND region_name = ?"
+		args = append(args, opts.RegionName)
+	}
+	if opts.MachineType != "" {
+		query += " AND machine_type = ?"
+		args = append(args, opts.MachineType)
+	}
+	// The filters narrow the scan through idx_region_updated_ts or the unique index;
+	// without them, this order lets the unique index stream the table without a sort.
+	query += " ORDER BY machine_type, region_name, updated_ts"
+
+	var current *SeriesCoverage
+	var prevTS int64
+	flush := func() {
+		if current == nil {
+			return
+		}
+		report.SeriesAnalyzed++
+		current.ExpectedSnapshots = countBetween(snapshots, current.FirstSeen.Unix(), current.LastSeen.Unix())
+		current.CoverageRatio = float64(current.ObservedSnapshots) / float64(current.ExpectedSnapshots)
+		if len(current.Gaps) > 0 {
+			report.SeriesWithGaps++
+		}
+		if len(current.Gaps) > 0 || current.ObservedSnapshots < current.ExpectedSnapshots {
+			report.SeriesCoverage = append(report.SeriesCoverage, *current)
+		}
+	}
+
+	err = q.QueryRowsNamed("coverage_observations", query, func(rows *sql.Rows) error {
+		var region, machine string
+		var ts int64
+		if err := rows.Scan(&region, &machine, &ts); err != nil {
+			return fmt.Errorf("failed to scan observation: %w", err)
+		}
+
+		if current == nil || current.RegionName != region || current.MachineType != machine {
+			flush()
+			current = &SeriesCoverage{
+				RegionName:  region,
+				MachineType: machine,
+				FirstSeen:   time.Unix(ts, 0).UTC(),
+				Gaps:        []Gap{},
+			}
+		} else {
+			// Snapshots strictly between two observations should have contained the series.
+			// Intervals without any snapshot are already reported as global gaps, and
+			// short ones only lower the coverage ratio.
+			missing := countBetween(snapshots, prevTS, ts) - 2
+			if missing > 0 && ts-prevTS > threshold {
+				current.Gaps = append(current.Gaps, newGap(prevTS, ts, missing))
+			}
+		}
+		current.LastSeen = time.Unix(ts, 0).UTC()
+		current.ObservedSnapshots++
+		prevTS = ts
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query observations: %w", err)
+	}
+	flush()
+
+	// Worst covered series first.
+	sort.SliceStable(report.SeriesCoverage, func(i, j int) bool {
+		a, b := report.SeriesCoverage[i], report.SeriesCoverage[j]
+		if a.CoverageRatio != b.CoverageRatio {
+			return a.CoverageRatio < b.CoverageRatio
+		}
+		if a.RegionName != b.RegionName {
+			return a.RegionName < b.RegionName
+		}
+		return a.MachineType < b.MachineType
+	})
+
+	return report, nil
+}
+
+func newGap(start, end int64, missing int) Gap {
+	return Gap{
+		Start:            time.Unix(start, 0).UTC(),
+		End:              time.Unix(end, 0).UTC(),
+		DurationHours:    float64(end-start) / 3600,
+		MissingSnapshots: missing,
+	}
+}
+
+// countBetween returns the number of snapshots with from <= ts <= to.
+func countBetween(snapshots []int64, from, to int64) int {
+	lo := sort.Search(len(snapshots), func(i int) bool { return snapshots[i] >= from })
+	hi := sort.Search(len(snapshots), func(i int) bool { return snapshots[i] > to })
+	return hi - lo
+}
+
+// FormatText renders a report for terminal output.
+func FormatText(report *Report) string {
+	var b strings.Builder
+	if report.SnapshotCount == 0 {
+		b.WriteString("No raw snapshots found\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Snapshots: %d from %s to %s\n", report.SnapshotCount,
+		report.FirstSnapshot.Format(time.RFC3339), report.LastSnapshot.Format(time.RFC3339))
+	if report.CompactedHistory {
+		b.WriteString("Note: compacted history is excluded from the analysis\n")
+	}
+
+	fmt.Fprintf(&b, "\nGaps longer than %.0fh without any snapshot: %d\n", report.GapThresholdHours, len(report.Gaps))
+	for _, gap := range report.Gaps {
+		fmt.Fprintf(&b, "  %s -> %s  (%.1f days)\n",
+			gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339), gap.DurationHours/24)
+	}
+
+	fmt.Fprintf(&b, "\nSeries missing from snapshots: %d of %d, %d with gaps longer than %.0fh\n",
+		len(report.SeriesCoverage), report.SeriesAnalyzed, report.SeriesWithGaps, report.GapThresholdHours)
+	for _, series := range report.SeriesCoverage {
+		fmt.Fprintf(&b, "  %-24s %-28s coverage %5.1f%% (%d/%d snapshots), %d gaps\n",
+			series.RegionName, series.MachineType, series.CoverageRatio*100,
+			series.ObservedSnapshots, series.ExpectedSnapshots, len(series.Gaps))
+		for _, gap := range series.Gaps {
+			fmt.Fprintf(&b, "      %s -> %s  (%.1f days, %d missing snapshots)\n",
+				gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339), gap.DurationHours/24, gap.MissingSnapshots)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package coverage
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mgruszkiewicz/google-cloud-spot-price-history/internal/db"
+)
+
+const day = 24 * 60 * 60
+
+type observation struct {
+	region, machine string
+	day             int64
+}
+
+func openHistory(t *testing.T, observations []observation) *db.Querier {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := sqlDB.Exec(`CREATE TABLE pricing_history (
+		machine_type varchar(64),
+		region_name varchar(64),
+		updated_ts INTEGER,
+		resolution varchar(8) NOT NULL DEFAULT 'raw',
+		UNIQUE(machine_type, region_name, updated_ts)
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range observations {
+		if _, err := sqlDB.Exec("INSERT INTO pricing_history (machine_type, region_name, updated_ts) VALUES (?, ?, ?)",
+			o.machine, o.region, o.day*day); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db.NewQuerier(sqlDB)
+}
+
+// series returns observations of one series on the given days.
+func series(region, machine string, days ...int64) []observation {
+	var observations []observation
+	for _, d := range days {
+		observations = append(observations, observation{region, machine, d})
+	}
+	return observations
+}
+
+func TestAnalyze(t *testing.T) {
+	// A reference series observed in every snapshot: days 0-3, then a 10 day hole, then 13-15.
+	reference := series("r1", "m1", 0, 1, 2, 3, 13, 14, 15)
+
+	tests := []struct {
+		name           string
+		observations   []observation
+		opts           Options
+		wantGaps       int
+		wantSeries     int
+		wantSeriesGaps []int
+		wantCoverage   []float64
+		wantWithGaps   int
+		wantAnalyzed   int
+		wantSnapshots  int
+	}{
+		{
+			name:          "empty history",
+			wantSnapshots: 0,
+		},
+		{
+			name:          "global gap over the default threshold",
+			observations:  reference,
+			wantGaps:      1,
+			wantAnalyzed:  1,
+			wantSnapshots: 7,
+		},
+		{
+			name:          "global gap below a custom threshold",
+			observations:  reference,
+			opts:          Options{GapThreshold: 11 * day * time.Second},
+			wantAnalyzed:  1,
+			wantSnapshots: 7,
+		},
+		{
+			name:           "short series hole only lowers coverage",
+			observations:   append(series("r2", "m2", 0, 2, 3), reference...),
+			wantGaps:       1,
+			wantSeries:     1,
+			wantSeriesGaps: []int{0},
+			wantCoverage:   []float64{0.75},
+			wantAnalyzed:   2,
+			wantSnapshots:  7,
+		},
+		{
+			name:           "series hole longer than the threshold is a gap",
+			observations:   append(series("r2", "m2", 0, 2, 3), reference...),
+			opts:           Options{GapThreshold: day * time.Second},
+			wantGaps:       1,
+			wantSeries:     1,
+			wantSeriesGaps: []int{1},
+			wantCoverage:   []float64{0.75},
+			wantWithGaps:   1,
+			wantAnalyzed:   2,
+			wantSnapshots:  7,
+		},
+		{
+			name:           "series missing across the global gap",
+			observations:   append(series("r2", "m2", 0, 1, 15), reference...),
+			opts:           Options{GapThreshold: 2 * day * time.Second},
+			wantGaps:       1,
+			wantSeries:     1,
+			wantSeriesGaps: []int{1},
+			wantCoverage:   []float64{3.0 / 7},
+			wantWithGaps:   1,
+			wantAnalyzed:   2,
+			wantSnapshots:  7,
+		},
+		{
+			name:          "filters restrict the series",
+			observations:  append(series("r2", "m2", 0, 2, 3), reference...),
+			opts:          Options{RegionName: "r1"},
+			wantGaps:      1,
+			wantAnalyzed:  1,
+			wantSnapshots: 7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Analyze(openHistory(t, tt.observations), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.SnapshotCount != tt.wantSnapshots {
+				t.Errorf("SnapshotCount = %d, want %d", report.SnapshotCount, tt.wantSnapshots)
+			}
+			if len(report.Gaps) != tt.wantGaps {
+				t.Errorf("len(Gaps) = %d, want %d", len(report.Gaps), tt.wantGaps)
+			}
+			if report.SeriesAnalyzed != tt.wantAnalyzed {
+				t.Errorf("SeriesAnalyzed = %d, want %d", report.SeriesAnalyzed, tt.wantAnalyzed)
+			}
+			if report.SeriesWithGaps != tt.wantWithGaps {
+				t.Errorf("SeriesWithGaps = %d, want %d", report.SeriesWithGaps, tt.wantWithGaps)
+			}
+			if len(report.SeriesCoverage) != tt.wantSeries {
+				t.Fatalf("len(SeriesCoverage) = %d, want %d", len(report.SeriesCoverage), tt.wantSeries)
+			}
+			for i, s := range report.SeriesCoverage {
+				if len(s.Gaps) != tt.wantSeriesGaps[i] {
+					t.Errorf("series %d: len(Gaps) = %d, want %d", i, len(s.Gaps), tt.wantSeriesGaps[i])
+				}
+				if s.CoverageRatio != tt.wantCoverage[i] {
+					t.Errorf("series %d: CoverageRatio = %v, want %v", i, s.CoverageRatio, tt.wantCoverage[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCountBetween(t *testing.T) {
+	snapshots := []int64{10, 20, 30, 40}
+	tests := []struct {
+		from, to int64
+		want     int
+	}{
+		{10, 40, 4},
+		{11, 39, 2},
+		{20, 20, 1},
+		{0, 5, 0},
+		{41, 50, 0},
+	}
+	for _, tt := range tests {
+		if got := countBetween(snapshots, tt.from, tt.to); got != tt.want {
+			t.Errorf("countBetween(%d, %d) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
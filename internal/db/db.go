@@ -1,13 +1,38 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrUnavailable is wrapped by errors of statements that failed because the database could
+// not be used rather than because of the statement: it is locked, cannot be opened or read,
+// or has not been initialized by dataprocessing.
+var ErrUnavailable = errors.New("database unavailable")
+
+// Operations reported to a QueryObserver.
+const (
+	OperationQueryRow  = "query_row"
+	OperationQueryRows = "query_rows"
+	OperationExec      = "exec"
 )
 
+// QueryObserver is called after every statement a Querier runs with the name the caller
+// gave the statement (empty for the unnamed methods), the operation, how long it took
+// (for QueryRows including scanning every row) and its error, if any.
+type QueryObserver func(name, operation string, duration time.Duration, err error)
+
 // Querier wraps a *sql.DB connection and provides helper methods for common query patterns.
 type Querier struct {
-	db *sql.DB
+	db       *sql.DB
+	observer QueryObserver
 }
 
 // NewQuerier creates a new Querier instance.
@@ -15,9 +40,63 @@ func NewQuerier(db *sql.DB) *Querier {
 	return &Querier{db: db}
 }
 
+// SetObserver installs a function observing every statement, e.g. to record latency metrics.
+// It must be called before the Querier is used.
+func (q *Querier) SetObserver(observer QueryObserver) {
+	q.observer = observer
+}
+
+// observe reports a statement started at start to the observer, if any.
+func (q *Querier) observe(name, operation string, start time.Time, err error) {
+	if q.observer != nil {
+		q.observer(name, operation, time.Since(start), err)
+	}
+}
+
 // QueryRow executes a query that is expected to return a single row.
 // It takes the query string, a function to scan the row, and optional arguments.
 func (q *Querier) QueryRow(query string, scanFunc func(*sql.Row) error, args ...interface{}) error {
+	return q.QueryRowNamed("", query, scanFunc, args...)
+}
+
+// QueryRowNamed is QueryRow reporting the statement to the observer under name.
+func (q *Querier) QueryRowNamed(name, query string, scanFunc func(*sql.Row) error, args ...interface{}) error {
+	start := time.Now()
+	err := classify(q.queryRow(query, scanFunc, args...))
+	q.observe(name, OperationQueryRow, start, err)
+	return err
+}
+
+// QueryRows executes a query that is expected to return multiple rows.
+// It takes the query string, a function to scan each row, and optional arguments.
+// The scanFunc will be called for each row returned by the query.
+func (q *Querier) QueryRows(query string, scanFunc func(*sql.Rows) error, args ...interface{}) error {
+	return q.QueryRowsNamed("", query, scanFunc, args...)
+}
+
+// QueryRowsNamed is QueryRows reporting the statement to the observer under name.
+func (q *Querier) QueryRowsNamed(name, query string, scanFunc func(*sql.Rows) error, args ...interface{}) error {
+	start := time.Now()
+	err := classify(q.queryRows(query, scanFunc, args...))
+	q.observe(name, OperationQueryRows, start, err)
+	return err
+}
+
+// Exec executes a statement that does not return rows, such as an INSERT, UPDATE or DELETE.
+func (q *Querier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return q.ExecNamed("", query, args...)
+}
+
+// ExecNamed is Exec reporting the statement to the observer under name.
+func (q *Querier) ExecNamed(name, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := q.exec(query, args...)
+	err = classify(err)
+	q.observe(name, OperationExec, start, err)
+	return result, err
+}
+
+func (q *Querier) queryRow(query string, scanFunc func(*sql.Row) error, args ...interface{}) error {
 	row := q.db.QueryRow(query, args...)
 	if err := scanFunc(row); err != nil {
 		return fmt.Errorf("failed to scan row: %w", err)
@@ -25,10 +104,7 @@ func (q *Querier) QueryRow(query string, scanFunc func(*sql.Row) error, args ...
 	return nil
 }
 
-// QueryRows executes a query that is expected to return multiple rows.
-// It takes the query string, a function to scan each row, and optional arguments.
-// The scanFunc will be called for each row returned by the query.
-func (q *Querier) QueryRows(query string, scanFunc func(*sql.Rows) error, args ...interface{}) error {
+func (q *Querier) queryRows(query string, scanFunc func(*sql.Rows) error, args ...interface{}) error {
 	rows, err := q.db.Query(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
@@ -46,3 +122,36 @@ func (q *Querier) QueryRows(query string, scanFunc func(*sql.Rows) error, args .
 	}
 	return nil
 }
+
+func (q *Querier) exec(query string, args ...interface{}) (sql.Result, error) {
+	result, err := q.db.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute statement: %w", err)
+	}
+	return result, nil
+}
+
+// classify wraps errors meaning the database is unavailable with ErrUnavailable.
+func classify(err error) error {
+	if err == nil || !unavailable(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+func unavailable(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked, sqlite3.ErrCantOpen, sqlite3.ErrIoErr,
+			sqlite3.ErrCorrupt, sqlite3.ErrNotADB, sqlite3.ErrNomem, sqlite3.ErrFull:
+			return true
+		case sqlite3.ErrError:
+			// Tables are created by dataprocessing, so the database was never imported into
+			return strings.HasPrefix(sqliteErr.Error(), "no such table")
+		}
+		return false
+	}
+	return errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
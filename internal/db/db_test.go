@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantUnavailable bool
+	}{
+		{name: "nil", err: nil},
+		{name: "no rows", err: sql.ErrNoRows},
+		{name: "busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, wantUnavailable: true},
+		{name: "not a database", err: fmt.Errorf("failed to execute query: %w", sqlite3.Error{Code: sqlite3.ErrNotADB}), wantUnavailable: true},
+		{name: "constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint}},
+		{name: "connection done", err: sql.ErrConnDone, wantUnavailable: true},
+		{name: "bad connection", err: fmt.Errorf("failed to scan row: %w", driver.ErrBadConn), wantUnavailable: true},
+		{name: "deadline", err: context.DeadlineExceeded, wantUnavailable: true},
+		{name: "canceled", err: context.Canceled},
+		{name: "other", err: errors.New("failed to scan row: converting NULL to float64")},
+	}
+	for _, tt := range tests {
+		got := classify(tt.err)
+		if tt.err == nil {
+			if got != nil {
+				t.Errorf("%s: classify(nil) = %v", tt.name, got)
+			}
+			continue
+		}
+		if !errors.Is(got, tt.err) {
+			t.Errorf("%s: classify lost the original error: %v", tt.name, got)
+		}
+		if errors.Is(got, ErrUnavailable) != tt.wantUnavailable {
+			t.Errorf("%s: classify = %v, want unavailable %v", tt.name, got, tt.wantUnavailable)
+		}
+	}
+}
+
+// observed is one statement reported to a QueryObserver.
+type observed struct {
+	name, operation string
+	err             error
+}
+
+func TestQuerierErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite3")
+	// Fail at once instead of waiting for the lock below.
+	sqlDB, err := sql.Open("sqlite3", path+"?_busy_timeout=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(1)
+	if _, err := sqlDB.Exec("CREATE TABLE prices (price REAL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var statements []observed
+	querier := NewQuerier(sqlDB)
+	querier.SetObserver(func(name, operation string, duration time.Duration, err error) {
+		statements = append(statements, observed{name, operation, err})
+	})
+	scanPrice := func(row *sql.Row) error {
+		var price float64
+		return row.Scan(&price)
+	}
+
+	err = querier.QueryRowNamed("missing_table", "SELECT price FROM history", scanPrice)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("query of a missing table: %v, want ErrUnavailable", err)
+	}
+	err = querier.QueryRowsNamed("syntax", "SELEC price FROM prices", func(*sql.Rows) error { return nil })
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		t.Errorf("query with a syntax error: %v, want an error other than ErrUnavailable", err)
+	}
+	err = querier.QueryRow("SELECT price FROM prices", scanPrice)
+	if !errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("query without rows: %v, want sql.ErrNoRows", err)
+	}
+
+	// Another connection holding a write lock makes the database busy.
+	locker, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer locker.Close()
+	tx, err := locker.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO prices VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := querier.ExecNamed("insert_price", "INSERT INTO prices VALUES (2)"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("insert into a locked database: %v, want ErrUnavailable", err)
+	}
+
+	// Every statement failed, and is reported with its name, operation and error.
+	want := []struct{ name, operation string }{
+		{"missing_table", OperationQueryRow},
+		{"syntax", OperationQueryRows},
+		{"", OperationQueryRow},
+		{"insert_price", OperationExec},
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("observed %d statements, want %d", len(statements), len(want))
+	}
+	for i, s := range statements {
+		if s.name != want[i].name || s.operation != want[i].operation || s.err == nil {
+			t.Errorf("statement %d observed as %q %q (error %v), want %q %q with an error", i, s.name, s.operation, s.err, want[i].name, want[i].operation)
+		}
+	}
+}
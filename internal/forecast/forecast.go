@@ -0,0 +1,222 @@
+// Package forecast projects daily price series a short horizon ahead.
+//
+// Two models are available: a least squares linear trend and Holt's linear
+// exponential smoothing, which follows recent level and trend changes more
+// closely. Both produce prediction intervals from the in-sample error, and
+// Backtest measures their error against held-out history.
+package forecast
+
+import (
+	"fmt"
+	"math"
+)
+
+// Models supported by Forecast.
+const (
+	ModelLinear = "linear"
+	ModelHolt   = "holt"
+)
+
+// MinTrainingPoints is the shortest series a model is fitted to.
+const MinTrainingPoints = 7
+
+// Prediction is the forecast of one step ahead of the series with its interval.
+type Prediction struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+// Params describes a fitted model.
+type Params struct {
+	// Slope and Intercept of the linear trend, in price per step from the first point.
+	Slope     float64
+	Intercept float64
+	// Alpha (level) and Beta (trend) smoothing factors of the Holt model.
+	Alpha float64
+	Beta  float64
+	// Sigma is the standard deviation of the in-sample one-step errors.
+	Sigma float64
+}
+
+// Result is a fitted model and its predictions for the steps after the series.
+type Result struct {
+	Model       string
+	Params      Params
+	Predictions []Prediction
+}
+
+// Forecast fits the model to the series and predicts horizon steps ahead. Intervals span
+// z standard errors around each prediction, and prices are never projected below zero.
+func Forecast(series []float64, model string, horizon int, z float64) (*Result, error) {
+	if len(series) < MinTrainingPoints {
+		return nil, fmt.Errorf("need at least %d points to fit a model, got %d", MinTrainingPoints, len(series))
+	}
+	if horizon <= 0 {
+		return nil, fmt.Errorf("horizon must be positive")
+	}
+
+	var result *Result
+	switch model {
+	case ModelLinear:
+		result = linear(series, horizon, z)
+	case ModelHolt:
+		result = holt(series, horizon, z)
+	default:
+		return nil, fmt.Errorf("unknown model %q, expected %s or %s", model, ModelLinear, ModelHolt)
+	}
+
+	for i := range result.Predictions {
+		p := &result.Predictions[i]
+		p.Value = math.Max(p.Value, 0)
+		p.Lower = math.Max(p.Lower, 0)
+		p.Upper = math.Max(p.Upper, 0)
+	}
+	return result, nil
+}
+
+// linear fits an ordinary least squares trend with the classic prediction interval.
+func linear(series []float64, horizon int, z float64) *Result {
+	n := float64(len(series))
+	var sumX, sumY float64
+	for i, y := range series {
+		sumX += float64(i)
+		sumY += y
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for i, y := range series {
+		dx := float64(i) - meanX
+		sxx += dx * dx
+		sxy += dx * (y - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i, y := range series {
+		r := y - (intercept + slope*float64(i))
+		sse += r * r
+	}
+	sigma := math.Sqrt(sse / (n - 2))
+
+	result := &Result{
+		Model:  ModelLinear,
+		Params: Params{Slope: slope, Intercept: intercept, Sigma: sigma},
+	}
+	for h := 1; h <= horizon; h++ {
+		x := n - 1 + float64(h)
+		value := intercept + slope*x
+		margin := z * sigma * math.Sqrt(1+1/n+(x-meanX)*(x-meanX)/sxx)
+		result.Predictions = append(result.Predictions, Prediction{Value: value, Lower: value - margin, Upper: value + margin})
+	}
+	return result
+}
+
+// holt fits Holt's linear exponential smoothing, choosing the smoothing factors that
+// minimize the one-step-ahead squared error on a grid.
+func holt(series []float64, horizon int, z float64) *Result {
+	best := Params{Sigma: math.Inf(1)}
+	var bestLevel, bestTrend float64
+	for a := 1; a < 20; a++ {
+		for b := 0; b <= 10; b++ {
+			alpha, beta := float64(a)/20, float64(b)/20
+			level, trend, sse := holtSmooth(series, alpha, beta)
+			if sse < best.Sigma {
+				best = Params{Alpha: alpha, Beta: beta, Sigma: sse}
+				bestLevel, bestTrend = level, trend
+			}
+		}
+	}
+	best.Sigma = math.Sqrt(best.Sigma / float64(len(series)-2))
+
+	result := &Result{Model: ModelHolt, Params: best}
+	// The h-step error variance grows with the smoothed shocks carried into later steps.
+	variance := 1.0
+	for h := 1; h <= horizon; h++ {
+		if h > 1 {
+			c := best.Alpha * (1 + float64(h-1)*best.Beta)
+			variance += c * c
+		}
+		value := bestLevel + float64(h)*bestTrend
+		margin := z * best.Sigma * math.Sqrt(variance)
+		result.Predictions = append(result.Predictions, Prediction{Value: value, Lower: value - margin, Upper: value + margin})
+	}
+	return result
+}
+
+// holtSmooth runs the smoothing recursion and returns the final level and trend and
+// the sum of squared one-step-ahead errors.
+func holtSmooth(series []float64, alpha, beta float64) (float64, float64, float64) {
+	level, trend := series[0], series[1]-series[0]
+	var sse float64
+	for _, y := range series[1:] {
+		forecast := level + trend
+		sse += (y - forecast) * (y - forecast)
+		previous := level
+		level = alpha*y + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+	}
+	return level, trend, sse
+}
+
+// BacktestResult compares a forecast made without the last horizon points to those points.
+type BacktestResult struct {
+	Forecast *Result
+	Actual   []float64
+	// MAE, MAPE (percent) and RMSE of the model.
+	MAE  float64
+	MAPE float64
+	RMSE float64
+	// Coverage is the share of actual values inside the prediction interval, in percent.
+	Coverage float64
+	// NaiveMAE and NaiveMAPE are the errors of repeating the last training value,
+	// the baseline a model has to beat to be useful.
+	NaiveMAE  float64
+	NaiveMAPE float64
+}
+
+// Backtest holds out the last horizon points of the series, forecasts them from the
+// rest and reports the error.
+func Backtest(series []float64, model string, horizon int, z float64) (*BacktestResult, error) {
+	if len(series)-horizon < MinTrainingPoints {
+		return nil, fmt.Errorf("need at least %d points before the held-out %d, got %d", MinTrainingPoints, horizon, len(series)-horizon)
+	}
+	training, actual := series[:len(series)-horizon], series[len(series)-horizon:]
+	fc, err := Forecast(training, model, horizon, z)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BacktestResult{Forecast: fc, Actual: actual}
+	naive := training[len(training)-1]
+	var squares float64
+	var inside, percentPoints int
+	for i, y := range actual {
+		p := fc.Predictions[i]
+		err := y - p.Value
+		result.MAE += math.Abs(err)
+		squares += err * err
+		result.NaiveMAE += math.Abs(y - naive)
+		if y != 0 {
+			result.MAPE += math.Abs(err / y)
+			result.NaiveMAPE += math.Abs((y - naive) / y)
+			percentPoints++
+		}
+		if y >= p.Lower && y <= p.Upper {
+			inside++
+		}
+	}
+
+	n := float64(len(actual))
+	result.MAE /= n
+	result.NaiveMAE /= n
+	result.RMSE = math.Sqrt(squares / n)
+	result.Coverage = float64(inside) / n * 100
+	if percentPoints > 0 {
+		result.MAPE = result.MAPE / float64(percentPoints) * 100
+		result.NaiveMAPE = result.NaiveMAPE / float64(percentPoints) * 100
+	}
+	return result, nil
+}
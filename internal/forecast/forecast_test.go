@@ -0,0 +1,172 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+const tolerance = 1e-9
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < tolerance
+}
+
+func TestForecast(t *testing.T) {
+	// y = 2, 4, 3, 5, 4, 6, 5 at x = 0..6: mean x 3, mean y 29/7, Sxx 28, Sxy 14, so the
+	// slope is 1/2 and the intercept 29/7 - 3/2 = 37/14. The residuals alternate -9/14
+	// and 12/14, giving SSE 27/7 and sigma sqrt(27/7 / 5).
+	zigzagSigma := math.Sqrt(27.0 / 35)
+
+	tests := []struct {
+		name    string
+		series  []float64
+		model   string
+		horizon int
+		z       float64
+		want    []Prediction
+		params  Params
+		// anyFactors skips checking Alpha and Beta where several fit exactly.
+		anyFactors bool
+	}{
+		{
+			name:    "linear on a line",
+			series:  []float64{1, 2, 3, 4, 5, 6, 7},
+			model:   ModelLinear,
+			horizon: 2,
+			z:       1.96,
+			want:    []Prediction{{8, 8, 8}, {9, 9, 9}},
+			params:  Params{Slope: 1, Intercept: 1},
+		},
+		{
+			name:    "linear on a zigzag",
+			series:  []float64{2, 4, 3, 5, 4, 6, 5},
+			model:   ModelLinear,
+			horizon: 1,
+			z:       1,
+			// x = 7: 37/14 + 7/2 = 43/7, margin sigma * sqrt(1 + 1/7 + 16/28).
+			want: []Prediction{{
+				Value: 43.0 / 7,
+				Lower: 43.0/7 - zigzagSigma*math.Sqrt(12.0/7),
+				Upper: 43.0/7 + zigzagSigma*math.Sqrt(12.0/7),
+			}},
+			params: Params{Slope: 0.5, Intercept: 37.0 / 14, Sigma: zigzagSigma},
+		},
+		{
+			name:    "linear is not projected below zero",
+			series:  []float64{6, 5, 4, 3, 2, 1, 0},
+			model:   ModelLinear,
+			horizon: 2,
+			z:       1.96,
+			want:    []Prediction{{0, 0, 0}, {0, 0, 0}},
+			params:  Params{Slope: -1, Intercept: 6},
+		},
+		{
+			// Every smoothing factor fits a line exactly, up to rounding.
+			name:       "holt on a line",
+			series:     []float64{1, 2, 3, 4, 5, 6, 7},
+			model:      ModelHolt,
+			horizon:    3,
+			z:          1.96,
+			want:       []Prediction{{8, 8, 8}, {9, 9, 9}, {10, 10, 10}},
+			anyFactors: true,
+		},
+		{
+			// Every smoothing factor fits exactly; the first of the grid is kept.
+			name:    "holt on a constant",
+			series:  []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			model:   ModelHolt,
+			horizon: 2,
+			z:       1.96,
+			want:    []Prediction{{5, 5, 5}, {5, 5, 5}},
+			params:  Params{Alpha: 0.05, Beta: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Forecast(tt.series, tt.model, tt.horizon, tt.z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Model != tt.model {
+				t.Errorf("Model = %q, want %q", result.Model, tt.model)
+			}
+			p := result.Params
+			if tt.anyFactors {
+				p.Alpha, p.Beta = tt.params.Alpha, tt.params.Beta
+			}
+			if !near(p.Slope, tt.params.Slope) || !near(p.Intercept, tt.params.Intercept) ||
+				!near(p.Alpha, tt.params.Alpha) || !near(p.Beta, tt.params.Beta) || !near(p.Sigma, tt.params.Sigma) {
+				t.Errorf("Params = %+v, want %+v", p, tt.params)
+			}
+			if len(result.Predictions) != len(tt.want) {
+				t.Fatalf("got %d predictions, want %d", len(result.Predictions), len(tt.want))
+			}
+			for i, got := range result.Predictions {
+				want := tt.want[i]
+				if !near(got.Value, want.Value) || !near(got.Lower, want.Lower) || !near(got.Upper, want.Upper) {
+					t.Errorf("prediction %d = %+v, want %+v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastErrors(t *testing.T) {
+	line := []float64{1, 2, 3, 4, 5, 6, 7}
+	tests := []struct {
+		name    string
+		series  []float64
+		model   string
+		horizon int
+	}{
+		{"too short", line[:MinTrainingPoints-1], ModelLinear, 1},
+		{"no horizon", line, ModelLinear, 0},
+		{"unknown model", line, "arima", 1},
+	}
+	for _, tt := range tests {
+		if _, err := Forecast(tt.series, tt.model, tt.horizon, 1.96); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	// Training on 1..7 predicts 8, 9 and 10 exactly; repeating 7 misses by 1, 2 and 3.
+	series := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	result, err := Backtest(series, ModelLinear, 3, 1.96)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	naiveMAPE := (1.0/8 + 2.0/9 + 3.0/10) / 3 * 100
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"MAE", result.MAE, 0},
+		{"MAPE", result.MAPE, 0},
+		{"RMSE", result.RMSE, 0},
+		{"Coverage", result.Coverage, 100},
+		{"NaiveMAE", result.NaiveMAE, 2},
+		{"NaiveMAPE", result.NaiveMAPE, naiveMAPE},
+	}
+	for _, c := range checks {
+		if !near(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if _, err := Backtest(series, ModelLinear, 4, 1.96); err == nil {
+		t.Error("expected an error when fewer than MinTrainingPoints remain for training")
+	}
+}
+
+func TestHoltSmooth(t *testing.T) {
+	// Level 1, trend 2. y=3 is forecast exactly: level .5*3 + .5*3 = 3, trend .5*2 + .5*2 = 2.
+	// y=2 is forecast as 5, error -3: level .5*2 + .5*5 = 3.5, trend .5*.5 + .5*2 = 1.25.
+	level, trend, sse := holtSmooth([]float64{1, 3, 2}, 0.5, 0.5)
+	if !near(level, 3.5) || !near(trend, 1.25) || !near(sse, 9) {
+		t.Errorf("holtSmooth = %v, %v, %v, want 3.5, 1.25, 9", level, trend, sse)
+	}
+}
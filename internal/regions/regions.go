@@ -0,0 +1,54 @@
+// Package regions maps Google Cloud regions to the continents they can be filtered by.
+package regions
+
+import (
+	"sort"
+	"strings"
+)
+
+// continentByPrefix maps region name prefixes to continents.
+var continentByPrefix = map[string]string{
+	"africa":       "africa",
+	"asia":         "asia",
+	"australia":    "oceania",
+	"europe":       "europe",
+	"me":           "middle-east",
+	"northamerica": "north-america",
+	"southamerica": "south-america",
+	"us":           "north-america",
+}
+
+// Continents returns the known continent names, sorted.
+func Continents() []string {
+	seen := map[string]bool{}
+	var continents []string
+	for _, continent := range continentByPrefix {
+		if !seen[continent] {
+			seen[continent] = true
+			continents = append(continents, continent)
+		}
+	}
+	sort.Strings(continents)
+	return continents
+}
+
+// Continent returns the continent of a region, derived from its name prefix
+// (europe-west1 is in europe, us-east1 and northamerica-northeast1 in north-america),
+// or "other" for unknown prefixes.
+func Continent(regionName string) string {
+	prefix, _, _ := strings.Cut(regionName, "-")
+	if continent, ok := continentByPrefix[prefix]; ok {
+		return continent
+	}
+	return "other"
+}
+
+// IsContinent reports whether name is one of Continents.
+func IsContinent(name string) bool {
+	for _, continent := range continentByPrefix {
+		if continent == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package regions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestContinent(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"europe-west1", "europe"},
+		{"us-east1", "north-america"},
+		{"northamerica-northeast1", "north-america"},
+		{"southamerica-east1", "south-america"},
+		{"australia-southeast1", "oceania"},
+		{"me-central2", "middle-east"},
+		{"africa-south1", "africa"},
+		{"asia-east1", "asia"},
+		{"mars-north1", "other"},
+		{"europe", "europe"},
+		{"", "other"},
+	}
+	for _, tt := range tests {
+		if got := Continent(tt.region); got != tt.want {
+			t.Errorf("Continent(%q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestContinents(t *testing.T) {
+	want := []string{"africa", "asia", "europe", "middle-east", "north-america", "oceania", "south-america"}
+	if got := Continents(); !slices.Equal(got, want) {
+		t.Errorf("Continents() = %v, want %v", got, want)
+	}
+	for _, continent := range want {
+		if !IsContinent(continent) {
+			t.Errorf("IsContinent(%q) = false", continent)
+		}
+	}
+	for _, name := range []string{"other", "us", "Europe", ""} {
+		if IsContinent(name) {
+			t.Errorf("IsContinent(%q) = true", name)
+		}
+	}
+}
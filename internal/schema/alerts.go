@@ -0,0 +1,51 @@
+// Package schema creates the tables both binaries write to. Tables only dataprocessing
+// writes are created by it; the API creates these at startup so that it can serve them
+// before the first import.
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// InitAlertTables creates alert_rules, which the API manages, and alert_deliveries, the
+// log of webhook notifications dataprocessing sends after imports.
+func InitAlertTables(db *sql.DB) error {
+	for _, statement := range []string{
+		`CREATE TABLE IF NOT EXISTS alert_rules (
+			id INTEGER PRIMARY KEY,
+			name TEXT,
+			machine_type varchar(64),
+			family varchar(64),
+			region_name varchar(64),
+			continent varchar(32),
+			price varchar(16) NOT NULL DEFAULT 'spot',
+			condition varchar(16),
+			threshold REAL,
+			webhook_url TEXT,
+			secret TEXT,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER,
+			updated_at INTEGER
+		)`,
+		`CREATE TABLE IF NOT EXISTS alert_deliveries (
+			id INTEGER PRIMARY KEY,
+			rule_id INTEGER,
+			ingestion_run_id INTEGER,
+			created_at INTEGER,
+			status varchar(16),
+			event_count INTEGER,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_attempt_at INTEGER,
+			response_status INTEGER,
+			error TEXT,
+			payload TEXT
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_alert_deliveries_rule ON alert_deliveries(rule_id, id)",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create alert tables: %w", err)
+		}
+	}
+	return nil
+}
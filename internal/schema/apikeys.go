@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// InitAPIKeyTables creates api_keys, managed with `dataprocessing apikey`, and
+// api_key_usage, the requests per key and UTC day counted by the API, adding columns
+// missing from databases created by older versions.
+func InitAPIKeyTables(db *sql.DB) error {
+	for _, statement := range []string{
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			prefix varchar(16),
+			key_hash varchar(64) NOT NULL UNIQUE,
+			rate_limit REAL NOT NULL DEFAULT 0,
+			burst INTEGER NOT NULL DEFAULT 0,
+			daily_quota INTEGER NOT NULL DEFAULT 0,
+			admin INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER,
+			revoked_at INTEGER
+		)`,
+		`CREATE TABLE IF NOT EXISTS api_key_usage (
+			key_id INTEGER,
+			day varchar(10),
+			requests INTEGER NOT NULL DEFAULT 0,
+			rejected INTEGER NOT NULL DEFAULT 0,
+			last_request_at INTEGER,
+			PRIMARY KEY(key_id, day)
+		)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create API key tables: %w", err)
+		}
+	}
+	return addColumn(db, "api_keys", "admin", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// addColumn adds a column to a table unless it has it already.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
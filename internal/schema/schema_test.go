@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestInitAPIKeyTables(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+
+	// api_keys as created before keys had an admin flag.
+	if _, err := sqlDB.Exec(`CREATE TABLE api_keys (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		prefix varchar(16),
+		key_hash varchar(64) NOT NULL UNIQUE,
+		rate_limit REAL NOT NULL DEFAULT 0,
+		burst INTEGER NOT NULL DEFAULT 0,
+		daily_quota INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER,
+		revoked_at INTEGER
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec("INSERT INTO api_keys (name, key_hash) VALUES ('old', 'hash')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Running it twice checks that it is idempotent.
+	for i := 0; i < 2; i++ {
+		if err := InitAPIKeyTables(sqlDB); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	var admin bool
+	if err := sqlDB.QueryRow("SELECT admin FROM api_keys WHERE name = 'old'").Scan(&admin); err != nil {
+		t.Fatal(err)
+	}
+	if admin {
+		t.Error("existing key became an admin key")
+	}
+	if _, err := sqlDB.Exec("INSERT INTO api_key_usage (key_id, day) VALUES (1, '2024-06-30')"); err != nil {
+		t.Errorf("api_key_usage was not created: %v", err)
+	}
+}
+
+func TestInitAlertTables(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := InitAlertTables(sqlDB); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	for _, table := range []string{"alert_rules", "alert_deliveries"} {
+		var count int
+		if err := sqlDB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Errorf("%s was not created: %v", table, err)
+		}
+	}
+}
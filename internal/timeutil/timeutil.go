@@ -0,0 +1,84 @@
+// Package timeutil contains time parsing helpers shared by the CLI and the API.
+package timeutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a positive duration. It accepts Go durations ("36h")
+// plus day ("90d") and week ("2w") suffixes, which time.ParseDuration lacks.
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count <= 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %q", s)
+	}
+	return d, nil
+}
+
+const compactDateLayout = "20060102"
+
+// ParseTime parses an optional YYYY-MM-DD or RFC 3339 time named name; "" gives the
+// zero time. A bare date used as an upper bound means the end of that day.
+func ParseTime(name, value string, upperBound bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", name)
+	}
+	if upperBound {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
+
+// ParseInstant parses an optional point in time given as a Unix timestamp, an RFC 3339
+// timestamp or a date, which means the end of that day. Eight digits that also read as a
+// YYYYMMDD date (20240315) are rejected rather than taken as a 1970s Unix timestamp.
+func ParseInstant(name, value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if _, err := time.Parse(compactDateLayout, value); err == nil {
+			return time.Time{}, fmt.Errorf("%s %q is ambiguous, write a date as YYYY-MM-DD", name, value)
+		}
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	t, err := ParseTime(name, value, true)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date (YYYY-MM-DD), RFC 3339 or Unix timestamp", name)
+	}
+	return t, nil
+}
+
+// BucketStart returns the start (UTC) of the day, week or month bucket containing t;
+// any other resolution gives the day. Weeks start on Monday.
+func BucketStart(t time.Time, resolution string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch resolution {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
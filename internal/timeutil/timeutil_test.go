@@ -0,0 +1,103 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketStart(t *testing.T) {
+	// 2024-03-14 is a Thursday.
+	at := time.Date(2024, 3, 14, 17, 30, 5, 0, time.UTC)
+	tests := []struct {
+		t          time.Time
+		resolution string
+		want       time.Time
+	}{
+		{at, "day", time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{at, "raw", time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{at, "week", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{at, "month", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Sunday belongs to the week started the Monday before.
+		{time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC), "week", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		// A week can start in the previous month and year.
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "week", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), "week", time.Date(2022, 12, 26, 0, 0, 0, 0, time.UTC)},
+		// Other zones are bucketed by their UTC time.
+		{time.Date(2024, 3, 1, 0, 30, 0, 0, time.FixedZone("CET", 3600)), "month", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := BucketStart(tt.t, tt.resolution); !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("BucketStart(%v, %q) = %v, want %v", tt.t, tt.resolution, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "36h", want: 36 * time.Hour},
+		{value: "90d", want: 90 * 24 * time.Hour},
+		{value: "2w", want: 14 * 24 * time.Hour},
+		{value: "0d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "xd", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value      string
+		upperBound bool
+		want       time.Time
+		wantErr    bool
+	}{
+		{value: ""},
+		{value: "2024-03-15", want: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-15", upperBound: true, want: time.Date(2024, 3, 15, 23, 59, 59, 0, time.UTC)},
+		{value: "2024-03-15T10:00:00Z", upperBound: true, want: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-03-15T12:00:00+02:00", want: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+		{value: "15/03/2024", wantErr: true},
+		{value: "2024-03-15 10:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime("from", tt.value, tt.upperBound)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q, %v) = %v, %v, want %v (error %v)", tt.value, tt.upperBound, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseInstant(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "1718000000", want: time.Unix(1718000000, 0)},
+		{value: "0", want: time.Unix(0, 0)},
+		{value: "2024-03-15", want: time.Date(2024, 3, 15, 23, 59, 59, 0, time.UTC)},
+		{value: "2024-03-15T10:00:00Z", want: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+		// Eight digits forming a date are neither taken as a date nor as a Unix timestamp.
+		{value: "20240315", wantErr: true},
+		// Eight digits that are no date can only be a Unix timestamp.
+		{value: "20241399", want: time.Unix(20241399, 0)},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInstant("as_of", tt.value)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("ParseInstant(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
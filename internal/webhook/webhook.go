@@ -0,0 +1,63 @@
+// Package webhook keeps alert webhooks from reaching the network of the host sending them.
+// Rules are created by API clients, so a webhook URL naming a loopback, link-local or
+// private address would let any client make dataprocessing POST to internal services.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrNotPublic is returned for addresses that are not public.
+var ErrNotPublic = errors.New("not a public address")
+
+// Public reports whether ip is an address webhooks may be sent to.
+func Public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// CheckURL checks that a webhook URL is an absolute http or https URL whose host resolves
+// to public addresses only.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook_url must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !Public(ip) {
+			return fmt.Errorf("webhook host %s is %w", host, ErrNotPublic)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !Public(addr.IP) {
+			return fmt.Errorf("webhook host %s resolves to %s, which is %w", host, addr.IP, ErrNotPublic)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to addresses that are not
+// public. Checking at dial time also covers redirects and hosts whose DNS records changed
+// after the rule was created.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !Public(ip) {
+		return fmt.Errorf("refusing to connect to %s: %w", host, ErrNotPublic)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.7", true},
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		// IPv4 addresses mapped to IPv6 are checked as IPv4.
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:203.0.113.7", true},
+	}
+	for _, tt := range tests {
+		if got := Public(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("Public(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantErr   bool
+		notPublic bool
+	}{
+		{url: "https://203.0.113.7/hooks/spot"},
+		{url: "http://[2001:db8::1]:8080/hook"},
+		{url: "ftp://203.0.113.7/hook", wantErr: true},
+		{url: "/hooks/spot", wantErr: true},
+		{url: "https://", wantErr: true},
+		{url: "https://127.0.0.1/hook", wantErr: true, notPublic: true},
+		{url: "http://[::1]:9000/hook", wantErr: true, notPublic: true},
+		{url: "http://169.254.169.254/computeMetadata/v1/", wantErr: true, notPublic: true},
+		// Resolved from the hosts file.
+		{url: "http://localhost:8080/hook", wantErr: true, notPublic: true},
+	}
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckURL(%q) = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+		if errors.Is(err, ErrNotPublic) != tt.notPublic {
+			t.Errorf("CheckURL(%q) = %v, want ErrNotPublic %v", tt.url, err, tt.notPublic)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"203.0.113.7:443", false},
+		{"[2001:db8::1]:443", false},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+		{"10.0.0.5:80", true},
+		{"no-port", true},
+	}
+	for _, tt := range tests {
+		if err := Control("tcp", tt.address, nil); (err != nil) != tt.wantErr {
+			t.Errorf("Control(%q) = %v, want error %v", tt.address, err, tt.wantErr)
+		}
+	}
+}